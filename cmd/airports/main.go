@@ -1,24 +1,89 @@
 package main
 
 import (
+	"bufio"
+	"flag"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/chrisnappin/flightchecker/pkg/application"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
 	"github.com/chrisnappin/flightchecker/pkg/framework"
 )
 
+// Looks up airports by free text, such as a name, municipality or code, to find the codes to use in arguments.json,
+// e.g.
+//
+//	airports heathrow
+//	airports -limit 5 los angeles
+//
+// Without any text, it reads a search from each line typed, until Ctrl-D. Airports are read from the flight checker's
+// database, imported from the airport data files if it doesn't have them yet.
 func main() {
-	logger := framework.NewLogWrapper("airports", true)
-	loader := framework.NewAirportDataLoader(framework.NewLogWrapper("airportDataLoader", true))
-	service := application.NewFindAirportsService(framework.NewLogWrapper("findAirportsService", true), loader)
-
-	const countryName = "United Kingdom"
-	const regionName = "England"
-	const exclude = "RAF "
-	err := service.FindAirports(countryName, regionName, exclude)
+	flags := flag.NewFlagSet("airports", flag.ExitOnError)
+	limit := flags.Int("limit", 10, "maximum number of airports to show for each search")
+	databaseFile := flags.String("db", "./data/flightchecker.db", "SQLite database file")
+	debug := flags.Bool("debug", false, "log debug messages")
+	flags.Parse(os.Args[1:])
+
+	logger := framework.NewLogWrapper("airports", *debug)
+	db, flightRepository, err := framework.OpenFlightRepository(framework.NewLogWrapper("sqliteRepository", *debug),
+		*databaseFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer db.Close()
+
+	loader := framework.NewAirportDataLoader(framework.NewLogWrapper("airportDataLoader", *debug))
+	service := application.NewFindAirportsService(framework.NewLogWrapper("findAirportsService", *debug), loader,
+		flightRepository)
+	airports, err := service.LoadMajorAirports()
 	if err != nil {
 		logger.Fatal(err)
 	}
 
-	os.Exit(0)
+	if flags.NArg() > 0 {
+		search(service, strings.Join(flags.Args(), " "), *limit, logger)
+		return
+	}
+
+	interactive := isTerminal(os.Stdin)
+	if interactive {
+		fmt.Printf("Loaded %d airports. Type a name, municipality or code to search for, or Ctrl-D to exit.\n",
+			len(airports))
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for prompt(interactive); scanner.Scan(); prompt(interactive) {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			search(service, scanner.Text(), *limit, logger)
+		}
+	}
+	if scanner.Err() != nil {
+		logger.Fatal(scanner.Err())
+	}
+}
+
+// prompt asks for the next search, if the searches are being typed.
+func prompt(interactive bool) {
+	if interactive {
+		fmt.Print("> ")
+	}
+}
+
+// isTerminal returns whether the file is a terminal, rather than e.g. a pipe.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// search prints the details of each airport that best matches the text, best first, in columns.
+func search(service *application.FindAirportsService, text string, limit int, logger domain.Logger) {
+	matches, err := service.SearchAirports(text, limit)
+	if err == nil {
+		err = service.OutputAirportMatches(os.Stdout, matches)
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/application"
+	"github.com/chrisnappin/flightchecker/pkg/framework"
+)
+
+// Runs a stand-in for the sky scanner API, so flightchecker can be run without an API key, e.g.
+//
+//	fakeskyscanner -addr localhost:8080 -pending-polls 2 -faults 3:429
+//	flightchecker quote -api-url http://localhost:8080
+func main() {
+	flags := flag.NewFlagSet("fakeskyscanner", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	pendingPolls := flags.Int("pending-polls", 2, "polls of each session answered as pending, before it completes")
+	itineraries := flags.Int("itineraries", 25, "itineraries found by each session")
+	faults := flags.String("faults", "",
+		"faults to inject, as comma separated request numbers and HTTP status codes or malformed, e.g. 2:429,5:malformed")
+	seed := flags.Int64("seed", 0, "seed for the random itineraries, or 0 for different itineraries each run")
+	databaseFile := flags.String("db", "./data/flightchecker.db", "SQLite database file to read the airports from")
+	debug := flags.Bool("debug", false, "log debug messages")
+	flags.Parse(os.Args[1:])
+
+	logger := framework.NewLogWrapper("fakeskyscanner", *debug)
+	options := framework.FakeSkyScannerOptions{PendingPolls: *pendingPolls, Itineraries: *itineraries, Seed: *seed}
+	if options.Seed == 0 {
+		options.Seed = time.Now().UnixNano()
+	}
+
+	var err error
+	options.Faults, err = framework.ParseFaults(*faults)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	db, flightRepository, err := framework.OpenFlightRepository(framework.NewLogWrapper("sqliteRepository", *debug),
+		*databaseFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	loader := framework.NewAirportDataLoader(framework.NewLogWrapper("airportDataLoader", *debug))
+	finder := application.NewFindAirportsService(framework.NewLogWrapper("findAirportsService", *debug), loader,
+		flightRepository)
+	airports, err := finder.LoadMajorAirports()
+	db.Close() // only needed for the airports
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("Listening on %s, with %d airports", *addr, len(airports))
+	err = http.ListenAndServe(*addr, framework.NewFakeSkyScanner(logger, airports, options))
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
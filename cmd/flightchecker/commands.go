@@ -0,0 +1,416 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chrisnappin/flightchecker/pkg/application"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/chrisnappin/flightchecker/pkg/framework"
+)
+
+// runQuote searches for quotes matching the arguments, saves and outputs them.
+func runQuote(args []string) error {
+	flags := flag.NewFlagSet("quote", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	overrides := addArgumentFlags(flags)
+	search := addSearchFlags(flags)
+	maxAge := flags.Duration("max-age", 0,
+		"use a saved quote for the same search if no older than this (e.g. 12h), rather than searching again")
+	bookingDetails := flags.Bool("booking-details", false,
+		"also find the ways to book the cheapest itinerary of a live search, with links to each booking page")
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := loadArguments(opts, overrides)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	orchestrator, err := search.newOrchestrator(opts, flightRepository)
+	if err != nil {
+		return err
+	}
+	loader := opts.newAirportLoader()
+	finder := application.NewFindAirportsService(opts.newLogger("airportLoader"), loader, flightRepository)
+	flightQuoter := application.NewQuoteForFlightsService(opts.newLogger("quoteForFlights"),
+		finder, flightRepository, orchestrator)
+
+	ctx, cancel := search.newContext()
+	defer cancel()
+	quote, err := flightQuoter.QuoteForFlights(ctx, arguments, *maxAge)
+	if err != nil {
+		return err
+	}
+
+	var details *domain.BookingDetails
+	if cheapest := quote.Cheapest(); *bookingDetails && cheapest != nil {
+		details, err = flightQuoter.FindBookingDetails(ctx, arguments, cheapest)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.format == "json" && details != nil {
+		return opts.writeJSON(struct {
+			Quote          *domain.Quote
+			BookingDetails *domain.BookingDetails
+		}{quote, details})
+	} else if opts.format == "json" {
+		return opts.writeJSON(quote)
+	}
+	flightQuoter.OutputQuotes(quote)
+	if details != nil {
+		flightQuoter.OutputBookingDetails(details, quote.CurrencyFormat)
+	}
+	return nil
+}
+
+// runResume resumes every search that was interrupted before its quotes were complete, then saves and outputs them.
+func runResume(args []string) error {
+	flags := flag.NewFlagSet("resume", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	overrides := addArgumentFlags(flags)
+	search := addSearchFlags(flags)
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := loadArguments(opts, overrides)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	orchestrator, err := search.newOrchestrator(opts, flightRepository)
+	if err != nil {
+		return err
+	}
+	loader := opts.newAirportLoader()
+	finder := application.NewFindAirportsService(opts.newLogger("airportLoader"), loader, flightRepository)
+	flightQuoter := application.NewQuoteForFlightsService(opts.newLogger("quoteForFlights"),
+		finder, flightRepository, orchestrator)
+
+	ctx, cancel := search.newContext()
+	defer cancel()
+	quotes, err := flightQuoter.ResumeSearches(ctx, arguments)
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		return opts.writeJSON(quotes)
+	}
+	for _, quote := range quotes {
+		flightQuoter.OutputQuotes(quote)
+	}
+	return nil
+}
+
+// runCalendar searches every outbound date and holiday duration in the ranges of the arguments, then outputs the
+// cheapest price of each.
+func runCalendar(args []string) error {
+	flags := flag.NewFlagSet("calendar", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	overrides := addArgumentFlags(flags)
+	search := addSearchFlags(flags)
+	maxAge := flags.Duration("max-age", 0,
+		"use saved quotes for the same searches if no older than this (e.g. 12h), rather than searching again")
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := loadArguments(opts, overrides)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	orchestrator, err := search.newOrchestrator(opts, flightRepository)
+	if err != nil {
+		return err
+	}
+	loader := opts.newAirportLoader()
+	finder := application.NewFindAirportsService(opts.newLogger("airportLoader"), loader, flightRepository)
+	fareCalendar := application.NewFareCalendarService(opts.newLogger("fareCalendar"),
+		finder, flightRepository, orchestrator)
+
+	ctx, cancel := search.newContext()
+	defer cancel()
+	calendar, err := fareCalendar.FindFareCalendar(ctx, arguments, *maxAge)
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		return opts.writeJSON(calendar)
+	}
+	fareCalendar.OutputFareCalendar(calendar)
+	return nil
+}
+
+// runHistory outputs the price history of previous searches for the origin, destination and outbound date.
+func runHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	overrides := addArgumentFlags(flags)
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := loadArguments(opts, overrides)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	history := application.NewPriceHistoryService(opts.newLogger("priceHistory"), flightRepository)
+	runs, err := history.ShowPriceHistory(arguments)
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		return opts.writeJSON(runs)
+	}
+	return nil
+}
+
+// runAirports outputs the airports within a country and region, or that best match the search text, or runs one of
+// the airport data sub-commands.
+func runAirports(args []string) error {
+	if len(args) > 0 && args[0] == "update" {
+		return runAirportsUpdate(args[1:])
+	} else if len(args) > 0 && args[0] == "rollback" {
+		return runAirportsRollback(args[1:])
+	} else if len(args) > 0 && args[0] == "import" {
+		return runAirportsImport(args[1:])
+	}
+
+	flags := flag.NewFlagSet("airports", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	country := flags.String("country", "United Kingdom", "country name")
+	region := flags.String("region", "England", "region name")
+	exclude := flags.String("exclude", "RAF ", "exclude airports whose names start with this prefix")
+	search := flags.String("search", "",
+		"find the airports that best match this text, e.g. a name, municipality or code, rather than by country")
+	limit := flags.Int("limit", 10, "maximum number of airports found by -search")
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	loader := opts.newAirportLoader()
+	service := application.NewFindAirportsService(opts.newLogger("findAirportsService"), loader, flightRepository)
+	if *search != "" {
+		matches, err := service.SearchAirports(*search, *limit)
+		if err != nil {
+			return err
+		}
+		if opts.format == "json" {
+			return opts.writeJSON(matches)
+		}
+		return service.OutputAirportMatches(os.Stdout, matches)
+	}
+
+	airports, err := service.FindAirports(*country, *region, *exclude)
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		return opts.writeJSON(airports)
+	}
+	return nil
+}
+
+// runAirportsImport imports the airports from the airport data files into the database, then outputs how many were
+// imported and any problems with the files ignored by -lenient-airports.
+func runAirportsImport(args []string) error {
+	flags := flag.NewFlagSet("airports import", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	service := application.NewFindAirportsService(opts.newLogger("findAirportsService"), opts.newAirportLoader(),
+		flightRepository)
+	airports, warnings, err := service.ImportAirports()
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		messages := []string{}
+		for _, warning := range warnings {
+			messages = append(messages, warning.Error())
+		}
+		return opts.writeJSON(struct {
+			Airports int
+			Warnings []string
+		}{len(airports), messages})
+	}
+	return nil
+}
+
+// runAirportsUpdate downloads the latest airport data files, then outputs the new dataset.
+func runAirportsUpdate(args []string) error {
+	flags := flag.NewFlagSet("airports update", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	baseURL := flags.String("airports-url", framework.DefaultAirportDataURL,
+		"base URL to download the airport data files from, e.g. file:///srv/mirror/ for a local mirror")
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	updater := framework.NewAirportDataUpdater(opts.newLogger("airportDataUpdater"),
+		framework.AirportDataUpdaterOptions{BaseURL: *baseURL})
+	service := application.NewUpdateAirportsService(opts.newLogger("updateAirports"), updater,
+		opts.newAirportLoader(), flightRepository)
+
+	ctx, cancel := newInterruptContext(0)
+	defer cancel()
+	dataset, err := service.UpdateAirports(ctx)
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		return opts.writeJSON(dataset)
+	}
+	return nil
+}
+
+// runAirportsRollback restores the airport data files replaced by the last update, then outputs the dataset now in
+// use (if known).
+func runAirportsRollback(args []string) error {
+	flags := flag.NewFlagSet("airports rollback", flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	err := parseFlags(flags, opts, args)
+	if err != nil {
+		return err
+	}
+
+	db, flightRepository, err := openRepository(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	updater := framework.NewAirportDataUpdater(opts.newLogger("airportDataUpdater"),
+		framework.AirportDataUpdaterOptions{})
+	service := application.NewUpdateAirportsService(opts.newLogger("updateAirports"), updater,
+		opts.newAirportLoader(), flightRepository)
+	dataset, err := service.RollbackAirports()
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		return opts.writeJSON(dataset)
+	}
+	return nil
+}
+
+// runDatabase runs one of the database maintenance sub-commands.
+func runDatabase(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Expected a db command: init, reset or migrate")
+	}
+
+	command := args[0]
+	flags := flag.NewFlagSet("db "+command, flag.ExitOnError)
+	opts := addCommonFlags(flags)
+	err := parseFlags(flags, opts, args[1:])
+	if err != nil {
+		return err
+	}
+	logger := opts.newLogger("flightchecker")
+
+	var recreate bool
+	switch command {
+	case "init", "migrate":
+		recreate = false
+	case "reset":
+		recreate = true
+	default:
+		return fmt.Errorf("Unknown db command %s", command)
+	}
+
+	db, err := framework.OpenDatabase(opts.databaseFile, recreate)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	flightRepository := framework.NewFlightRepository(opts.newLogger("sqliteRepository"), db)
+
+	version, err := flightRepository.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if command == "init" && version != 0 {
+		return fmt.Errorf("Database %s is already initialised, with schema version %d", opts.databaseFile, version)
+	}
+
+	err = flightRepository.MigrateSchema()
+	if err != nil {
+		return err
+	}
+	logger.Infof("Database %s migrated from schema version %d to %d", opts.databaseFile, version,
+		flightRepository.LatestSchemaVersion())
+	return nil
+}
+
+// openRepository opens the database, creating the schema if the database is new. An existing database with an older
+// schema must be upgraded first, using "db migrate".
+func openRepository(opts *options) (*sql.DB, *framework.FlightRepository, error) {
+	return framework.OpenFlightRepository(opts.newLogger("sqliteRepository"), opts.databaseFile)
+}
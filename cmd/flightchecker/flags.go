@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/application"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/chrisnappin/flightchecker/pkg/framework"
+)
+
+// options holds the flags common to every command.
+type options struct {
+	argumentsFile   string
+	databaseFile    string
+	logLevel        string
+	format          string
+	lenientAirports bool
+}
+
+// addCommonFlags defines the common flags on the flag set.
+func addCommonFlags(flags *flag.FlagSet) *options {
+	opts := &options{}
+	flags.StringVar(&opts.argumentsFile, "arguments", "arguments.json", "JSON file of quote arguments, or \"\" for none")
+	flags.StringVar(&opts.databaseFile, "db", "./data/flightchecker.db", "SQLite database file")
+	flags.StringVar(&opts.logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	flags.StringVar(&opts.format, "format", "text", "output format: text or json")
+	flags.BoolVar(&opts.lenientAirports, "lenient-airports", false,
+		"keep airports with unknown country or region codes, and skip other invalid airport data, with a warning for "+
+			"each, rather than failing")
+	return opts
+}
+
+// validate checks the common flags have valid values.
+func (opts *options) validate() error {
+	if opts.format != "text" && opts.format != "json" {
+		return fmt.Errorf("Unknown format %s", opts.format)
+	}
+	_, err := framework.NewLogWrapperWithOptions("flightchecker", framework.LogOptions{Level: opts.logLevel})
+	return err
+}
+
+// newLogger creates a logger at the configured level. With JSON output, logs are written to stderr so stdout only
+// contains the JSON.
+func (opts *options) newLogger(name string) *framework.LogWrapper {
+	var output io.Writer = os.Stdout
+	if opts.format == "json" {
+		output = os.Stderr
+	}
+	logger, _ := framework.NewLogWrapperWithOptions(name, framework.LogOptions{Level: opts.logLevel, Output: output})
+	return logger // level already validated
+}
+
+// newAirportLoader creates a loader of the airport data files, in lenient mode if configured.
+func (opts *options) newAirportLoader() *framework.AirportDataLoaderService {
+	return framework.NewAirportDataLoaderWithOptions(opts.newLogger("airportDataLoader"),
+		framework.AirportDataOptions{Lenient: opts.lenientAirports})
+}
+
+// writeJSON writes the value to stdout as indented JSON.
+func (opts *options) writeJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// argumentFlags holds flags that override individual fields of the arguments file.
+type argumentFlags struct {
+	flags             *flag.FlagSet
+	overrides         domain.Arguments
+	originFilter      domain.AirportFilter
+	destinationFilter domain.AirportFilter
+	groupPricing      bool
+}
+
+// addArgumentFlags defines the argument override flags on the flag set.
+func addArgumentFlags(flags *flag.FlagSet) *argumentFlags {
+	a := &argumentFlags{flags: flags}
+	flags.StringVar(&a.overrides.Origin, "origin", "", "origin IATA airport code, or several separated by commas")
+	flags.StringVar(&a.originFilter.Country, "origin-country", "", "also search from every airport in this country")
+	flags.StringVar(&a.originFilter.Region, "origin-region", "", "also search from every airport in this region")
+	flags.StringVar(&a.originFilter.ExcludePrefix, "origin-exclude", "",
+		"exclude origin airports whose names start with this prefix")
+	flags.StringVar(&a.overrides.Destination, "destination", "",
+		"destination IATA airport code, or several separated by commas")
+	flags.StringVar(&a.destinationFilter.Country, "destination-country", "",
+		"also search to every airport in this country")
+	flags.StringVar(&a.destinationFilter.Region, "destination-region", "", "also search to every airport in this region")
+	flags.StringVar(&a.destinationFilter.ExcludePrefix, "destination-exclude", "",
+		"exclude destination airports whose names start with this prefix")
+	flags.StringVar((*string)(&a.overrides.TripType), "trip", "", "trip type: return, oneway or openjaw")
+	flags.StringVar(&a.overrides.ReturnOrigin, "return-origin", "",
+		"IATA airport code to fly home from, for open-jaw trips")
+	flags.IntVar(&a.overrides.Adults, "adults", 0, "number of adults (over 16)")
+	flags.IntVar(&a.overrides.Children, "children", 0, "number of children (1-16)")
+	flags.IntVar(&a.overrides.Infants, "infants", 0, "number of infants (0-12 months)")
+	flags.StringVar(&a.overrides.OutboundDate, "outbound-date", "", "outbound date, as YYYY-MM-DD")
+	flags.StringVar(&a.overrides.LatestOutboundDate, "latest-outbound-date", "",
+		"latest outbound date to search, as YYYY-MM-DD")
+	flags.IntVar(&a.overrides.HolidayDuration, "nights", 0, "holiday duration, in nights")
+	flags.IntVar(&a.overrides.MaxHolidayDuration, "max-nights", 0, "longest holiday duration to search, in nights")
+	flags.StringVar(&a.overrides.CabinClass, "cabin", "",
+		"cabin class: economy, premiumeconomy, business or first (default economy)")
+	flags.StringVar(&a.overrides.Currency, "currency", "", "ISO 4217 currency code to price in (default GBP)")
+	flags.StringVar(&a.overrides.Country, "country", "", "ISO 3166 country code of the market to search (default GB)")
+	flags.StringVar(&a.overrides.Locale, "locale", "", "locale of the results (default en-GB)")
+	flags.BoolVar(&a.groupPricing, "group-pricing", domain.DefaultGroupPricing,
+		"price for all passengers, or false for 1 adult")
+	flags.StringVar(&a.overrides.APIHost, "api-host", "", "rapidapi host")
+	flags.StringVar(&a.overrides.APIKey, "api-key", "", "rapidapi key")
+	return a
+}
+
+// apply copies the value of every override flag that was set onto the arguments.
+func (a *argumentFlags) apply(arguments *domain.Arguments) {
+	a.flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "origin":
+			arguments.Origin, arguments.Origins = splitCodes(a.overrides.Origin)
+		case "origin-country", "origin-region", "origin-exclude":
+			arguments.OriginFilter = &a.originFilter
+		case "destination":
+			arguments.Destination, arguments.Destinations = splitCodes(a.overrides.Destination)
+		case "destination-country", "destination-region", "destination-exclude":
+			arguments.DestinationFilter = &a.destinationFilter
+		case "trip":
+			arguments.TripType = a.overrides.TripType
+		case "return-origin":
+			arguments.ReturnOrigin = a.overrides.ReturnOrigin
+		case "adults":
+			arguments.Adults = a.overrides.Adults
+		case "children":
+			arguments.Children = a.overrides.Children
+		case "infants":
+			arguments.Infants = a.overrides.Infants
+		case "outbound-date":
+			arguments.OutboundDate = a.overrides.OutboundDate
+		case "latest-outbound-date":
+			arguments.LatestOutboundDate = a.overrides.LatestOutboundDate
+		case "nights":
+			arguments.HolidayDuration = a.overrides.HolidayDuration
+		case "max-nights":
+			arguments.MaxHolidayDuration = a.overrides.MaxHolidayDuration
+		case "cabin":
+			arguments.CabinClass = a.overrides.CabinClass
+		case "currency":
+			arguments.Currency = a.overrides.Currency
+		case "country":
+			arguments.Country = a.overrides.Country
+		case "locale":
+			arguments.Locale = a.overrides.Locale
+		case "group-pricing":
+			arguments.GroupPricing = &a.groupPricing
+		case "api-host":
+			arguments.APIHost = a.overrides.APIHost
+		case "api-key":
+			arguments.APIKey = a.overrides.APIKey
+		}
+	})
+}
+
+// searchFlags holds the flags that control how searches are run.
+type searchFlags struct {
+	workers           int
+	requestsPerMinute int
+	timeout           time.Duration
+	pageSize          int
+	retries           int
+	apiURL            string
+	requestTimeout    time.Duration
+	userAgent         string
+	proxy             string
+	record            string
+	replay            string
+	poll              application.PollStrategy
+	providers         string
+	fareFile          string
+}
+
+// addSearchFlags defines the search flags on the flag set.
+func addSearchFlags(flags *flag.FlagSet) *searchFlags {
+	s := &searchFlags{}
+	flags.IntVar(&s.workers, "workers", 4, "maximum number of searches to run at a time")
+	flags.IntVar(&s.requestsPerMinute, "requests-per-minute", 0,
+		"maximum number of requests to sky scanner per minute, across all searches, or 0 for no limit")
+	flags.DurationVar(&s.timeout, "timeout", 0, "give up on searches still running after this long, or 0 for no limit")
+	flags.IntVar(&s.pageSize, "page-size", framework.DefaultPageSize,
+		"number of itineraries to fetch per request, once a search completes")
+	flags.IntVar(&s.retries, "retries", framework.DefaultRetryPolicy.MaxAttempts-1,
+		"number of times to retry a request that fails with a network error, rate limit or server error")
+
+	defaults := framework.DefaultSkyScannerOptions()
+	flags.StringVar(&s.apiURL, "api-url", "",
+		"base URL of the sky scanner API, e.g. http://localhost:8080, or empty for https:// then the API host")
+	flags.DurationVar(&s.requestTimeout, "request-timeout", defaults.RequestTimeout,
+		"give up on each request to sky scanner after this long, or 0 for no limit")
+	flags.StringVar(&s.userAgent, "user-agent", defaults.UserAgent, "user agent of requests to sky scanner")
+	flags.StringVar(&s.proxy, "proxy", "",
+		"URL of the proxy for requests to sky scanner, or empty to use the HTTPS_PROXY environment variable")
+	flags.StringVar(&s.record, "record", "", "file to record every request to sky scanner and its response to")
+	flags.StringVar(&s.replay, "replay", "",
+		"file of recorded requests to sky scanner, to replay the responses of rather than calling sky scanner")
+
+	poll := application.DefaultPollStrategy()
+	flags.DurationVar(&s.poll.InitialDelay, "poll-delay", poll.InitialDelay, "wait before first polling a search")
+	flags.DurationVar(&s.poll.Interval, "poll-interval", poll.Interval, "wait between the first and second polls")
+	flags.Float64Var(&s.poll.Backoff, "poll-backoff", poll.Backoff,
+		"multiply the wait between polls by this after each poll, or 1 for a fixed wait")
+	flags.DurationVar(&s.poll.MaxInterval, "poll-max-interval", poll.MaxInterval, "longest wait between polls")
+	flags.DurationVar(&s.poll.Deadline, "poll-deadline", poll.Deadline,
+		"stop polling a search this long after the first poll")
+	flags.BoolVar(&s.poll.AcceptPartial, "accept-partial", poll.AcceptPartial,
+		"use the quotes found so far if a search isn't complete by the poll deadline, rather than failing")
+
+	flags.StringVar(&s.providers, "providers", application.SkyScannerProviderName,
+		"comma separated sources of quotes, skyscanner and/or farefile, merged if more than one")
+	flags.StringVar(&s.fareFile, "fare-file", "", "JSON or CSV file of fares, for the farefile source of quotes")
+	return s
+}
+
+// newOrchestrator creates a search orchestrator with the configured number of workers and rate limit.
+func (s *searchFlags) newOrchestrator(opts *options, flightRepository application.FlightRepository) (
+	*application.SearchOrchestrator, error) {
+	if s.workers < 1 {
+		return nil, fmt.Errorf("Invalid number of workers %d", s.workers)
+	}
+	if s.pageSize < 1 {
+		return nil, fmt.Errorf("Invalid page size %d", s.pageSize)
+	}
+	if s.retries < 0 {
+		return nil, fmt.Errorf("Invalid number of retries %d", s.retries)
+	}
+	if s.requestsPerMinute < 0 {
+		return nil, fmt.Errorf("Invalid requests per minute %d", s.requestsPerMinute)
+	}
+	err := s.poll.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.newProvider(opts, flightRepository)
+	if err != nil {
+		return nil, err
+	}
+	return application.NewSearchOrchestrator(opts.newLogger("searchOrchestrator"), provider, flightRepository,
+		s.workers), nil
+}
+
+// newProvider creates the configured source of quotes, or one that merges several sources. Sky scanner search
+// sessions are saved to the repository, so interrupted searches can be resumed.
+func (s *searchFlags) newProvider(opts *options, flightRepository application.FlightRepository) (
+	application.FlightQuoteProvider, error) {
+	providers := []application.FlightQuoteProvider{}
+	for _, name := range strings.Split(s.providers, ",") {
+		switch strings.TrimSpace(name) {
+		case application.SkyScannerProviderName:
+			skyScannerOptions, err := s.skyScannerOptions()
+			if err != nil {
+				return nil, err
+			}
+			skyscanner := framework.NewSkyScannerService(opts.newLogger("skyscannerQuoter"), skyScannerOptions)
+			providers = append(providers, application.NewSkyScannerProvider(opts.newLogger("skyscannerProvider"),
+				skyscanner, s.poll, flightRepository))
+
+		case framework.FareFileProviderName:
+			if s.fareFile == "" {
+				return nil, errors.New("The farefile source of quotes needs -fare-file")
+			}
+			fareFile, err := framework.NewFareFileProvider(opts.newLogger("fareFileProvider"), s.fareFile)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, fareFile)
+
+		default:
+			return nil, fmt.Errorf("Unknown source of quotes %s, expected %s or %s", name,
+				application.SkyScannerProviderName, framework.FareFileProviderName)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return application.NewMultiProvider(opts.newLogger("multiProvider"), providers...), nil
+}
+
+// skyScannerOptions returns the configured options for calling sky scanner.
+func (s *searchFlags) skyScannerOptions() (framework.SkyScannerOptions, error) {
+	skyScannerOptions := framework.DefaultSkyScannerOptions()
+	skyScannerOptions.PageSize = s.pageSize
+	skyScannerOptions.Retry.MaxAttempts = s.retries + 1
+	skyScannerOptions.RequestTimeout = s.requestTimeout
+	skyScannerOptions.UserAgent = s.userAgent
+	if s.requestsPerMinute > 0 {
+		skyScannerOptions.Limiter = framework.NewTokenBucket(s.requestsPerMinute, s.workers)
+	}
+
+	if s.apiURL != "" {
+		apiURL, err := url.Parse(s.apiURL)
+		if err != nil || (apiURL.Scheme != "http" && apiURL.Scheme != "https") || apiURL.Host == "" {
+			return skyScannerOptions, fmt.Errorf("Invalid API URL %s, expected e.g. http://localhost:8080", s.apiURL)
+		}
+		skyScannerOptions.BaseURL = s.apiURL
+	}
+
+	var transport http.RoundTripper
+	if s.proxy != "" {
+		proxyURL, err := url.Parse(s.proxy)
+		if err != nil || proxyURL.Host == "" {
+			return skyScannerOptions, fmt.Errorf("Invalid proxy URL %s", s.proxy)
+		}
+		proxyTransport := http.DefaultTransport.(*http.Transport).Clone()
+		proxyTransport.Proxy = http.ProxyURL(proxyURL)
+		transport = proxyTransport
+	}
+
+	switch {
+	case s.record != "" && s.replay != "":
+		return skyScannerOptions, errors.New("Only one of -record and -replay can be set")
+	case s.record != "":
+		transport = framework.NewRecordingTransport(transport, s.record)
+	case s.replay != "":
+		replayTransport, err := framework.NewReplayTransport(s.replay)
+		if err != nil {
+			return skyScannerOptions, err
+		}
+		transport = replayTransport
+	}
+
+	if transport != nil {
+		skyScannerOptions.Client = &http.Client{Transport: transport}
+	}
+	return skyScannerOptions, nil
+}
+
+// newContext returns a context that is cancelled by an interrupt (Ctrl-C), or once the timeout (if any) has passed.
+// The returned function must be called to release its resources.
+func (s *searchFlags) newContext() (context.Context, context.CancelFunc) {
+	return newInterruptContext(s.timeout)
+}
+
+// newInterruptContext returns a context that is cancelled by an interrupt (Ctrl-C), or once the timeout has passed if
+// not zero. The returned function must be called to release its resources.
+func newInterruptContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		cancel() // replaced by the timeout's cancel
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupts)
+		cancel()
+	}
+}
+
+// splitCodes returns the first of a comma separated list of airport codes, and any others.
+func splitCodes(list string) (string, []string) {
+	codes := strings.Split(list, ",")
+	for index, code := range codes {
+		codes[index] = strings.TrimSpace(code)
+	}
+	return codes[0], codes[1:]
+}
+
+// loadArguments loads the arguments file (if set), then applies any override flags.
+func loadArguments(opts *options, overrides *argumentFlags) (*domain.Arguments, error) {
+	arguments := &domain.Arguments{}
+	if opts.argumentsFile != "" {
+		var err error
+		arguments, err = framework.NewArgumentsLoader(opts.newLogger("argumentsLoader")).Load(opts.argumentsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	overrides.apply(arguments)
+	return arguments, nil
+}
+
+// parseFlags parses the command line arguments, then validates the common flags.
+func parseFlags(flags *flag.FlagSet, opts *options, args []string) error {
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("Unexpected arguments %v", flags.Args())
+	}
+	return opts.validate()
+}
@@ -1,31 +1,58 @@
 package main
 
 import (
+	"fmt"
 	"os"
-
-	"github.com/chrisnappin/flightchecker/pkg/application"
-	"github.com/chrisnappin/flightchecker/pkg/framework"
 )
 
-func main() {
-	mainLogger := framework.NewLogWrapper("flightchecker", true)
-	loader := framework.NewAirportDataLoader(framework.NewLogWrapper("airportDataLoader", true))
-	finder := application.NewFindAirportsService(framework.NewLogWrapper("airportLoader", true), loader)
-	argumentsLoader := framework.NewArgumentsLoader(framework.NewLogWrapper("argumentsLoader", true))
-	skyscanner := framework.NewSkyScannerService(framework.NewLogWrapper("skyscannerQuoter", true))
+const usage = `Usage: flightchecker <command> [flags]
 
-	recreateDatabase := true // TODO - set this via command line flag
-	db, err := framework.OpenDatabase("./data/flightchecker.db", recreateDatabase)
-	if err != nil {
-		mainLogger.Fatal(err)
-	}
-	defer db.Close()
+Commands:
+  quote              search for flight quotes, and save them
+  resume             resume searches that were interrupted before their quotes were complete
+  calendar           search a range of outbound dates and holiday durations, for the cheapest of each
+  history            show how prices have changed across previous searches
+  airports           list the airports within a country and region
+  airports update    download the latest airport data files
+  airports rollback  restore the airport data files replaced by the last update
+  airports import    import the airport data files into the database, after changing them by hand
+  db init            create a new database
+  db reset           delete the database, then create a new one
+  db migrate         upgrade the database to the latest schema
+
+Run "flightchecker <command> -h" for the flags of each command.
+`
 
-	flightRepository := framework.NewFlightRepository(framework.NewLogWrapper("sqliteRepository", true), db)
-	flightQuoter := application.NewQuoteForFlightsService(framework.NewLogWrapper("quoteForFlights", true),
-		argumentsLoader, finder, skyscanner, flightRepository)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
 
-	flightQuoter.QuoteForFlights("arguments.json")
+	var err error
+	switch os.Args[1] {
+	case "quote":
+		err = runQuote(os.Args[2:])
+	case "resume":
+		err = runResume(os.Args[2:])
+	case "calendar":
+		err = runCalendar(os.Args[2:])
+	case "history":
+		err = runHistory(os.Args[2:])
+	case "airports":
+		err = runAirports(os.Args[2:])
+	case "db":
+		err = runDatabase(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
 
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flightchecker: %s\n", err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
@@ -10,6 +10,11 @@ type AirportDataLoader struct {
 	mock.Mock
 }
 
+// ClearWarnings provides a mock function with given fields:
+func (_m *AirportDataLoader) ClearWarnings() {
+	_m.Called()
+}
+
 // LoadAirports provides a mock function with given fields: filename, countries, regions
 func (_m *AirportDataLoader) LoadAirports(filename string, countries map[string]string, regions map[string]string) (map[string]domain.Airport, error) {
 	ret := _m.Called(filename, countries, regions)
@@ -78,3 +83,19 @@ func (_m *AirportDataLoader) LoadRegions(filename string) (map[string]string, er
 
 	return r0, r1
 }
+
+// Warnings provides a mock function with given fields:
+func (_m *AirportDataLoader) Warnings() []error {
+	ret := _m.Called()
+
+	var r0 []error
+	if rf, ok := ret.Get(0).(func() []error); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	return r0
+}
@@ -1,6 +1,8 @@
 package application
 
 import (
+	"context"
+
 	"github.com/chrisnappin/flightchecker/pkg/domain"
 )
 
@@ -8,37 +10,81 @@ import (
 // Interfaces for framework services...
 //
 
-// AirportDataLoader handles being able to load CSV data
+// AirportDataLoader handles being able to load CSV data. Problems ignored in lenient mode are kept as warnings, across
+// loads until cleared.
 type AirportDataLoader interface {
 	LoadCountries(filename string) (map[string]string, error)
 	LoadRegions(filename string) (map[string]string, error)
 	LoadAirports(filename string, countries map[string]string, regions map[string]string) (map[string]domain.Airport, error)
+	Warnings() []error
+	ClearWarnings()
 }
 
-// ArgumentsLoader handles being able to load arguments from a JSON file.
-type ArgumentsLoader interface {
-	Load(filename string) (*domain.Arguments, error)
+// AirportDataUpdater handles downloading new versions of the airport data files, and replacing the current files
+// with them while keeping the previous version.
+type AirportDataUpdater interface {
+	Download(ctx context.Context, dir string, filenames []string) (*domain.AirportDataset, string, error)
+	Install(dir string, stagingDir string, filenames []string) (bool, error)
+	Discard(stagingDir string) error
+	Rollback(dir string, filenames []string) error
+	Remove(dir string, filenames []string) error
 }
 
 // SkyScannerQuoter handles finding flight quotes from Sky Scanner.
 type SkyScannerQuoter interface {
-	PollForQuotes(sessionKey string, apiHost string, apiKey string, airports map[string]domain.Airport) (*domain.Quote, error)
-	StartSearch(arguments *domain.Arguments) (string, error)
+	PollForQuotes(ctx context.Context, sessionKey string, apiHost string, apiKey string,
+		airports map[string]domain.Airport) (*domain.Quote, error)
+	StartSearch(ctx context.Context, arguments *domain.Arguments) (string, error)
+	GetBookingDetails(ctx context.Context, link *domain.BookingDetailsLink, apiHost string, apiKey string) (
+		*domain.BookingDetails, error)
 }
 
 // FlightRepository handles saving and loading flight data
 type FlightRepository interface {
-	InitialiseSchema() error
-	CreateAirports(airports []domain.Airport) error
+	ImportAirports(airports []domain.Airport, dataset *domain.AirportDataset) error
 	ReadAllAirports() ([]domain.Airport, error)
+	SaveQuote(quote *domain.Quote) error
+	ReadQuote(id int64) (*domain.Quote, error)
+	ReadAllQuotes() ([]*domain.Quote, error)
+	ReadCurrencyFormat(code string) (*domain.CurrencyFormat, error)
+	SaveSearchRun(run *domain.SearchRun) error
+	ReadPriceHistory(arguments *domain.Arguments) ([]*domain.SearchRun, error)
+	ReadLatestSearchRun(arguments *domain.Arguments) (*domain.SearchRun, error)
+	SaveSearchSession(session *domain.SearchSession) error
+	ReadSearchSessions() ([]*domain.SearchSession, error)
+	DeleteSearchSession(sessionKey string) error
+	ReadLatestAirportDataset() (*domain.AirportDataset, error)
+	DeleteAirportDataset(version int64) error
 }
 
 //
 // Interfaces for application services...
 //
 
+// FlightQuoteProvider handles finding quotes for flights from a single source, e.g. sky scanner or a fare file. Quotes
+// are for the origin, destination, dates and currency of the arguments, and may leave the origin, destination and
+// provider of each itinerary empty for the caller to fill in.
+type FlightQuoteProvider interface {
+	Name() string
+	Quote(ctx context.Context, arguments *domain.Arguments, airports map[string]domain.Airport) (*domain.Quote, error)
+}
+
+// BookingDetailsFinder is implemented by providers that can find the ways to book their itineraries.
+type BookingDetailsFinder interface {
+	FindBookingDetails(ctx context.Context, arguments *domain.Arguments, itinerary *domain.Itinerary) (
+		*domain.BookingDetails, error)
+}
+
+// SearchResumer is implemented by providers that can resume a search that was interrupted before its quotes were
+// complete. The API host and key aren't saved with the session, so are given by the caller.
+type SearchResumer interface {
+	ResumeSearch(ctx context.Context, session *domain.SearchSession, apiHost string, apiKey string,
+		airports map[string]domain.Airport) (*domain.Quote, error)
+}
+
 // AirportFinder handles being able to load airport datasets
 type AirportFinder interface {
-	FindAirports(countryName string, regionName string, excludePrefix string) error
+	FindAirports(countryName string, regionName string, excludePrefix string) ([]domain.Airport, error)
 	LoadMajorAirports() (map[string]domain.Airport, error)
+	ImportAirports() (map[string]domain.Airport, []error, error)
 }
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// FareCalendarService handles finding the cheapest quotes across a range of outbound dates and holiday durations.
+type FareCalendarService struct {
+	logger           domain.Logger
+	finder           AirportFinder
+	flightRepository FlightRepository
+	orchestrator     *SearchOrchestrator
+}
+
+// NewFareCalendarService creates a new instance.
+func NewFareCalendarService(logger domain.Logger, finder AirportFinder, flightRepository FlightRepository,
+	orchestrator *SearchOrchestrator) *FareCalendarService {
+	return &FareCalendarService{logger, finder, flightRepository, orchestrator}
+}
+
+// FindFareCalendar searches for every combination of outbound date and holiday duration within the ranges of the
+// arguments, between every origin and destination airport, saving each quote to the repository, and returns the
+// cheapest itinerary of each date and duration. Saved quotes no older than maxCacheAge are used rather than searching
+// again (a maxCacheAge of zero always searches).
+// Searches that fail are left empty in the calendar, unless every search fails or the context is done.
+func (service *FareCalendarService) FindFareCalendar(ctx context.Context, arguments *domain.Arguments,
+	maxCacheAge time.Duration) (*domain.FareCalendar, error) {
+	arguments = arguments.WithDefaults()
+	err := arguments.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	airports, err := service.finder.LoadMajorAirports()
+	if err != nil {
+		return nil, err
+	}
+
+	origins, err := arguments.OriginAirports(airports)
+	if err != nil {
+		return nil, err
+	}
+	destinations, err := arguments.DestinationAirports(airports)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := arguments.ExpandAirports(airports)
+	if err != nil {
+		return nil, err
+	}
+
+	dates, err := arguments.OutboundDates()
+	if err != nil {
+		return nil, err
+	}
+	durations, err := arguments.HolidayDurations()
+	if err != nil {
+		return nil, err
+	}
+	searches := []*domain.Arguments{}
+	for _, route := range routes {
+		routeSearches, err := route.ExpandDates()
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, routeSearches...)
+	}
+
+	service.logger.Infof("Looking for flights from %s to %s, leaving %s to %s, staying for %d to %d nights",
+		airportCodes(origins), airportCodes(destinations), dates[0], dates[len(dates)-1],
+		durations[0], durations[len(durations)-1])
+
+	calendar := domain.NewFareCalendar(arguments.Trip(), arguments.Currency, dates, durations)
+	results, err := succeeded(ctx, service.logger,
+		service.orchestrator.SearchAll(ctx, searches, airports, maxCacheAge))
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Quote.CurrencyFormat != nil {
+			calendar.CurrencyFormat = result.Quote.CurrencyFormat
+		}
+		calendar.Add(result.Arguments.OutboundDate, result.Arguments.HolidayDuration, result.Quote.Cheapest())
+	}
+	return calendar, nil
+}
+
+// airportCodes returns the IATA codes of the airports, separated by commas.
+func airportCodes(airports []domain.Airport) string {
+	codes := make([]string, len(airports))
+	for index, airport := range airports {
+		codes[index] = airport.IataCode
+	}
+	return strings.Join(codes, ",")
+}
+
+// OutputFareCalendar logs the cheapest price of each outbound date (rows) and holiday duration (columns).
+func (service *FareCalendarService) OutputFareCalendar(calendar *domain.FareCalendar) {
+	const columnWidth = 12
+
+	service.logger.Infof("Cheapest prices, in %s", calendar.Currency)
+	header := fmt.Sprintf("%-*s", columnWidth, "Outbound")
+	for _, duration := range calendar.HolidayDurations {
+		label := fmt.Sprintf("%d nights", duration)
+		if calendar.TripType == domain.OneWayTrip {
+			label = "One way"
+		}
+		header += fmt.Sprintf(" %*s", columnWidth, label)
+	}
+	service.logger.Info(header)
+
+	for dateIndex, date := range calendar.OutboundDates {
+		row := strings.Builder{}
+		row.WriteString(fmt.Sprintf("%-*s", columnWidth, date))
+		for _, itinerary := range calendar.Cheapest[dateIndex] {
+			price := "-"
+			if itinerary != nil {
+				price = itinerary.Price.Format(calendar.CurrencyFormat)
+			}
+			row.WriteString(fmt.Sprintf(" %*s", columnWidth, price))
+		}
+		service.logger.Info(row.String())
+	}
+}
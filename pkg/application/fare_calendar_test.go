@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestFareCalendarService returns a service with mock dependencies, that knows about the dummy airports.
+func newTestFareCalendarService() (*FareCalendarService, *mocks.SkyScannerQuoter, *mocks.FlightRepository) {
+	mockLogger := &mocks.Logger{}
+	mockFinder := &mocks.AirportFinder{}
+	mockQuoter := &mocks.SkyScannerQuoter{}
+	mockRepository := &mocks.FlightRepository{}
+
+	allowLogging(mockLogger)
+	mockFinder.On("LoadMajorAirports").Return(dummyAirports, nil)
+	mockRepository.On("SaveQuote", mock.Anything).Return(nil)
+	mockRepository.On("SaveSearchRun", mock.Anything).Return(nil)
+
+	orchestrator := NewSearchOrchestrator(mockLogger,
+		NewSkyScannerProvider(mockLogger, mockQuoter, testPollStrategy, nil), mockRepository, 2)
+	return NewFareCalendarService(mockLogger, mockFinder, mockRepository, orchestrator), mockQuoter, mockRepository
+}
+
+// TestFindFareCalendar_HappyPath tests searching two outbound dates, one of which has no flights.
+func TestFindFareCalendar_HappyPath(t *testing.T) {
+	service, mockQuoter, _ := newTestFareCalendarService()
+
+	arguments := dummyArguments
+	arguments.LatestOutboundDate = "2019-11-02"
+
+	first := dummyArguments
+	second := dummyArguments
+	second.OutboundDate = "2019-11-02"
+
+	cheap := &domain.Itinerary{Price: domain.Money{Amount: 100, Currency: "GBP"}}
+	expensive := &domain.Itinerary{Price: domain.Money{Amount: 200, Currency: "GBP"}}
+	mockQuoter.On("StartSearch", mock.Anything, &first).Return("first", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "first", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Itineraries: []*domain.Itinerary{expensive, cheap}, Complete: true}, nil)
+	mockQuoter.On("StartSearch", mock.Anything, &second).Return("second", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "second", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Itineraries: []*domain.Itinerary{}, Complete: true}, nil)
+
+	expected := &domain.FareCalendar{
+		TripType:         domain.ReturnTrip,
+		Currency:         "GBP",
+		OutboundDates:    []string{"2019-11-01", "2019-11-02"},
+		HolidayDurations: []int{14},
+		Cheapest:         [][]*domain.Itinerary{[]*domain.Itinerary{cheap}, []*domain.Itinerary{nil}},
+	}
+
+	result, err := service.FindFareCalendar(context.Background(), &arguments, 0)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, expected, result, "Wrong result")
+	mockQuoter.AssertExpectations(t)
+}
+
+// TestFindFareCalendar_InvalidRange tests searching with an invalid range.
+func TestFindFareCalendar_InvalidRange(t *testing.T) {
+	service, mockQuoter, _ := newTestFareCalendarService()
+
+	arguments := dummyArguments
+	arguments.MaxHolidayDuration = 1
+
+	result, err := service.FindFareCalendar(context.Background(), &arguments, 0)
+	assert.Nil(t, result, "Expected no result")
+	assert.Error(t, err, "Expected an error")
+	mockQuoter.AssertNotCalled(t, "StartSearch", mock.Anything, mock.Anything)
+}
+
+// TestFindFareCalendar_SomeSearchesFail tests the calendar is still returned when only some searches fail.
+func TestFindFareCalendar_SomeSearchesFail(t *testing.T) {
+	service, mockQuoter, _ := newTestFareCalendarService()
+
+	arguments := dummyArguments
+	arguments.LatestOutboundDate = "2019-11-02"
+
+	second := dummyArguments
+	second.OutboundDate = "2019-11-02"
+
+	cheap := &domain.Itinerary{Price: domain.Money{Amount: 100, Currency: "GBP"}}
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("", errors.New("Oops"))
+	mockQuoter.On("StartSearch", mock.Anything, &second).Return("second", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "second", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Itineraries: []*domain.Itinerary{cheap}, Complete: true}, nil)
+
+	expected := &domain.FareCalendar{
+		TripType:         domain.ReturnTrip,
+		Currency:         "GBP",
+		OutboundDates:    []string{"2019-11-01", "2019-11-02"},
+		HolidayDurations: []int{14},
+		Cheapest:         [][]*domain.Itinerary{[]*domain.Itinerary{nil}, []*domain.Itinerary{cheap}},
+	}
+
+	result, err := service.FindFareCalendar(context.Background(), &arguments, 0)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, expected, result, "Wrong result")
+}
+
+// TestFindFareCalendar_SearchFails tests when a search fails.
+func TestFindFareCalendar_SearchFails(t *testing.T) {
+	service, mockQuoter, _ := newTestFareCalendarService()
+
+	mockQuoter.On("StartSearch", mock.Anything, mock.Anything).Return("", errors.New("Oops"))
+
+	result, err := service.FindFareCalendar(context.Background(), &dummyArguments, 0)
+	assert.Nil(t, result, "Expected no result")
+	assert.Error(t, err, "Expected an error")
+}
@@ -1,59 +1,156 @@
 package application
 
 import (
-	"strings"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
 
 	"github.com/chrisnappin/flightchecker/pkg/domain"
 )
 
+// The airport data files, from ourairports.com, and the directory of their current version.
+const (
+	AirportDataDir = "data/airports"
+	CountriesFile  = "countries.csv"
+	RegionsFile    = "regions.csv"
+	AirportsFile   = "airports.csv"
+)
+
 // FindAirportsService handles finding a range of airports.
 type FindAirportsService struct {
-	logger domain.Logger
-	loader AirportDataLoader
+	logger           domain.Logger
+	loader           AirportDataLoader
+	flightRepository FlightRepository
 }
 
-// NewFindAirportsService creates a new instance.
-func NewFindAirportsService(logger domain.Logger, loader AirportDataLoader) *FindAirportsService {
-	return &FindAirportsService{logger, loader}
+// NewFindAirportsService creates a new instance. Airports are read from the repository, once imported from the airport
+// data files.
+func NewFindAirportsService(logger domain.Logger, loader AirportDataLoader,
+	flightRepository FlightRepository) *FindAirportsService {
+	return &FindAirportsService{logger, loader, flightRepository}
 }
 
-// FindAirports logs all airports within the specified country and region, excluding any matching the prefix (if set).
-func (service *FindAirportsService) FindAirports(countryName string, regionName string, excludePrefix string) error {
+// FindAirports logs and returns all airports within the specified country and region, excluding any matching the
+// prefix (if set).
+func (service *FindAirportsService) FindAirports(countryName string, regionName string, excludePrefix string) (
+	[]domain.Airport, error) {
 	airports, err := service.LoadMajorAirports()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	filteredAirports := domain.AirportMapFilter(airports, func(a domain.Airport) bool {
-		if excludePrefix != "" {
-			return a.Country == countryName && a.Region == regionName && !strings.HasPrefix(a.Name, excludePrefix)
-		}
-		return a.Country == countryName && a.Region == regionName
-	})
+	filter := domain.AirportFilter{Country: countryName, Region: regionName, ExcludePrefix: excludePrefix}
+	filteredAirports := domain.AirportMapFilter(airports, filter.Matches)
 
 	service.logger.Info("Matching Airports")
 	for _, airport := range filteredAirports {
 		service.logger.Infof("Name: %s, Code: %s, Region: %s", airport.Name, airport.IataCode, airport.Region)
 	}
 
-	return nil
+	return filteredAirports, nil
+}
+
+// SearchAirports returns the airports that best match free text, such as a name, municipality or code, best first,
+// up to the limit (if not zero).
+func (service *FindAirportsService) SearchAirports(text string, limit int) ([]domain.AirportMatch, error) {
+	airports, err := service.LoadMajorAirports()
+	if err != nil {
+		return nil, err
+	}
+	return domain.SearchAirports(domain.AirportMapValues(airports), text, limit), nil
+}
+
+// OutputAirportMatches writes the details of each airport found by a search, best first, as a table.
+func (service *FindAirportsService) OutputAirportMatches(writer io.Writer, matches []domain.AirportMatch) error {
+	if len(matches) == 0 {
+		_, err := fmt.Fprintln(writer, "No matching airports")
+		return err
+	}
+
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "IATA\tICAO\tName\tMunicipality\tRegion\tCountry")
+	for _, match := range matches {
+		airport := match.Airport
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", airport.IataCode, airport.IcaoCode, airport.Name,
+			airport.Municipality, airport.Region, airport.Country)
+	}
+	return table.Flush()
 }
 
-// LoadMajorAirports returns a map of all major airports, keyed by IATA code
+// LoadMajorAirports returns a map of all major airports, keyed by IATA code. If the repository has no airports yet,
+// they are imported first.
 func (service *FindAirportsService) LoadMajorAirports() (map[string]domain.Airport, error) {
-	countries, err := service.loader.LoadCountries("data/airports/countries.csv")
+	airports, err := service.flightRepository.ReadAllAirports()
+	if err != nil {
+		return nil, err
+	}
+	if len(airports) == 0 {
+		service.logger.Infof("Importing airports from %s, which is only needed once", AirportDataDir)
+		imported, _, err := service.ImportAirports()
+		return imported, err
+	}
+	return domain.NewAirportMap(airports), nil
+}
+
+// ImportAirports loads the airports from the airport data files, and replaces those in the repository with them,
+// returning a map of them keyed by IATA code, and a warning for every problem with the files ignored in lenient mode.
+// This is only needed after the files are changed other than by UpdateAirports, e.g. downloaded by hand.
+func (service *FindAirportsService) ImportAirports() (map[string]domain.Airport, []error, error) {
+	data, err := loadAirportData(service.loader, AirportDataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = service.flightRepository.ImportAirports(domain.AirportMapValues(data.airports), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	service.logger.Infof("Imported %d airports", len(data.airports))
+	logWarnings(service.logger, data.warnings)
+	return data.airports, data.warnings, nil
+}
+
+// airportData is the contents of the airport data files.
+type airportData struct {
+	countries map[string]string
+	regions   map[string]string
+	airports  map[string]domain.Airport
+	warnings  []error // problems ignored in lenient mode
+}
+
+// loadAirportData loads the countries, regions and airports from the airport data files in the directory, with the
+// warnings of all three files.
+func loadAirportData(loader AirportDataLoader, dir string) (*airportData, error) {
+	loader.ClearWarnings()
+	countries, err := loader.LoadCountries(filepath.Join(dir, CountriesFile))
 	if err != nil {
 		return nil, err
 	}
 
-	regions, err := service.loader.LoadRegions("data/airports/regions.csv")
+	regions, err := loader.LoadRegions(filepath.Join(dir, RegionsFile))
 	if err != nil {
 		return nil, err
 	}
 
-	airports, err := service.loader.LoadAirports("data/airports/airports.csv", countries, regions)
+	airports, err := loader.LoadAirports(filepath.Join(dir, AirportsFile), countries, regions)
 	if err != nil {
 		return nil, err
 	}
-	return airports, nil
+	return &airportData{countries, regions, airports, loader.Warnings()}, nil
+}
+
+// logWarnings logs how many problems with the airport data files were ignored, if any (each is logged as found).
+func logWarnings(logger domain.Logger, warnings []error) {
+	if len(warnings) > 0 {
+		logger.Warnf("Ignored %d problems in the airport data files", len(warnings))
+	}
+}
+
+// warningMessages returns the message of each warning, or nil if there are none.
+func warningMessages(warnings []error) []string {
+	var messages []string
+	for _, warning := range warnings {
+		messages = append(messages, warning.Error())
+	}
+	return messages
 }
@@ -1,7 +1,9 @@
 package application
 
 import (
+	"bytes"
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/chrisnappin/flightchecker/mocks"
@@ -41,11 +43,29 @@ var dummyAirports = map[string]domain.Airport{
 	airport2.IataCode: airport2,
 }
 
+// newMockLoader returns a mock loader whose loads find the warnings.
+func newMockLoader(warnings ...error) *mocks.AirportDataLoader {
+	mockLoader := &mocks.AirportDataLoader{}
+	mockLoader.On("ClearWarnings").Maybe()
+	mockLoader.On("Warnings").Return(warnings).Maybe()
+	return mockLoader
+}
+
+// newEmptyRepository returns a mock repository without any airports, so they are imported from the airport data files.
+func newEmptyRepository(mockLogger *mocks.Logger) *mocks.FlightRepository {
+	mockRepository := &mocks.FlightRepository{}
+	mockRepository.On("ReadAllAirports").Return([]domain.Airport{}, nil)
+	mockRepository.On("ImportAirports", mock.Anything, (*domain.AirportDataset)(nil)).Return(nil)
+	mockLogger.On("Infof", "Importing airports from %s, which is only needed once", AirportDataDir).Maybe()
+	mockLogger.On("Infof", "Imported %d airports", mock.Anything).Maybe()
+	return mockRepository
+}
+
 // TestLoadMajorAirports_HappyPath tests LoadMajorAirports when all is good.
 func TestLoadMajorAirports_HappyPath(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(dummyCountries, nil)
 	mockLoader.On("LoadRegions", mock.Anything).Return(dummyRegions, nil)
@@ -59,8 +79,8 @@ func TestLoadMajorAirports_HappyPath(t *testing.T) {
 // TestLoadMajorAirports_HappyPath tests LoadMajorAirports when countries error.
 func TestLoadMajorAirports_CountriesFail(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(nil, errors.New("Oops"))
 
@@ -72,8 +92,8 @@ func TestLoadMajorAirports_CountriesFail(t *testing.T) {
 // TestLoadMajorAirports_HappyPath tests LoadMajorAirports when regions error.
 func TestLoadMajorAirports_RegionsFail(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(dummyCountries, nil)
 	mockLoader.On("LoadRegions", mock.Anything).Return(nil, errors.New("Oops"))
@@ -86,8 +106,8 @@ func TestLoadMajorAirports_RegionsFail(t *testing.T) {
 // TestLoadMajorAirports_HappyPath tests LoadMajorAirports when airports error.
 func TestLoadMajorAirports_AirportFail(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(dummyCountries, nil)
 	mockLoader.On("LoadRegions", mock.Anything).Return(dummyRegions, nil)
@@ -101,8 +121,8 @@ func TestLoadMajorAirports_AirportFail(t *testing.T) {
 // TestFindAirports_HappyPath tests FindAirports with a prefix.
 func TestFindAirports_WithPrefix(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(dummyCountries, nil)
 	mockLoader.On("LoadRegions", mock.Anything).Return(dummyRegions, nil)
@@ -111,7 +131,8 @@ func TestFindAirports_WithPrefix(t *testing.T) {
 	mockLogger.On("Info", "Matching Airports")
 	// no matching result logged
 
-	err := service.FindAirports("Country1", "Region1", "A") // filters out the result
+	result, err := service.FindAirports("Country1", "Region1", "A") // filters out the result
+	assert.Equal(t, []domain.Airport{}, result, "Wrong results")
 	assert.Nil(t, err, "Expected no error")
 	mockLoader.AssertExpectations(t)
 	mockLogger.AssertExpectations(t)
@@ -120,8 +141,8 @@ func TestFindAirports_WithPrefix(t *testing.T) {
 // TestFindAirports_HappyPath tests FindAirports without a prefix.
 func TestFindAirports_WithoutPrefix(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(dummyCountries, nil)
 	mockLoader.On("LoadRegions", mock.Anything).Return(dummyRegions, nil)
@@ -130,7 +151,8 @@ func TestFindAirports_WithoutPrefix(t *testing.T) {
 	mockLogger.On("Info", "Matching Airports")
 	mockLogger.On("Infof", "Name: %s, Code: %s, Region: %s", airport1.Name, airport1.IataCode, airport1.Region)
 
-	err := service.FindAirports("Country1", "Region1", "") // doesn't filter out the result
+	result, err := service.FindAirports("Country1", "Region1", "") // doesn't filter out the result
+	assert.Equal(t, []domain.Airport{airport1}, result, "Wrong results")
 	assert.Nil(t, err, "Expected no error")
 	mockLoader.AssertExpectations(t)
 	mockLogger.AssertExpectations(t)
@@ -139,11 +161,107 @@ func TestFindAirports_WithoutPrefix(t *testing.T) {
 // TestFindAirports_HappyPath tests FindAirports when it fails.
 func TestFindAirports_Fails(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	mockLoader := &mocks.AirportDataLoader{}
-	service := NewFindAirportsService(mockLogger, mockLoader)
+	mockLoader := newMockLoader()
+	service := NewFindAirportsService(mockLogger, mockLoader, newEmptyRepository(mockLogger))
 
 	mockLoader.On("LoadCountries", mock.Anything).Return(nil, errors.New("Oops"))
 
-	err := service.FindAirports("AA", "BB", "")
+	result, err := service.FindAirports("AA", "BB", "")
+	assert.Nil(t, result, "Expected no result")
 	assert.Error(t, err, "Expected an error")
 }
+
+// TestLoadMajorAirports_FromRepository tests airports already imported are read from the repository, not the files.
+func TestLoadMajorAirports_FromRepository(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockLoader := newMockLoader()
+	mockRepository := &mocks.FlightRepository{}
+	service := NewFindAirportsService(mockLogger, mockLoader, mockRepository)
+
+	mockRepository.On("ReadAllAirports").Return([]domain.Airport{airport1, airport2}, nil)
+
+	result, err := service.LoadMajorAirports()
+	assert.Equal(t, dummyAirports, result, "Wrong results")
+	assert.Nil(t, err, "Expected no error")
+	mockLoader.AssertNotCalled(t, "LoadAirports", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestLoadMajorAirports_Imported tests airports are imported from the files into an empty repository.
+func TestLoadMajorAirports_Imported(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	allowLogging(mockLogger)
+	mockLoader := newMockLoader()
+	mockRepository := &mocks.FlightRepository{}
+	service := NewFindAirportsService(mockLogger, mockLoader, mockRepository)
+
+	mockRepository.On("ReadAllAirports").Return([]domain.Airport{}, nil)
+	mockLoader.On("LoadCountries", filepath.Join(AirportDataDir, CountriesFile)).Return(dummyCountries, nil)
+	mockLoader.On("LoadRegions", filepath.Join(AirportDataDir, RegionsFile)).Return(dummyRegions, nil)
+	mockLoader.On("LoadAirports", filepath.Join(AirportDataDir, AirportsFile), dummyCountries, dummyRegions).
+		Return(dummyAirports, nil)
+	mockRepository.On("ImportAirports", mock.Anything, (*domain.AirportDataset)(nil)).Return(nil).
+		Run(func(args mock.Arguments) {
+			assert.ElementsMatch(t, []domain.Airport{airport1, airport2}, args.Get(0), "Wrong airports")
+		})
+
+	result, err := service.LoadMajorAirports()
+	assert.Equal(t, dummyAirports, result, "Wrong results")
+	assert.Nil(t, err, "Expected no error")
+	mockRepository.AssertExpectations(t)
+}
+
+// TestImportAirports_Warnings tests the problems with the airport data files ignored in lenient mode are returned,
+// cleared before loading so they are only those of this import.
+func TestImportAirports_Warnings(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	allowLogging(mockLogger)
+	warnings := []error{errors.New("countries.csv line 3: wrong number of fields"),
+		errors.New("airports.csv line 12, column iso_country: Unknown country code ZZ of airport ABC")}
+	mockLoader := newMockLoader(warnings...)
+	mockRepository := &mocks.FlightRepository{}
+	service := NewFindAirportsService(mockLogger, mockLoader, mockRepository)
+
+	mockLoader.On("LoadCountries", mock.Anything).Return(dummyCountries, nil)
+	mockLoader.On("LoadRegions", mock.Anything).Return(dummyRegions, nil)
+	mockLoader.On("LoadAirports", mock.Anything, mock.Anything, mock.Anything).Return(dummyAirports, nil)
+	mockRepository.On("ImportAirports", mock.Anything, (*domain.AirportDataset)(nil)).Return(nil)
+
+	airports, result, err := service.ImportAirports()
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, dummyAirports, airports, "Wrong airports")
+	assert.Equal(t, warnings, result, "Wrong warnings")
+	mockLoader.AssertCalled(t, "ClearWarnings")
+	mockLogger.AssertCalled(t, "Warnf", "Ignored %d problems in the airport data files", 2)
+}
+
+// TestSearchAirports tests the best matches of the search text are found.
+func TestSearchAirports(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockLoader := newMockLoader()
+	mockRepository := &mocks.FlightRepository{}
+	service := NewFindAirportsService(mockLogger, mockLoader, mockRepository)
+
+	mockRepository.On("ReadAllAirports").Return([]domain.Airport{airport1, airport2}, nil)
+
+	result, err := service.SearchAirports("airprt2", 10)
+	assert.Equal(t, []domain.AirportMatch{{Airport: airport2, Score: 63}}, result, "Wrong results")
+	assert.Nil(t, err, "Expected no error")
+}
+
+// TestOutputAirportMatches tests the airports found are written as a table, or a message if there aren't any.
+func TestOutputAirportMatches(t *testing.T) {
+	service := NewFindAirportsService(&mocks.Logger{}, newMockLoader(), &mocks.FlightRepository{})
+	heathrow := domain.Airport{IataCode: "LHR", IcaoCode: "EGLL", Name: "London Heathrow Airport",
+		Municipality: "London", Region: "England", Country: "United Kingdom"}
+
+	var output bytes.Buffer
+	assert.Nil(t, service.OutputAirportMatches(&output, []domain.AirportMatch{{Airport: heathrow, Score: 100},
+		{Airport: airport1, Score: 50}}), "Expected no error")
+	assert.Equal(t, "IATA   ICAO  Name                     Municipality  Region   Country\n"+
+		"LHR    EGLL  London Heathrow Airport  London        England  United Kingdom\n"+
+		"Code1        Airport1                               Region1  Country1\n", output.String(), "Wrong output")
+
+	output.Reset()
+	assert.Nil(t, service.OutputAirportMatches(&output, []domain.AirportMatch{}), "Expected no error")
+	assert.Equal(t, "No matching airports\n", output.String(), "Wrong output")
+}
@@ -0,0 +1,134 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// MultiProvider aggregates several quote providers, so their prices can be compared. Every provider is searched at
+// once, and their itineraries merged, with any found by more than one provider only kept at the cheapest price.
+type MultiProvider struct {
+	logger    domain.Logger
+	providers []FlightQuoteProvider
+}
+
+// NewMultiProvider creates a new instance, aggregating the providers.
+func NewMultiProvider(logger domain.Logger, providers ...FlightQuoteProvider) *MultiProvider {
+	return &MultiProvider{logger, providers}
+}
+
+// Name returns the names of every provider, separated by "+".
+func (multi *MultiProvider) Name() string {
+	names := make([]string, len(multi.providers))
+	for index, provider := range multi.providers {
+		names[index] = provider.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Quote finds quotes from every provider at once, and merges them into one quote, ranked cheapest first. Providers
+// that fail are skipped, unless they all fail.
+func (multi *MultiProvider) Quote(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	return multi.search(ctx, multi.providers, func(provider FlightQuoteProvider) (*domain.Quote, error) {
+		return provider.Quote(ctx, arguments, airports)
+	})
+}
+
+// ResumeSearch resumes an interrupted search with every provider that can resume searches, merging their quotes as
+// per Quote.
+func (multi *MultiProvider) ResumeSearch(ctx context.Context, session *domain.SearchSession, apiHost string,
+	apiKey string, airports map[string]domain.Airport) (*domain.Quote, error) {
+	resumers := []FlightQuoteProvider{}
+	for _, provider := range multi.providers {
+		if _, ok := provider.(SearchResumer); ok {
+			resumers = append(resumers, provider)
+		}
+	}
+	if len(resumers) == 0 {
+		return nil, fmt.Errorf("Searches from %s can't be resumed", multi.Name())
+	}
+
+	return multi.search(ctx, resumers, func(provider FlightQuoteProvider) (*domain.Quote, error) {
+		return provider.(SearchResumer).ResumeSearch(ctx, session, apiHost, apiKey, airports)
+	})
+}
+
+// search finds quotes from each of the providers at once, and merges them into one quote, ranked cheapest first.
+// Providers that fail are skipped, unless they all fail.
+func (multi *MultiProvider) search(ctx context.Context, providers []FlightQuoteProvider,
+	quote func(provider FlightQuoteProvider) (*domain.Quote, error)) (*domain.Quote, error) {
+	quotes := make([]*domain.Quote, len(providers))
+	errs := make([]error, len(providers))
+
+	var waitGroup sync.WaitGroup
+	for index, provider := range providers {
+		waitGroup.Add(1)
+		go func(index int, provider FlightQuoteProvider) {
+			defer waitGroup.Done()
+			quotes[index], errs[index] = quote(provider)
+		}(index, provider)
+	}
+	waitGroup.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	successes := []*domain.Quote{}
+	var firstErr error
+	for index, provider := range providers {
+		if errs[index] != nil {
+			multi.logger.Warnf("Quotes from %s failed: %s", provider.Name(), errs[index])
+			if firstErr == nil {
+				firstErr = fmt.Errorf("Quotes from %s failed: %w", provider.Name(), errs[index])
+			}
+			continue
+		}
+
+		quote := quotes[index]
+		for _, itinerary := range quote.Itineraries {
+			if itinerary.Provider == "" {
+				itinerary.Provider = provider.Name()
+			}
+		}
+		if cheapest := quote.Cheapest(); cheapest != nil {
+			multi.logger.Infof("%s found %d itineraries, the cheapest %s", provider.Name(), len(quote.Itineraries),
+				cheapest.Price.Format(quote.CurrencyFormat))
+		} else {
+			multi.logger.Infof("%s found no itineraries", provider.Name())
+		}
+		successes = append(successes, quote)
+	}
+	if len(successes) == 0 {
+		return nil, firstErr
+	}
+
+	merged, err := domain.MergeQuotes(successes)
+	if err != nil {
+		return nil, err
+	}
+	total := len(merged.Itineraries)
+	merged.Itineraries = domain.DeduplicateItineraries(merged.Itineraries)
+	multi.logger.Debugf("Removed %d itineraries found by more than one provider", total-len(merged.Itineraries))
+	return merged, nil
+}
+
+// FindBookingDetails finds the ways to book the itinerary, with the provider that found it.
+func (multi *MultiProvider) FindBookingDetails(ctx context.Context, arguments *domain.Arguments,
+	itinerary *domain.Itinerary) (*domain.BookingDetails, error) {
+	for _, provider := range multi.providers {
+		if provider.Name() != itinerary.Provider {
+			continue
+		}
+		finder, ok := provider.(BookingDetailsFinder)
+		if !ok {
+			break
+		}
+		return finder.FindBookingDetails(ctx, arguments, itinerary)
+	}
+	return nil, fmt.Errorf("No booking details for itineraries from %s", itinerary.Provider)
+}
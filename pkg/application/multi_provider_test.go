@@ -0,0 +1,142 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestProvider returns a mock provider with the name.
+func newTestProvider(name string) *mocks.FlightQuoteProvider {
+	mockProvider := &mocks.FlightQuoteProvider{}
+	mockProvider.On("Name").Return(name)
+	return mockProvider
+}
+
+// testItinerary returns an itinerary with the supplier and price, on a single outbound flight.
+func testItinerary(supplier string, amount int) *domain.Itinerary {
+	return &domain.Itinerary{
+		SupplierName: supplier,
+		Price:        domain.Money{Amount: amount, Currency: "GBP"},
+		OutboundJourney: &domain.Journey{ID: "leg1", Flights: []*domain.Flight{&domain.Flight{
+			FlightNumber: &domain.FlightNumber{CarrierCode: "BA", FlightNumber: "123"},
+			StartTime:    time.Date(2019, time.November, 1, 8, 30, 0, 0, time.UTC),
+		}}},
+	}
+}
+
+// TestMultiProvider_Quote tests the quotes of every provider are merged, keeping the cheapest of any duplicates.
+func TestMultiProvider_Quote(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	allowLogging(mockLogger)
+	first := newTestProvider("first")
+	second := newTestProvider("second")
+	multi := NewMultiProvider(mockLogger, first, second)
+
+	firstQuote := &domain.Quote{Currency: "GBP", Complete: true, Itineraries: []*domain.Itinerary{
+		testItinerary("Agent1", 300), testItinerary("Agent2", 200)}}
+	secondQuote := &domain.Quote{Currency: "GBP", CurrencyFormat: poundFormat, Complete: true,
+		Itineraries: []*domain.Itinerary{testItinerary("agent1", 250), testItinerary("Agent3", 400)}}
+	first.On("Quote", mock.Anything, &dummyArguments, dummyAirports).Return(firstQuote, nil)
+	second.On("Quote", mock.Anything, &dummyArguments, dummyAirports).Return(secondQuote, nil)
+
+	quote, err := multi.Quote(context.Background(), &dummyArguments, dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, "first+second", multi.Name(), "Wrong name")
+	assert.Equal(t, poundFormat, quote.CurrencyFormat, "Wrong currency format")
+	assert.Equal(t, []*domain.Itinerary{
+		firstQuote.Itineraries[1], secondQuote.Itineraries[0], secondQuote.Itineraries[1],
+	}, quote.Itineraries, "Wrong itineraries")
+	assert.Equal(t, "second", quote.Itineraries[1].Provider, "Wrong provider")
+	assert.Equal(t, "first", quote.Itineraries[0].Provider, "Wrong provider")
+}
+
+// TestMultiProvider_OneFailed tests the quotes of the providers that succeed are used, if any fail.
+func TestMultiProvider_OneFailed(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	allowLogging(mockLogger)
+	first := newTestProvider("first")
+	second := newTestProvider("second")
+	multi := NewMultiProvider(mockLogger, first, second)
+
+	first.On("Quote", mock.Anything, &dummyArguments, dummyAirports).Return(nil, errors.New("Oops"))
+	second.On("Quote", mock.Anything, &dummyArguments, dummyAirports).Return(
+		&domain.Quote{Currency: "GBP", Complete: true, Itineraries: []*domain.Itinerary{testItinerary("Agent1", 1)}},
+		nil)
+
+	quote, err := multi.Quote(context.Background(), &dummyArguments, dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Len(t, quote.Itineraries, 1, "Wrong number of itineraries")
+}
+
+// TestMultiProvider_AllFailed tests an error is returned if every provider fails.
+func TestMultiProvider_AllFailed(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	allowLogging(mockLogger)
+	first := newTestProvider("first")
+	second := newTestProvider("second")
+	multi := NewMultiProvider(mockLogger, first, second)
+
+	failure := errors.New("Oops")
+	first.On("Quote", mock.Anything, &dummyArguments, dummyAirports).Return(nil, failure)
+	second.On("Quote", mock.Anything, &dummyArguments, dummyAirports).Return(nil, errors.New("Oops again"))
+
+	_, err := multi.Quote(context.Background(), &dummyArguments, dummyAirports)
+	assert.True(t, errors.Is(err, failure), "Wrong error %s", err)
+	assert.EqualError(t, err, "Quotes from first failed: Oops", "Wrong error")
+}
+
+// TestMultiProvider_FindBookingDetails tests booking details are found with the provider of the itinerary.
+func TestMultiProvider_FindBookingDetails(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockQuoter := &mocks.SkyScannerQuoter{}
+	allowLogging(mockLogger)
+	multi := NewMultiProvider(mockLogger, newTestProvider("first"),
+		NewSkyScannerProvider(mockLogger, mockQuoter, testPollStrategy, nil))
+
+	link := &domain.BookingDetailsLink{URI: "/booking"}
+	expected := &domain.BookingDetails{Complete: true}
+	mockQuoter.On("GetBookingDetails", mock.Anything, link, "test.com", "testKey").Return(expected, nil)
+
+	itinerary := testItinerary("Agent1", 100)
+	itinerary.Provider = SkyScannerProviderName
+	itinerary.BookingDetailsLink = link
+	details, err := multi.FindBookingDetails(context.Background(), &dummyArguments, itinerary)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, expected, details, "Wrong booking details")
+
+	itinerary.Provider = "first"
+	_, err = multi.FindBookingDetails(context.Background(), &dummyArguments, itinerary)
+	assert.EqualError(t, err, "No booking details for itineraries from first", "Wrong error")
+}
+
+// TestMultiProvider_ResumeSearch tests an interrupted search is resumed by the providers that can resume searches.
+func TestMultiProvider_ResumeSearch(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockQuoter := &mocks.SkyScannerQuoter{}
+	allowLogging(mockLogger)
+	fareFile := newTestProvider("farefile")
+	multi := NewMultiProvider(mockLogger, fareFile,
+		NewSkyScannerProvider(mockLogger, mockQuoter, testPollStrategy, nil))
+
+	session := domain.NewSearchSession("abc", &dummyArguments, time.Now())
+	resumed := &domain.Quote{Currency: "GBP", Complete: true, Itineraries: []*domain.Itinerary{
+		testItinerary("Agent1", 100)}}
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(resumed, nil)
+
+	quote, err := multi.ResumeSearch(context.Background(), session, "test.com", "testKey", dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, resumed.Itineraries, quote.Itineraries, "Wrong itineraries")
+	assert.Equal(t, SkyScannerProviderName, quote.Itineraries[0].Provider, "Wrong provider")
+	fareFile.AssertNotCalled(t, "Quote", mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = NewMultiProvider(mockLogger, fareFile).ResumeSearch(context.Background(), session, "test.com",
+		"testKey", dummyAirports)
+	assert.EqualError(t, err, "Searches from farefile can't be resumed", "Wrong error")
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"fmt"
+	"time"
+)
+
+// PollStrategy controls how often a search is polled for quotes, and for how long.
+type PollStrategy struct {
+	InitialDelay  time.Duration // before the first poll, since quotes are never ready straight away
+	Interval      time.Duration // between the first and second polls
+	Backoff       float64       // multiplies the interval after each poll, e.g. 1.5, or 1 for a fixed interval
+	MaxInterval   time.Duration // the longest interval between polls
+	Deadline      time.Duration // stops polling this long after the first poll
+	AcceptPartial bool          // returns the quotes found so far once the deadline passes, rather than failing
+}
+
+// DefaultPollStrategy polls after 2 seconds, then at growing intervals of up to 15 seconds, for up to 2 minutes.
+// In practice quotes are typically complete after 20-30 seconds.
+func DefaultPollStrategy() PollStrategy {
+	return PollStrategy{
+		InitialDelay: 2 * time.Second,
+		Interval:     3 * time.Second,
+		Backoff:      1.5,
+		MaxInterval:  15 * time.Second,
+		Deadline:     2 * time.Minute,
+	}
+}
+
+// Validate returns an error if the strategy would never poll, or poll without waiting forever.
+func (strategy PollStrategy) Validate() error {
+	switch {
+	case strategy.InitialDelay < 0:
+		return fmt.Errorf("Invalid initial poll delay %s", strategy.InitialDelay)
+	case strategy.Interval <= 0:
+		return fmt.Errorf("Invalid poll interval %s", strategy.Interval)
+	case strategy.Backoff < 1:
+		return fmt.Errorf("Invalid poll backoff %g, expected at least 1", strategy.Backoff)
+	case strategy.MaxInterval < strategy.Interval:
+		return fmt.Errorf("Invalid maximum poll interval %s, expected at least the interval %s",
+			strategy.MaxInterval, strategy.Interval)
+	case strategy.Deadline <= 0:
+		return fmt.Errorf("Invalid poll deadline %s", strategy.Deadline)
+	}
+	return nil
+}
+
+// NextInterval returns the interval to wait after the specified interval.
+func (strategy PollStrategy) NextInterval(interval time.Duration) time.Duration {
+	next := time.Duration(float64(interval) * strategy.Backoff)
+	if next > strategy.MaxInterval {
+		return strategy.MaxInterval
+	}
+	return next
+}
@@ -0,0 +1,36 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPollStrategy_NextInterval tests intervals grow by the backoff, up to the maximum.
+func TestPollStrategy_NextInterval(t *testing.T) {
+	strategy := PollStrategy{Interval: 2 * time.Second, Backoff: 1.5, MaxInterval: 4 * time.Second,
+		Deadline: time.Minute}
+
+	assert.Equal(t, 3*time.Second, strategy.NextInterval(2*time.Second), "Wrong interval")
+	assert.Equal(t, 4*time.Second, strategy.NextInterval(3*time.Second), "Wrong interval")
+	assert.Equal(t, 4*time.Second, strategy.NextInterval(4*time.Second), "Wrong interval")
+}
+
+// TestPollStrategy_Validate tests the default strategy is valid, and various invalid ones.
+func TestPollStrategy_Validate(t *testing.T) {
+	assert.Nil(t, DefaultPollStrategy().Validate(), "Expected default to be valid")
+
+	invalid := []func(*PollStrategy){
+		func(strategy *PollStrategy) { strategy.InitialDelay = -time.Second },
+		func(strategy *PollStrategy) { strategy.Interval = 0 },
+		func(strategy *PollStrategy) { strategy.Backoff = 0.5 },
+		func(strategy *PollStrategy) { strategy.MaxInterval = time.Second },
+		func(strategy *PollStrategy) { strategy.Deadline = 0 },
+	}
+	for index, change := range invalid {
+		strategy := DefaultPollStrategy()
+		change(&strategy)
+		assert.Error(t, strategy.Validate(), "Expected strategy %d to be invalid", index)
+	}
+}
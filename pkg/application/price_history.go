@@ -0,0 +1,68 @@
+package application
+
+import (
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// PriceHistoryService handles reporting how prices have changed across repeated searches.
+type PriceHistoryService struct {
+	logger           domain.Logger
+	flightRepository FlightRepository
+}
+
+// NewPriceHistoryService creates a new instance.
+func NewPriceHistoryService(logger domain.Logger, flightRepository FlightRepository) *PriceHistoryService {
+	return &PriceHistoryService{logger, flightRepository}
+}
+
+// ShowPriceHistory logs the prices found by every previous search with the same search criteria as the arguments
+// (route, dates, trip type, passengers, cabin class, currency, country and group pricing), so every price can be
+// compared, and returns them oldest first.
+func (service *PriceHistoryService) ShowPriceHistory(arguments *domain.Arguments) ([]*domain.SearchRun, error) {
+	const dayTimeFormat = "2006-01-02 15:04"
+
+	arguments = arguments.WithDefaults()
+	err := arguments.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := service.flightRepository.ReadPriceHistory(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := arguments.Currency
+	format, err := service.flightRepository.ReadCurrencyFormat(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	service.logger.Infof("Found %d searches from %s to %s on %s", len(runs), arguments.Origin,
+		arguments.Destination, arguments.OutboundDate)
+	for index, run := range runs {
+		service.logger.Infof("%s for %d nights: cheapest %s, median %s, most expensive %s",
+			run.Created.Local().Format(dayTimeFormat), run.Arguments.HolidayDuration,
+			domain.Money{Amount: run.Cheapest, Currency: currency}.Format(format),
+			domain.Money{Amount: run.Median, Currency: currency}.Format(format),
+			domain.Money{Amount: run.MostExpensive, Currency: currency}.Format(format))
+
+		if index > 0 {
+			service.logger.Infof("Cheapest price is %s",
+				describePriceChange(runs[index-1].Cheapest, run.Cheapest, currency, format))
+		}
+	}
+	return runs, nil
+}
+
+// describePriceChange describes the difference between a previous and current price, in the same currency.
+func describePriceChange(previous int, current int, currency string, format *domain.CurrencyFormat) string {
+	switch {
+	case current > previous:
+		return "up " + domain.Money{Amount: current - previous, Currency: currency}.Format(format)
+	case current < previous:
+		return "down " + domain.Money{Amount: previous - current, Currency: currency}.Format(format)
+	default:
+		return "unchanged"
+	}
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestShowPriceHistory_HappyPath tests showing the history of several searches.
+func TestShowPriceHistory_HappyPath(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockRepository := &mocks.FlightRepository{}
+	service := NewPriceHistoryService(mockLogger, mockRepository)
+
+	runs := []*domain.SearchRun{
+		&domain.SearchRun{Arguments: dummyArguments, Cheapest: 10000, Median: 15000, MostExpensive: 20000},
+		&domain.SearchRun{Arguments: dummyArguments, Cheapest: 9050, Median: 15000, MostExpensive: 20000},
+	}
+	mockRepository.On("ReadPriceHistory", &dummyArguments).Return(runs, nil)
+	mockRepository.On("ReadCurrencyFormat", "GBP").Return(poundFormat, nil).Once()
+
+	mockLogger.On("Infof", "Found %d searches from %s to %s on %s", 2, "Code1", "Code2", "2019-11-01")
+	mockLogger.On("Infof", "%s for %d nights: cheapest %s, median %s, most expensive %s",
+		mock.Anything, 14, "£100.00", "£150.00", "£200.00")
+	mockLogger.On("Infof", "%s for %d nights: cheapest %s, median %s, most expensive %s",
+		mock.Anything, 14, "£90.50", "£150.00", "£200.00")
+	mockLogger.On("Infof", "Cheapest price is %s", "down £9.50")
+
+	result, err := service.ShowPriceHistory(&dummyArguments)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, runs, result, "Wrong results")
+	mockLogger.AssertExpectations(t)
+	mockRepository.AssertExpectations(t)
+}
+
+// TestShowPriceHistory_UnknownCurrencyFormat tests showing history in a currency with no known format.
+func TestShowPriceHistory_UnknownCurrencyFormat(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockRepository := &mocks.FlightRepository{}
+	service := NewPriceHistoryService(mockLogger, mockRepository)
+
+	runs := []*domain.SearchRun{
+		&domain.SearchRun{Arguments: dummyArguments, Cheapest: 10000, Median: 15000, MostExpensive: 20000},
+	}
+	mockRepository.On("ReadPriceHistory", &dummyArguments).Return(runs, nil)
+	mockRepository.On("ReadCurrencyFormat", "GBP").Return(nil, nil)
+
+	mockLogger.On("Infof", "Found %d searches from %s to %s on %s", 1, "Code1", "Code2", "2019-11-01")
+	mockLogger.On("Infof", "%s for %d nights: cheapest %s, median %s, most expensive %s",
+		mock.Anything, 14, "100.00 GBP", "150.00 GBP", "200.00 GBP")
+
+	_, err := service.ShowPriceHistory(&dummyArguments)
+	assert.Nil(t, err, "Expected no error")
+	mockLogger.AssertExpectations(t)
+}
+
+// TestShowPriceHistory_Fails tests showing history when the repository fails.
+func TestShowPriceHistory_Fails(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	mockRepository := &mocks.FlightRepository{}
+	service := NewPriceHistoryService(mockLogger, mockRepository)
+
+	mockRepository.On("ReadPriceHistory", &dummyArguments).Return(nil, errors.New("Oops"))
+
+	result, err := service.ShowPriceHistory(&dummyArguments)
+	assert.Nil(t, result, "Expected no result")
+	assert.Error(t, err, "Expected an error")
+}
+
+// TestDescribePriceChange tests describing rising, falling and unchanged prices.
+func TestDescribePriceChange(t *testing.T) {
+	assert.Equal(t, "up £1.50", describePriceChange(1000, 1150, "GBP", poundFormat))
+	assert.Equal(t, "down £0.25", describePriceChange(1000, 975, "GBP", poundFormat))
+	assert.Equal(t, "down 0.25 GBP", describePriceChange(1000, 975, "GBP", nil))
+	assert.Equal(t, "unchanged", describePriceChange(1000, 1000, "GBP", poundFormat))
+}
@@ -1,6 +1,7 @@
 package application
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -10,119 +11,189 @@ import (
 // QuoteForFlightsService handles finding quotes for flights.
 type QuoteForFlightsService struct {
 	logger           domain.Logger
-	loader           ArgumentsLoader
 	finder           AirportFinder
-	skyScannerQuoter SkyScannerQuoter
 	flightRepository FlightRepository
+	orchestrator     *SearchOrchestrator
 }
 
 // NewQuoteForFlightsService creates a new instance.
-func NewQuoteForFlightsService(logger domain.Logger, loader ArgumentsLoader, finder AirportFinder,
-	skyScannerQuoter SkyScannerQuoter, flightRepository FlightRepository) *QuoteForFlightsService {
-	return &QuoteForFlightsService{logger, loader, finder, skyScannerQuoter, flightRepository}
+func NewQuoteForFlightsService(logger domain.Logger, finder AirportFinder, flightRepository FlightRepository,
+	orchestrator *SearchOrchestrator) *QuoteForFlightsService {
+	return &QuoteForFlightsService{logger, finder, flightRepository, orchestrator}
 }
 
-// QuoteForFlights finds some quotes for flights defined in the arguments.
-func (service *QuoteForFlightsService) QuoteForFlights(argumentsFilename string) {
+// QuoteForFlights finds some quotes for flights defined in the arguments, and saves them to the repository. Optional
+// arguments that aren't set are given their defaults.
+// With several origin or destination airports, every route between them is searched and the itineraries merged into
+// one quote, ranked cheapest first; routes that fail are skipped, unless they all fail.
+// If the repository holds a quote for the same search that is no older than maxCacheAge, that is returned instead of
+// searching again (a maxCacheAge of zero always searches). The search is abandoned once the context is done.
+func (service *QuoteForFlightsService) QuoteForFlights(ctx context.Context, arguments *domain.Arguments,
+	maxCacheAge time.Duration) (*domain.Quote, error) {
+
+	arguments = arguments.WithDefaults()
+	err := arguments.Validate()
+	if err != nil {
+		return nil, err
+	}
 
 	airports, err := service.finder.LoadMajorAirports()
 	if err != nil {
-		service.logger.Fatal(err)
+		return nil, err
 	}
 
-	arguments, originAirport, destinationAirport, err := service.loadArguments(argumentsFilename, airports)
+	origins, err := arguments.OriginAirports(airports)
 	if err != nil {
-		service.logger.Fatal(err)
+		return nil, err
 	}
-
-	service.logger.Infof("Looking for flights from %s staying for %d nights",
-		arguments.OutboundDate, arguments.HolidayDuration)
-	service.logger.Infof("from %s (%s) in %s, %s",
-		originAirport.Name, originAirport.IataCode, originAirport.Region, originAirport.Country)
-	service.logger.Infof("to %s (%s) in %s, %s",
-		destinationAirport.Name, destinationAirport.IataCode, destinationAirport.Region, destinationAirport.Country)
-	service.logger.Infof("for %d adults, %d children, %d infants",
-		arguments.Adults, arguments.Children, arguments.Infants)
-
-	// TODO: do a cached-data only option where the schema is preserved
-	err = service.flightRepository.InitialiseSchema()
+	destinations, err := arguments.DestinationAirports(airports)
 	if err != nil {
-		service.logger.Fatal(err)
+		return nil, err
 	}
-
-	err = service.flightRepository.CreateAirports(domain.AirportMapValues(airports))
+	searches, err := arguments.ExpandAirports(airports)
 	if err != nil {
-		service.logger.Fatal(err)
+		return nil, err
+	}
+
+	switch arguments.Trip() {
+	case domain.OneWayTrip:
+		service.logger.Infof("Looking for one-way flights on %s", arguments.OutboundDate)
+	case domain.OpenJawTrip:
+		returnOrigin := airports[arguments.ReturnOrigin]
+		service.logger.Infof("Looking for open-jaw flights from %s staying for %d nights, returning from %s (%s)",
+			arguments.OutboundDate, arguments.HolidayDuration, returnOrigin.Name, returnOrigin.IataCode)
+	default:
+		service.logger.Infof("Looking for flights from %s staying for %d nights",
+			arguments.OutboundDate, arguments.HolidayDuration)
+	}
+	for _, airport := range origins {
+		service.logger.Infof("from %s (%s) in %s, %s", airport.Name, airport.IataCode, airport.Region, airport.Country)
+	}
+	for _, airport := range destinations {
+		service.logger.Infof("to %s (%s) in %s, %s", airport.Name, airport.IataCode, airport.Region, airport.Country)
 	}
+	service.logger.Infof("for %d adults, %d children, %d infants, in %s class, priced in %s",
+		arguments.Adults, arguments.Children, arguments.Infants, arguments.CabinClass, arguments.Currency)
 
-	/*
-	 * The way the skyscanner API works is that we first make our search,
-	 * then poll for results.
-	 */
-	sessionKey, err := service.skyScannerQuoter.StartSearch(arguments)
+	results, err := succeeded(ctx, service.logger,
+		service.orchestrator.SearchAll(ctx, searches, airports, maxCacheAge))
 	if err != nil {
-		service.logger.Fatal(err)
+		return nil, err
+	}
+	if len(searches) == 1 {
+		return results[0].Quote, nil
 	}
 
-	/*
-	 * In practice, initial polls return partial results and have status of "UpdatesPending"
-	 * Then after typically 20-30 seconds we get a fully populated result with status of "UpdatesComplete".
-	 */
-	var response *domain.Quote
-	for index := 0; index < 6; index++ {
+	quotes := []*domain.Quote{}
+	for _, result := range results {
+		quotes = append(quotes, result.Quote)
+	}
+	return domain.MergeQuotes(quotes)
+}
 
-		service.logger.Debugf("Poll %d...", index)
-		response, err = service.skyScannerQuoter.PollForQuotes(sessionKey, arguments.APIHost, arguments.APIKey, airports)
-		if err != nil {
-			service.logger.Fatal(err)
-		}
+// ResumeSearches resumes every search that was interrupted before its quotes were complete, e.g. by the program being
+// killed, using the API host and key of the arguments. Searches whose sessions have expired are started again. The
+// quotes of each resumed search are saved, and returned oldest search first; searches that fail are skipped, unless
+// they all fail. The searches are abandoned once the context is done.
+func (service *QuoteForFlightsService) ResumeSearches(ctx context.Context, arguments *domain.Arguments) (
+	[]*domain.Quote, error) {
+	provider := service.orchestrator.searcher.provider
+	resumer, ok := provider.(SearchResumer)
+	if !ok {
+		return nil, fmt.Errorf("Searches from %s can't be resumed", provider.Name())
+	}
 
-		service.logger.Debugf("Polled for quotes, status is %t, found %d itineries",
-			response.Complete, len(response.Itineraries))
+	sessions, err := service.flightRepository.ReadSearchSessions()
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		service.logger.Info("No interrupted searches to resume")
+		return []*domain.Quote{}, nil
+	}
 
-		if response.Complete {
-			service.logger.Debugf("Quotes are complete...")
-			break
+	airports, err := service.finder.LoadMajorAirports()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, len(sessions))
+	for index, session := range sessions {
+		sessionArguments := session.Arguments
+		sessionArguments.APIHost = arguments.APIHost
+		sessionArguments.APIKey = arguments.APIKey
+		results[index] = SearchResult{Arguments: &sessionArguments}
+
+		results[index].Err = ctx.Err()
+		if results[index].Err != nil {
+			continue
+		}
+		service.logger.Infof("Resuming search from %s to %s leaving %s for %d nights, started %s",
+			session.Arguments.Origin, session.Arguments.Destination, session.Arguments.OutboundDate,
+			session.Arguments.HolidayDuration, session.Created.Local().Format("2006-01-02 15:04"))
+		results[index].Quote, results[index].Err = service.orchestrator.searcher.resume(ctx, resumer, session,
+			&sessionArguments, airports)
+		if results[index].Err != nil {
+			service.logger.Warnf("Resumed search from %s to %s failed: %s", session.Arguments.Origin,
+				session.Arguments.Destination, results[index].Err)
 		}
-
-		time.Sleep(10 * time.Second)
 	}
 
-	if response.Complete {
-		service.outputQuotes(response)
-	} else {
-		service.logger.Fatalf("Quotes not completed in time")
+	results, err = succeeded(ctx, service.logger, results)
+	if err != nil {
+		return nil, err
 	}
+	quotes := []*domain.Quote{}
+	for _, result := range results {
+		quotes = append(quotes, result.Quote)
+	}
+	return quotes, nil
 }
 
-// loadArguments attempts to load the details to quote for, and returns the arguments, origin airport, dest airport,
-// or an error.
-func (service *QuoteForFlightsService) loadArguments(argumentsFilename string, airports map[string]domain.Airport) (
-	*domain.Arguments, *domain.Airport, *domain.Airport, error) {
-	arguments, err := service.loader.Load(argumentsFilename)
-	if err != nil {
-		return nil, nil, nil, err
+// FindBookingDetails finds the ways to book the itinerary with each supplier, using the API host and key of the
+// arguments. This only works if the provider of the itinerary can find booking details, e.g. for itineraries of a live
+// sky scanner search (not a saved quote), while its search session lasts.
+func (service *QuoteForFlightsService) FindBookingDetails(ctx context.Context, arguments *domain.Arguments,
+	itinerary *domain.Itinerary) (*domain.BookingDetails, error) {
+	provider := service.orchestrator.searcher.provider
+	finder, ok := provider.(BookingDetailsFinder)
+	if !ok {
+		return nil, fmt.Errorf("No booking details for itineraries from %s", provider.Name())
 	}
+	return finder.FindBookingDetails(ctx, arguments, itinerary)
+}
 
-	originAirport, exists := airports[arguments.Origin]
-	if !exists {
-		return nil, nil, nil, fmt.Errorf("Origin airport code %s unknown", arguments.Origin)
+// OutputBookingDetails logs each way to book an itinerary, cheapest first.
+func (service *QuoteForFlightsService) OutputBookingDetails(details *domain.BookingDetails,
+	format *domain.CurrencyFormat) {
+	service.logger.Infof("Found %d booking options", len(details.Options))
+	if !details.Complete {
+		service.logger.Warn("Some suppliers have yet to confirm their prices")
 	}
-
-	destinationAirport, exists := airports[arguments.Destination]
-	if !exists {
-		return nil, nil, nil, fmt.Errorf("Destination airport code %s unknown", arguments.Destination)
+	for index, option := range details.Options {
+		if price, err := option.Price(); err != nil {
+			service.logger.Warnf("Booking option %d has no total price: %s", index+1, err)
+		} else {
+			service.logger.Infof("Booking option %d is %s", index+1, price.Format(format))
+		}
+		for _, item := range option.Items {
+			service.logger.Infof("%s (%s) %s, %s: %s", item.SupplierName, item.SupplierType,
+				item.Price.Format(format), item.Status, item.DeeplinkURL)
+		}
 	}
-
-	return arguments, &originAirport, &destinationAirport, nil
 }
 
-func (service *QuoteForFlightsService) outputQuotes(response *domain.Quote) {
+// OutputQuotes logs the details of every itinerary in the quote.
+func (service *QuoteForFlightsService) OutputQuotes(response *domain.Quote) {
 	const dayTimeFormat = "2006-01-02 15:04"
 	service.logger.Infof("Quote completed, found %d flights", len(response.Itineraries))
 	for _, itinerary := range response.Itineraries {
-		service.logger.Infof("Flight with %s (%s) is %s",
-			itinerary.SupplierName, itinerary.SupplierType, formatPrice(itinerary.Amount))
+		service.logger.Infof("Flight from %s to %s with %s (%s) is %s, found by %s", itinerary.Origin,
+			itinerary.Destination, itinerary.SupplierName, itinerary.SupplierType,
+			itinerary.Price.Format(response.CurrencyFormat), itinerary.Provider)
+		if itinerary.DeeplinkURL != "" {
+			service.logger.Infof("Book at %s (price quoted %s ago)", itinerary.DeeplinkURL,
+				formatQuoteAge(itinerary.QuoteAge))
+		}
 
 		outboundJourney := itinerary.OutboundJourney
 		service.logger.Infof("Outbound Journey takes %s", formatFlightDuration(outboundJourney.Duration))
@@ -138,6 +209,9 @@ func (service *QuoteForFlightsService) outputQuotes(response *domain.Quote) {
 		}
 
 		inboundJourney := itinerary.InboundJourney
+		if inboundJourney == nil {
+			continue // one-way trip
+		}
 		service.logger.Infof("Inbound Journey takes %s", formatFlightDuration(inboundJourney.Duration))
 		for index, flight := range inboundJourney.Flights {
 			service.logger.Infof("Inbound flight %d is flight %s%s (%s) from %s (%s) to %s (%s)",
@@ -152,8 +226,13 @@ func (service *QuoteForFlightsService) outputQuotes(response *domain.Quote) {
 	}
 }
 
-func formatPrice(amount int) string {
-	return fmt.Sprintf("%.2f", float64(amount)/100.0)
+// formatQuoteAge returns the age of a price in minutes, or hours and minutes if over an hour.
+func formatQuoteAge(age time.Duration) string {
+	minutes := int(age.Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%d mins", minutes)
+	}
+	return fmt.Sprintf("%d hrs, %d mins", minutes/60, minutes%60)
 }
 
 func formatFlightDuration(duration time.Duration) string {
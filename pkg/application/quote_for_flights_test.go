@@ -0,0 +1,343 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var groupPricing = true
+
+// dummyArguments has every optional field set to its default, so is unchanged by WithDefaults.
+var dummyArguments = domain.Arguments{
+	Origin:          "Code1",
+	Destination:     "Code2",
+	Adults:          2,
+	OutboundDate:    "2019-11-01",
+	HolidayDuration: 14,
+	CabinClass:      "economy",
+	Currency:        "GBP",
+	Country:         "GB",
+	Locale:          "en-GB",
+	GroupPricing:    &groupPricing,
+	APIHost:         "test.com",
+	APIKey:          "testKey",
+}
+
+var poundFormat = &domain.CurrencyFormat{Code: "GBP", Symbol: "£", ThousandsSeparator: ",", DecimalSeparator: ".",
+	SymbolOnLeft: true, DecimalDigits: 2}
+
+var dummyQuote = &domain.Quote{
+	ID:             7,
+	Currency:       "GBP",
+	CurrencyFormat: poundFormat,
+	Itineraries:    []*domain.Itinerary{&domain.Itinerary{Price: domain.Money{Amount: 12345, Currency: "GBP"}}},
+	Complete:       true,
+}
+
+// allowLogging lets the mock logger accept any log message, with up to 6 arguments.
+func allowLogging(mockLogger *mocks.Logger) {
+	for _, method := range []string{"Debug", "Debugf", "Info", "Infof", "Warn", "Warnf"} {
+		args := []interface{}{}
+		for count := 0; count <= 6; count++ {
+			args = append(args, mock.Anything)
+			mockLogger.On(method, args...).Maybe()
+		}
+	}
+}
+
+// newTestQuoteService returns a service with mock dependencies, that knows about the dummy airports.
+func newTestQuoteService() (*QuoteForFlightsService, *mocks.SkyScannerQuoter, *mocks.FlightRepository) {
+	mockLogger := &mocks.Logger{}
+	mockFinder := &mocks.AirportFinder{}
+	mockQuoter := &mocks.SkyScannerQuoter{}
+	mockRepository := &mocks.FlightRepository{}
+
+	allowLogging(mockLogger)
+	mockFinder.On("LoadMajorAirports").Return(dummyAirports, nil)
+
+	orchestrator := NewSearchOrchestrator(mockLogger,
+		NewSkyScannerProvider(mockLogger, mockQuoter, testPollStrategy, nil), mockRepository, 1)
+	return NewQuoteForFlightsService(mockLogger, mockFinder, mockRepository, orchestrator), mockQuoter, mockRepository
+}
+
+// expectLiveSearch sets up the mocks for a search that completes on the first poll.
+func expectLiveSearch(mockQuoter *mocks.SkyScannerQuoter, mockRepository *mocks.FlightRepository) {
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(dummyQuote, nil)
+	mockRepository.On("SaveQuote", dummyQuote).Return(nil)
+	mockRepository.On("SaveSearchRun", mock.Anything).Return(nil)
+}
+
+// TestQuoteForFlights_NoCache tests a search is always made when the cache is not used.
+func TestQuoteForFlights_NoCache(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+	expectLiveSearch(mockQuoter, mockRepository)
+
+	result, err := service.QuoteForFlights(context.Background(), &dummyArguments, 0)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, dummyQuote, result, "Wrong result")
+	mockQuoter.AssertExpectations(t)
+	mockRepository.AssertExpectations(t)
+	mockRepository.AssertNotCalled(t, "ReadLatestSearchRun", mock.Anything)
+}
+
+// TestQuoteForFlights_CacheHit tests a recent saved quote is returned without searching.
+func TestQuoteForFlights_CacheHit(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+
+	run := &domain.SearchRun{QuoteID: 7, Created: time.Now().Add(-time.Hour)}
+	mockRepository.On("ReadLatestSearchRun", &dummyArguments).Return(run, nil)
+	mockRepository.On("ReadQuote", int64(7)).Return(dummyQuote, nil)
+
+	result, err := service.QuoteForFlights(context.Background(), &dummyArguments, 2*time.Hour)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, dummyQuote, result, "Wrong result")
+	mockRepository.AssertExpectations(t)
+	mockQuoter.AssertNotCalled(t, "StartSearch", mock.Anything, mock.Anything)
+}
+
+// TestQuoteForFlights_CacheStale tests a search is made when the saved quote is too old.
+func TestQuoteForFlights_CacheStale(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+
+	run := &domain.SearchRun{QuoteID: 7, Created: time.Now().Add(-3 * time.Hour)}
+	mockRepository.On("ReadLatestSearchRun", &dummyArguments).Return(run, nil)
+	expectLiveSearch(mockQuoter, mockRepository)
+
+	result, err := service.QuoteForFlights(context.Background(), &dummyArguments, 2*time.Hour)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, dummyQuote, result, "Wrong result")
+	mockQuoter.AssertExpectations(t)
+	mockRepository.AssertNotCalled(t, "ReadQuote", mock.Anything)
+}
+
+// TestQuoteForFlights_CacheMiss tests a search is made when there is no saved quote.
+func TestQuoteForFlights_CacheMiss(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+
+	mockRepository.On("ReadLatestSearchRun", &dummyArguments).Return(nil, nil)
+	expectLiveSearch(mockQuoter, mockRepository)
+
+	result, err := service.QuoteForFlights(context.Background(), &dummyArguments, 2*time.Hour)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, dummyQuote, result, "Wrong result")
+	mockQuoter.AssertExpectations(t)
+}
+
+// TestQuoteForFlights_InvalidCurrency tests searching with an invalid currency.
+func TestQuoteForFlights_InvalidCurrency(t *testing.T) {
+	service, mockQuoter, _ := newTestQuoteService()
+
+	arguments := dummyArguments
+	arguments.Currency = "pounds"
+
+	result, err := service.QuoteForFlights(context.Background(), &arguments, 0)
+	assert.Nil(t, result, "Expected no result")
+	assert.EqualError(t, err, "Invalid currency pounds, expected an ISO 4217 code such as GBP")
+	mockQuoter.AssertNotCalled(t, "StartSearch", mock.Anything, mock.Anything)
+}
+
+// TestQuoteForFlights_UnknownAirport tests searching from an unknown airport.
+func TestQuoteForFlights_UnknownAirport(t *testing.T) {
+	service, mockQuoter, _ := newTestQuoteService()
+
+	arguments := dummyArguments
+	arguments.Origin = "XYZ"
+
+	result, err := service.QuoteForFlights(context.Background(), &arguments, 0)
+	assert.Nil(t, result, "Expected no result")
+	assert.EqualError(t, err, "Origin airport code XYZ unknown")
+	mockQuoter.AssertNotCalled(t, "StartSearch", mock.Anything, mock.Anything)
+}
+
+// TestQuoteForFlights_MultipleAirports tests every route is searched, and the itineraries merged cheapest first.
+func TestQuoteForFlights_MultipleAirports(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+	mockRepository.On("SaveQuote", mock.Anything).Return(nil)
+	mockRepository.On("SaveSearchRun", mock.Anything).Return(nil)
+
+	arguments := dummyArguments
+	arguments.Origins = []string{"Code2"}
+	arguments.Destinations = []string{"Code1"}
+
+	outbound := dummyArguments
+	inbound := dummyArguments
+	inbound.Origin = "Code2"
+	inbound.Destination = "Code1"
+
+	expensive := &domain.Itinerary{Price: domain.Money{Amount: 200, Currency: "GBP"}}
+	cheap := &domain.Itinerary{Price: domain.Money{Amount: 100, Currency: "GBP"}}
+	mockQuoter.On("StartSearch", mock.Anything, &outbound).Return("outbound", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "outbound", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Itineraries: []*domain.Itinerary{expensive}, Complete: true}, nil)
+	mockQuoter.On("StartSearch", mock.Anything, &inbound).Return("inbound", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "inbound", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Itineraries: []*domain.Itinerary{cheap}, Complete: true}, nil)
+
+	result, err := service.QuoteForFlights(context.Background(), &arguments, 0)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, []*domain.Itinerary{cheap, expensive}, result.Itineraries, "Wrong itineraries")
+	assert.Equal(t, "Code2", cheap.Origin, "Wrong origin")
+	assert.Equal(t, "Code1", expensive.Origin, "Wrong origin")
+	mockQuoter.AssertExpectations(t)
+}
+
+// TestQuoteForFlights_OpenJaw tests an open-jaw trip is searched as two one-way trips, then combined.
+func TestQuoteForFlights_OpenJaw(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+	mockRepository.On("SaveQuote", mock.Anything).Return(nil)
+	mockRepository.On("SaveSearchRun", mock.Anything).Return(nil)
+
+	arguments := dummyArguments
+	arguments.TripType = domain.OpenJawTrip
+	arguments.ReturnOrigin = "Code2"
+
+	outbound := dummyArguments
+	outbound.TripType = domain.OneWayTrip
+	outbound.HolidayDuration = 0
+	inbound := outbound
+	inbound.Origin = "Code2"
+	inbound.Destination = "Code1"
+	inbound.OutboundDate = "2019-11-15"
+
+	outboundJourney := &domain.Journey{ID: "leg1", Direction: domain.Outbound}
+	inboundJourney := &domain.Journey{ID: "leg2", Direction: domain.Outbound}
+	mockQuoter.On("StartSearch", mock.Anything, &outbound).Return("outbound", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "outbound", "test.com", "testKey", dummyAirports).Return(&domain.Quote{
+		Itineraries: []*domain.Itinerary{&domain.Itinerary{Price: domain.Money{Amount: 200, Currency: "GBP"}, OutboundJourney: outboundJourney}},
+		Complete:    true}, nil)
+	mockQuoter.On("StartSearch", mock.Anything, &inbound).Return("inbound", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "inbound", "test.com", "testKey", dummyAirports).Return(&domain.Quote{
+		Itineraries: []*domain.Itinerary{&domain.Itinerary{Price: domain.Money{Amount: 100, Currency: "GBP"}, OutboundJourney: inboundJourney}},
+		Complete:    true}, nil)
+
+	result, err := service.QuoteForFlights(context.Background(), &arguments, 0)
+	assert.Nil(t, err, "Expected no error")
+	assert.Len(t, result.Itineraries, 1, "Wrong number of itineraries")
+	assert.Equal(t, 300, result.Itineraries[0].Price.Amount, "Wrong amount")
+	assert.Equal(t, "leg2", result.Itineraries[0].InboundJourney.ID, "Wrong inbound journey")
+	assert.Equal(t, domain.Inbound, result.Itineraries[0].InboundJourney.Direction, "Wrong direction")
+	mockQuoter.AssertExpectations(t)
+}
+
+// TestFindBookingDetails_HappyPath tests finding the booking details of an itinerary of a live search.
+func TestFindBookingDetails_HappyPath(t *testing.T) {
+	service, mockQuoter, _ := newTestQuoteService()
+
+	link := &domain.BookingDetailsLink{URI: "/booking", Body: "OutboundLegId=leg1", Method: "PUT"}
+	itinerary := &domain.Itinerary{Price: domain.Money{Amount: 12345, Currency: "GBP"}, BookingDetailsLink: link}
+	expected := &domain.BookingDetails{Options: []*domain.BookingOption{}, Complete: true}
+	mockQuoter.On("GetBookingDetails", mock.Anything, link, "test.com", "testKey").Return(expected, nil)
+
+	details, err := service.FindBookingDetails(context.Background(), &dummyArguments, itinerary)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, expected, details, "Wrong booking details")
+}
+
+// TestFindBookingDetails_NoLink tests finding the booking details of an itinerary of a saved quote.
+func TestFindBookingDetails_NoLink(t *testing.T) {
+	service, mockQuoter, _ := newTestQuoteService()
+
+	_, err := service.FindBookingDetails(context.Background(), &dummyArguments, dummyQuote.Itineraries[0])
+	assert.Error(t, err, "Error expected")
+	mockQuoter.AssertNotCalled(t, "GetBookingDetails", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// newTestResumeService returns a service with mock dependencies, whose provider saves search sessions.
+func newTestResumeService() (*QuoteForFlightsService, *mocks.SkyScannerQuoter, *mocks.FlightRepository) {
+	service, mockQuoter, mockRepository := newTestQuoteService()
+	service.orchestrator.searcher.provider.(*SkyScannerProvider).flightRepository = mockRepository
+	mockRepository.On("SaveQuote", mock.Anything).Return(nil)
+	mockRepository.On("SaveSearchRun", mock.Anything).Return(nil)
+	return service, mockQuoter, mockRepository
+}
+
+// TestQuoteForFlights_SessionSaved tests the session of a search is saved until its quotes are complete.
+func TestQuoteForFlights_SessionSaved(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestResumeService()
+
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(dummyQuote, nil)
+	mockRepository.On("SaveSearchSession", mock.MatchedBy(func(session *domain.SearchSession) bool {
+		return session.SessionKey == "abc" && session.Arguments.Origin == "Code1" && session.Arguments.APIKey == ""
+	})).Return(nil)
+	mockRepository.On("DeleteSearchSession", "abc").Return(nil)
+
+	_, err := service.QuoteForFlights(context.Background(), &dummyArguments, 0)
+	assert.Nil(t, err, "Expected no error")
+	mockRepository.AssertExpectations(t)
+}
+
+// TestQuoteForFlights_SessionKept tests the session of a search that doesn't complete is kept, to be resumed.
+func TestQuoteForFlights_SessionKept(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestResumeService()
+	testPoll(service.orchestrator).Deadline = 0
+
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Complete: false}, nil)
+	mockRepository.On("SaveSearchSession", mock.Anything).Return(nil)
+
+	_, err := service.QuoteForFlights(context.Background(), &dummyArguments, 0)
+	assert.EqualError(t, err, "Quotes not completed within 0s")
+	mockRepository.AssertNotCalled(t, "DeleteSearchSession", mock.Anything)
+}
+
+// TestResumeSearches_StillValid tests a session that hasn't expired is polled, without starting a new search.
+func TestResumeSearches_StillValid(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestResumeService()
+
+	session := domain.NewSearchSession("abc", &dummyArguments, time.Now())
+	mockRepository.On("ReadSearchSessions").Return([]*domain.SearchSession{session}, nil)
+	mockRepository.On("DeleteSearchSession", "abc").Return(nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(dummyQuote, nil)
+
+	quotes, err := service.ResumeSearches(context.Background(), &dummyArguments)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, []*domain.Quote{dummyQuote}, quotes, "Wrong quotes")
+	assert.Equal(t, "skyscanner", dummyQuote.Itineraries[0].Provider, "Wrong provider")
+	mockRepository.AssertExpectations(t)
+	mockQuoter.AssertNotCalled(t, "StartSearch", mock.Anything, mock.Anything)
+}
+
+// TestResumeSearches_Expired tests a new search is started when the session has expired.
+func TestResumeSearches_Expired(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestResumeService()
+
+	session := domain.NewSearchSession("old", &dummyArguments, time.Now().Add(-time.Hour))
+	arguments := session.Arguments
+	arguments.APIHost = "test.com"
+	arguments.APIKey = "testKey"
+	mockRepository.On("ReadSearchSessions").Return([]*domain.SearchSession{session}, nil)
+	mockRepository.On("DeleteSearchSession", "old").Return(nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "old", "test.com", "testKey", dummyAirports).Return(nil,
+		domain.ErrSessionExpired)
+	mockQuoter.On("StartSearch", mock.Anything, &arguments).Return("new", nil)
+	mockRepository.On("SaveSearchSession", mock.Anything).Return(nil)
+	mockRepository.On("DeleteSearchSession", "new").Return(nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "new", "test.com", "testKey", dummyAirports).Return(dummyQuote, nil)
+
+	quotes, err := service.ResumeSearches(context.Background(), &dummyArguments)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, []*domain.Quote{dummyQuote}, quotes, "Wrong quotes")
+	mockRepository.AssertExpectations(t)
+	mockQuoter.AssertExpectations(t)
+}
+
+// TestResumeSearches_None tests resuming when no searches were interrupted.
+func TestResumeSearches_None(t *testing.T) {
+	service, mockQuoter, mockRepository := newTestResumeService()
+	mockRepository.On("ReadSearchSessions").Return([]*domain.SearchSession{}, nil)
+
+	quotes, err := service.ResumeSearches(context.Background(), &dummyArguments)
+	assert.Nil(t, err, "Expected no error")
+	assert.Empty(t, quotes, "Expected no quotes")
+	mockQuoter.AssertNotCalled(t, "PollForQuotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything)
+}
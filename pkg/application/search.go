@@ -0,0 +1,114 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// flightSearcher handles a single search for quotes, from the repository cache or from a quote provider.
+type flightSearcher struct {
+	logger           domain.Logger
+	provider         FlightQuoteProvider
+	flightRepository FlightRepository
+}
+
+// quote returns the most recent saved quote for the search if no older than maxCacheAge (zero always searches),
+// otherwise searches for quotes then saves them.
+func (searcher *flightSearcher) quote(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport, maxCacheAge time.Duration) (*domain.Quote, error) {
+	if maxCacheAge > 0 {
+		quote, err := searcher.readCachedQuote(arguments, maxCacheAge)
+		if err != nil || quote != nil {
+			return quote, err
+		}
+	}
+
+	quote, err := searcher.search(ctx, arguments, airports)
+	if err != nil {
+		return nil, err
+	}
+	return quote, searcher.save(arguments, quote)
+}
+
+// resume resumes an interrupted search with the resumer, then saves its quotes. The arguments are those of the
+// session, with the API host and key to use.
+func (searcher *flightSearcher) resume(ctx context.Context, resumer SearchResumer, session *domain.SearchSession,
+	arguments *domain.Arguments, airports map[string]domain.Airport) (*domain.Quote, error) {
+	quote, err := resumer.ResumeSearch(ctx, session, arguments.APIHost, arguments.APIKey, airports)
+	if err != nil {
+		return nil, err
+	}
+	searcher.fillIn(arguments, quote)
+	return quote, searcher.save(arguments, quote)
+}
+
+// save saves the quote, and a search run recording its prices if it found any itineraries.
+func (searcher *flightSearcher) save(arguments *domain.Arguments, quote *domain.Quote) error {
+	err := searcher.flightRepository.SaveQuote(quote)
+	if err != nil {
+		return err
+	}
+	searcher.logger.Debugf("Saved quote with id %d", quote.ID)
+
+	if len(quote.Itineraries) > 0 {
+		return searcher.flightRepository.SaveSearchRun(domain.NewSearchRun(arguments, quote, time.Now()))
+	}
+	return nil
+}
+
+// readCachedQuote returns the most recent saved quote for the same search, if no older than maxAge, or nil.
+func (searcher *flightSearcher) readCachedQuote(arguments *domain.Arguments, maxAge time.Duration) (
+	*domain.Quote, error) {
+	run, err := searcher.flightRepository.ReadLatestSearchRun(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if run == nil {
+		searcher.logger.Debug("No saved quote found, searching...")
+		return nil, nil
+	}
+
+	age := time.Since(run.Created)
+	if age > maxAge {
+		searcher.logger.Debugf("Saved quote %d is %s old, searching...", run.QuoteID, age.Round(time.Minute))
+		return nil, nil
+	}
+
+	searcher.logger.Infof("Using saved quote %d from %s", run.QuoteID, run.Created.Local().Format("2006-01-02 15:04"))
+	return searcher.flightRepository.ReadQuote(run.QuoteID)
+}
+
+// search finds quotes from the provider, filling in any details of the itineraries that the provider left empty.
+func (searcher *flightSearcher) search(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	response, err := searcher.provider.Quote(ctx, arguments, airports)
+	if err != nil {
+		return nil, err
+	}
+	searcher.fillIn(arguments, response)
+	return response, nil
+}
+
+// fillIn sets the currency, origin, destination and provider of any itineraries in the response that are empty.
+func (searcher *flightSearcher) fillIn(arguments *domain.Arguments, response *domain.Quote) {
+	if response.Currency == "" {
+		response.Currency = arguments.Currency
+	}
+	for _, itinerary := range response.Itineraries {
+		if itinerary.Origin == "" {
+			itinerary.Origin = arguments.Origin
+		}
+		if itinerary.Destination == "" {
+			itinerary.Destination = arguments.Destination
+		}
+		if itinerary.Price.Currency == "" {
+			itinerary.Price.Currency = response.Currency
+		}
+		if itinerary.Provider == "" {
+			itinerary.Provider = searcher.provider.Name()
+		}
+	}
+}
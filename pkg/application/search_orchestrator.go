@@ -0,0 +1,131 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// SearchResult is the outcome of a single search.
+type SearchResult struct {
+	Arguments *domain.Arguments
+	Quote     *domain.Quote
+	Err       error
+}
+
+// SearchOrchestrator handles running many searches concurrently, with a bounded number of workers that share a quote
+// provider.
+type SearchOrchestrator struct {
+	logger   domain.Logger
+	searcher *flightSearcher
+	workers  int
+}
+
+// NewSearchOrchestrator creates a new instance, running up to the specified number of searches at a time with the
+// provider.
+func NewSearchOrchestrator(logger domain.Logger, provider FlightQuoteProvider, flightRepository FlightRepository,
+	workers int) *SearchOrchestrator {
+	if workers < 1 {
+		workers = 1
+	}
+	return &SearchOrchestrator{logger, &flightSearcher{logger, provider, flightRepository}, workers}
+}
+
+// SearchAll runs all of the searches, saving each quote to the repository, and returns their results in the same order.
+// Saved quotes no older than maxCacheAge are used rather than searching again (a maxCacheAge of zero always searches).
+// Once the context is done, searches in progress are abandoned and those not yet started fail with the context error.
+// Once a search fails as unauthorized, every other search would too, so all the rest fail with the same error.
+func (orchestrator *SearchOrchestrator) SearchAll(ctx context.Context, searches []*domain.Arguments,
+	airports map[string]domain.Airport, maxCacheAge time.Duration) []SearchResult {
+	results := make([]SearchResult, len(searches))
+	indexes := make(chan int)
+
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var abortOnce sync.Once
+	var abortErr error
+
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < orchestrator.workers && worker < len(searches); worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range indexes {
+				results[index] = orchestrator.search(searchCtx, index, searches, airports, maxCacheAge)
+				if errors.Is(results[index].Err, domain.ErrUnauthorized) {
+					abortOnce.Do(func() {
+						abortErr = results[index].Err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for index := range searches {
+		indexes <- index
+	}
+	close(indexes)
+	waitGroup.Wait()
+
+	if abortErr != nil && ctx.Err() == nil {
+		for index := range results {
+			if results[index].Err == context.Canceled {
+				results[index].Err = abortErr
+			}
+		}
+	}
+	return results
+}
+
+// search runs the search at the index, unless the context is already done.
+func (orchestrator *SearchOrchestrator) search(ctx context.Context, index int, searches []*domain.Arguments,
+	airports map[string]domain.Airport, maxCacheAge time.Duration) SearchResult {
+	arguments := searches[index]
+	result := SearchResult{Arguments: arguments}
+
+	result.Err = ctx.Err()
+	if result.Err != nil {
+		return result
+	}
+
+	orchestrator.logger.Infof("Search %d of %d, from %s to %s leaving %s for %d nights", index+1, len(searches),
+		arguments.Origin, arguments.Destination, arguments.OutboundDate, arguments.HolidayDuration)
+	result.Quote, result.Err = orchestrator.searcher.quote(ctx, arguments, airports, maxCacheAge)
+	if result.Err != nil {
+		orchestrator.logger.Warnf("Search %d of %d failed: %s", index+1, len(searches), result.Err)
+	}
+	return result
+}
+
+// succeeded returns the results of the searches that succeeded, logging how many failed. Returns an error if the
+// context is done, or every search failed.
+func succeeded(ctx context.Context, logger domain.Logger, results []SearchResult) ([]SearchResult, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	successes := []SearchResult{}
+	var firstErr error
+	for _, result := range results {
+		if result.Err == nil {
+			successes = append(successes, result)
+		} else if firstErr == nil {
+			firstErr = result.Err
+		}
+	}
+
+	if len(successes) == 0 && len(results) > 1 {
+		return nil, fmt.Errorf("All %d searches failed, the first with: %w", len(results), firstErr)
+	} else if len(successes) == 0 {
+		return nil, firstErr
+	}
+	if len(successes) < len(results) {
+		logger.Warnf("%d of %d searches failed", len(results)-len(successes), len(results))
+	}
+	return successes, nil
+}
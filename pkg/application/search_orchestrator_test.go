@@ -0,0 +1,176 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testPollStrategy polls without waiting.
+var testPollStrategy = PollStrategy{Interval: time.Millisecond, Backoff: 1, MaxInterval: time.Millisecond,
+	Deadline: time.Second}
+
+// testPoll returns the poll strategy of an orchestrator created by newTestOrchestrator, so tests can change it.
+func testPoll(orchestrator *SearchOrchestrator) *PollStrategy {
+	return &orchestrator.searcher.provider.(*SkyScannerProvider).poll
+}
+
+// newTestOrchestrator returns an orchestrator with mock dependencies, that polls without waiting.
+func newTestOrchestrator(workers int) (*SearchOrchestrator, *mocks.SkyScannerQuoter) {
+	mockLogger := &mocks.Logger{}
+	mockQuoter := &mocks.SkyScannerQuoter{}
+	mockRepository := &mocks.FlightRepository{}
+
+	allowLogging(mockLogger)
+	mockRepository.On("SaveQuote", mock.Anything).Return(nil)
+	mockRepository.On("SaveSearchRun", mock.Anything).Return(nil)
+
+	provider := NewSkyScannerProvider(mockLogger, mockQuoter, testPollStrategy, nil)
+	orchestrator := NewSearchOrchestrator(mockLogger, provider, mockRepository, workers)
+	return orchestrator, mockQuoter
+}
+
+// TestSearchAll_Order tests results are returned in the same order as the searches, whichever finishes first.
+func TestSearchAll_Order(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(3)
+
+	searches := []*domain.Arguments{}
+	for _, date := range []string{"2019-11-01", "2019-11-02", "2019-11-03"} {
+		search := dummyArguments
+		search.OutboundDate = date
+		searches = append(searches, &search)
+	}
+
+	first := &domain.Quote{Itineraries: []*domain.Itinerary{&domain.Itinerary{Price: domain.Money{Amount: 1, Currency: "GBP"}}}, Complete: true}
+	third := &domain.Quote{Itineraries: []*domain.Itinerary{&domain.Itinerary{Price: domain.Money{Amount: 3, Currency: "GBP"}}}, Complete: true}
+	mockQuoter.On("StartSearch", mock.Anything, searches[0]).Return("first", nil).After(20 * time.Millisecond)
+	mockQuoter.On("StartSearch", mock.Anything, searches[1]).Return("", errors.New("Oops"))
+	mockQuoter.On("StartSearch", mock.Anything, searches[2]).Return("third", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "first", "test.com", "testKey", dummyAirports).Return(first, nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "third", "test.com", "testKey", dummyAirports).Return(third, nil)
+
+	results := orchestrator.SearchAll(context.Background(), searches, dummyAirports, 0)
+	assert.Equal(t, []SearchResult{
+		SearchResult{searches[0], first, nil},
+		SearchResult{searches[1], nil, errors.New("Oops")},
+		SearchResult{searches[2], third, nil},
+	}, results, "Wrong results")
+}
+
+// TestSearchAll_Cancelled tests searches are not made once the context is done.
+func TestSearchAll_Cancelled(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := orchestrator.SearchAll(ctx, []*domain.Arguments{&dummyArguments, &dummyArguments}, dummyAirports, 0)
+	assert.Len(t, results, 2, "Wrong number of results")
+	for _, result := range results {
+		assert.Equal(t, context.Canceled, result.Err, "Wrong error")
+	}
+	mockQuoter.AssertNotCalled(t, "StartSearch", mock.Anything, mock.Anything)
+}
+
+// TestSearchAll_CancelledWhilePolling tests a search in progress is abandoned once the context is done.
+func TestSearchAll_CancelledWhilePolling(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(1)
+	testPoll(orchestrator).Interval = time.Hour
+	testPoll(orchestrator).MaxInterval = time.Hour
+	testPoll(orchestrator).Deadline = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Complete: false}, nil).Run(func(mock.Arguments) { cancel() })
+
+	results := orchestrator.SearchAll(ctx, []*domain.Arguments{&dummyArguments}, dummyAirports, 0)
+	assert.Equal(t, context.Canceled, results[0].Err, "Wrong error")
+}
+
+// TestSearchAll_Unauthorized tests the remaining searches aren't made once one fails as unauthorized.
+func TestSearchAll_Unauthorized(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(1)
+
+	searches := []*domain.Arguments{}
+	for _, date := range []string{"2019-11-01", "2019-11-02", "2019-11-03"} {
+		search := dummyArguments
+		search.OutboundDate = date
+		searches = append(searches, &search)
+	}
+	unauthorized := fmt.Errorf("%w (403 Forbidden)", domain.ErrUnauthorized)
+	mockQuoter.On("StartSearch", mock.Anything, searches[0]).Return("", unauthorized)
+
+	results := orchestrator.SearchAll(context.Background(), searches, dummyAirports, 0)
+	for _, result := range results {
+		assert.Equal(t, unauthorized, result.Err, "Wrong error")
+	}
+	mockQuoter.AssertNumberOfCalls(t, "StartSearch", 1)
+}
+
+// TestSearchAll_SessionExpired tests a search whose session expires is started again, once.
+func TestSearchAll_SessionExpired(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(1)
+
+	quote := &domain.Quote{Itineraries: []*domain.Itinerary{}, Complete: true}
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("expired", nil).Once()
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("fresh", nil).Once()
+	mockQuoter.On("PollForQuotes", mock.Anything, "expired", "test.com", "testKey", dummyAirports).Return(
+		nil, fmt.Errorf("%w (410 Gone)", domain.ErrSessionExpired))
+	mockQuoter.On("PollForQuotes", mock.Anything, "fresh", "test.com", "testKey", dummyAirports).Return(quote, nil)
+
+	results := orchestrator.SearchAll(context.Background(), []*domain.Arguments{&dummyArguments}, dummyAirports, 0)
+	assert.Nil(t, results[0].Err, "Expected no error")
+	assert.Equal(t, quote, results[0].Quote, "Wrong quote")
+	mockQuoter.AssertExpectations(t)
+}
+
+// TestSearchAll_DeadlinePassed tests a search fails if quotes aren't complete by the deadline.
+func TestSearchAll_DeadlinePassed(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(1)
+	testPoll(orchestrator).Deadline = 20 * time.Millisecond
+
+	partial := &domain.Quote{Itineraries: dummyQuote.Itineraries, Agents: 3, AgentsPending: 1}
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(partial, nil)
+
+	results := orchestrator.SearchAll(context.Background(), []*domain.Arguments{&dummyArguments}, dummyAirports, 0)
+	assert.Equal(t, errors.New("Quotes not completed within 20ms"), results[0].Err, "Wrong error")
+	assert.True(t, len(mockQuoter.Calls) > 2, "Expected several polls")
+}
+
+// TestSearchAll_AcceptPartial tests the quotes found so far are used if not complete by the deadline, when allowed.
+func TestSearchAll_AcceptPartial(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(1)
+	testPoll(orchestrator).Deadline = 20 * time.Millisecond
+	testPoll(orchestrator).AcceptPartial = true
+
+	partial := &domain.Quote{Itineraries: dummyQuote.Itineraries, Agents: 3, AgentsPending: 1}
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(partial, nil)
+
+	results := orchestrator.SearchAll(context.Background(), []*domain.Arguments{&dummyArguments}, dummyAirports, 0)
+	assert.Nil(t, results[0].Err, "No error expected")
+	assert.Equal(t, partial, results[0].Quote, "Wrong quote")
+}
+
+// TestSearchAll_AcceptPartialNoneFound tests a search still fails at the deadline if no quotes were found so far.
+func TestSearchAll_AcceptPartialNoneFound(t *testing.T) {
+	orchestrator, mockQuoter := newTestOrchestrator(1)
+	testPoll(orchestrator).Deadline = 20 * time.Millisecond
+	testPoll(orchestrator).AcceptPartial = true
+
+	mockQuoter.On("StartSearch", mock.Anything, &dummyArguments).Return("abc", nil)
+	mockQuoter.On("PollForQuotes", mock.Anything, "abc", "test.com", "testKey", dummyAirports).Return(
+		&domain.Quote{Agents: 3, AgentsPending: 3}, nil)
+
+	results := orchestrator.SearchAll(context.Background(), []*domain.Arguments{&dummyArguments}, dummyAirports, 0)
+	assert.Equal(t, errors.New("Quotes not completed within 20ms"), results[0].Err, "Wrong error")
+}
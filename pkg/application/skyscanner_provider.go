@@ -0,0 +1,227 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// SkyScannerProviderName identifies the itineraries found by sky scanner.
+const SkyScannerProviderName = "skyscanner"
+
+// SkyScannerProvider adapts the sky scanner session model, of starting a search then polling it until complete, to a
+// FlightQuoteProvider.
+type SkyScannerProvider struct {
+	logger           domain.Logger
+	skyScannerQuoter SkyScannerQuoter
+	poll             PollStrategy
+	flightRepository FlightRepository // saves the session of each search until its quotes are complete
+}
+
+// NewSkyScannerProvider creates a new instance, polling each search as per the strategy. A nil repository doesn't
+// save search sessions, so they can't be resumed.
+func NewSkyScannerProvider(logger domain.Logger, skyScannerQuoter SkyScannerQuoter, poll PollStrategy,
+	flightRepository FlightRepository) *SkyScannerProvider {
+	return &SkyScannerProvider{logger, skyScannerQuoter, poll, flightRepository}
+}
+
+// Name returns the name of the provider.
+func (provider *SkyScannerProvider) Name() string {
+	return SkyScannerProviderName
+}
+
+// Quote finds quotes from sky scanner, waiting until they are complete or the context is done.
+func (provider *SkyScannerProvider) Quote(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	if arguments.Trip() == domain.OpenJawTrip {
+		return provider.searchOpenJaw(ctx, arguments, airports)
+	}
+
+	// sessions only last a short while, so one that expires while polling is worth a single fresh attempt
+	response, err := provider.searchSession(ctx, arguments, airports)
+	if errors.Is(err, domain.ErrSessionExpired) {
+		provider.logger.Warnf("%s, starting a new search", err)
+		response, err = provider.searchSession(ctx, arguments, airports)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, itinerary := range response.Itineraries {
+		itinerary.Origin = arguments.Origin
+		itinerary.Destination = arguments.Destination
+		itinerary.Provider = SkyScannerProviderName
+	}
+	return response, nil
+}
+
+// FindBookingDetails finds the ways to book an itinerary found by sky scanner, while its search session lasts.
+func (provider *SkyScannerProvider) FindBookingDetails(ctx context.Context, arguments *domain.Arguments,
+	itinerary *domain.Itinerary) (*domain.BookingDetails, error) {
+	if itinerary.BookingDetailsLink == nil {
+		return nil, errors.New("No booking details for this itinerary, only those of a live search have them")
+	}
+
+	return provider.skyScannerQuoter.GetBookingDetails(ctx, itinerary.BookingDetailsLink, arguments.APIHost,
+		arguments.APIKey)
+}
+
+// ResumeSearch polls a sky scanner search session that was interrupted before its quotes were complete, or starts a
+// new search with the same arguments if the session has expired. Each leg of an open-jaw trip has its own session, so
+// is resumed as a one-way trip.
+func (provider *SkyScannerProvider) ResumeSearch(ctx context.Context, session *domain.SearchSession, apiHost string,
+	apiKey string, airports map[string]domain.Airport) (*domain.Quote, error) {
+	arguments := session.Arguments
+	arguments.APIHost = apiHost
+	arguments.APIKey = apiKey
+
+	response, err := provider.pollSession(ctx, session.SessionKey, &arguments, airports)
+	if errors.Is(err, domain.ErrSessionExpired) {
+		provider.logger.Warnf("%s, starting a new search", err)
+		return provider.Quote(ctx, &arguments, airports)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, itinerary := range response.Itineraries {
+		itinerary.Origin = arguments.Origin
+		itinerary.Destination = arguments.Destination
+		itinerary.Provider = SkyScannerProviderName
+	}
+	return response, nil
+}
+
+// searchSession starts a sky scanner search session, then polls it until the quotes are complete.
+func (provider *SkyScannerProvider) searchSession(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	/*
+	 * The way the skyscanner API works is that we first make our search,
+	 * then poll for results.
+	 */
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	sessionKey, err := provider.skyScannerQuoter.StartSearch(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+	if provider.flightRepository != nil {
+		err = provider.flightRepository.SaveSearchSession(domain.NewSearchSession(sessionKey, arguments, time.Now()))
+		if err != nil {
+			provider.logger.Warnf("Search session %s not saved, so can't be resumed: %s", sessionKey, err)
+		}
+	}
+
+	/*
+	 * In practice, initial polls return partial results and have status of "UpdatesPending"
+	 * Then after typically 20-30 seconds we get a fully populated result with status of "UpdatesComplete".
+	 */
+	err = sleep(ctx, provider.poll.InitialDelay)
+	if err != nil {
+		return nil, err
+	}
+	return provider.pollSession(ctx, sessionKey, arguments, airports)
+}
+
+// pollSession polls a sky scanner search session until the quotes are complete, or the poll deadline has passed. The
+// saved session is deleted once finished with, or left to be resumed if the search is interrupted or incomplete.
+func (provider *SkyScannerProvider) pollSession(ctx context.Context, sessionKey string, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	response, err := provider.pollUntilComplete(ctx, sessionKey, arguments, airports)
+	if err == nil || errors.Is(err, domain.ErrSessionExpired) {
+		provider.deleteSession(sessionKey)
+	}
+	return response, err
+}
+
+// pollUntilComplete polls a sky scanner search session until the quotes are complete, or the poll deadline has passed.
+func (provider *SkyScannerProvider) pollUntilComplete(ctx context.Context, sessionKey string,
+	arguments *domain.Arguments, airports map[string]domain.Airport) (*domain.Quote, error) {
+	var response *domain.Quote
+	deadline := time.Now().Add(provider.poll.Deadline)
+	interval := provider.poll.Interval
+	for poll := 1; ; poll++ {
+		var err error
+		response, err = provider.skyScannerQuoter.PollForQuotes(ctx, sessionKey, arguments.APIHost,
+			arguments.APIKey, airports)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Complete {
+			provider.logger.Debugf("Poll %d from %s to %s, quotes are complete, found %d itineraries", poll,
+				arguments.Origin, arguments.Destination, len(response.Itineraries))
+			return response, nil
+		}
+		provider.logger.Infof("Poll %d from %s to %s, %d of %d agents still pending, found %d itineraries so far",
+			poll, arguments.Origin, arguments.Destination, response.AgentsPending, response.Agents,
+			len(response.Itineraries))
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		if interval > remaining {
+			interval = remaining
+		}
+		err = sleep(ctx, interval)
+		if err != nil {
+			return nil, err
+		}
+		interval = provider.poll.NextInterval(interval)
+	}
+
+	if provider.poll.AcceptPartial && len(response.Itineraries) > 0 {
+		provider.logger.Warnf("Quotes from %s to %s not completed within %s, using the %d found so far",
+			arguments.Origin, arguments.Destination, provider.poll.Deadline, len(response.Itineraries))
+		return response, nil
+	}
+	return nil, fmt.Errorf("Quotes not completed within %s", provider.poll.Deadline)
+}
+
+// searchOpenJaw finds quotes for an open-jaw trip, which sky scanner can't search directly, as two one-way trips.
+func (provider *SkyScannerProvider) searchOpenJaw(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	outboundArguments, inboundArguments, err := arguments.OpenJawLegs()
+	if err != nil {
+		return nil, err
+	}
+
+	outbound, err := provider.Quote(ctx, outboundArguments, airports)
+	if err != nil {
+		return nil, err
+	}
+	inbound, err := provider.Quote(ctx, inboundArguments, airports)
+	if err != nil {
+		return nil, err
+	}
+	return domain.CombineOpenJaw(outbound, inbound)
+}
+
+// deleteSession deletes the saved search session, if sessions are saved.
+func (provider *SkyScannerProvider) deleteSession(sessionKey string) {
+	if provider.flightRepository == nil {
+		return
+	}
+	err := provider.flightRepository.DeleteSearchSession(sessionKey)
+	if err != nil {
+		provider.logger.Warnf("Search session %s not deleted: %s", sessionKey, err)
+	}
+}
+
+// sleep pauses for the duration, or returns an error if the context is done first.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
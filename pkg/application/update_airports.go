@@ -0,0 +1,130 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// airportDataFiles are every airport data file, in the order they are downloaded.
+var airportDataFiles = []string{CountriesFile, RegionsFile, AirportsFile}
+
+// UpdateAirportsService handles updating the airport data files to the latest version, and rolling them back.
+type UpdateAirportsService struct {
+	logger           domain.Logger
+	updater          AirportDataUpdater
+	loader           AirportDataLoader
+	flightRepository FlightRepository
+}
+
+// NewUpdateAirportsService creates a new instance.
+func NewUpdateAirportsService(logger domain.Logger, updater AirportDataUpdater, loader AirportDataLoader,
+	flightRepository FlightRepository) *UpdateAirportsService {
+	return &UpdateAirportsService{logger, updater, loader, flightRepository}
+}
+
+// UpdateAirports downloads the latest airport data files, checks they load, then replaces the current files with them,
+// keeping the current files so the update can be rolled back. The airports are imported into the repository, and the
+// new dataset recorded there and returned, with the warnings of the files. If anything fails, the current files are
+// left as they are, or if there were none, the new files are removed.
+func (service *UpdateAirportsService) UpdateAirports(ctx context.Context) (*domain.AirportDataset, error) {
+	dataset, stagingDir, err := service.updater.Download(ctx, AirportDataDir, airportDataFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer service.discard(stagingDir)
+
+	data, err := loadAirportData(service.loader, stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("Downloaded airport data is invalid, so was not installed: %w", err)
+	}
+	if len(data.airports) == 0 {
+		return nil, fmt.Errorf("Downloaded airport data has no airports with IATA codes, so was not installed")
+	}
+	dataset.Countries = len(data.countries)
+	dataset.Regions = len(data.regions)
+	dataset.Airports = len(data.airports)
+
+	replaced, err := service.updater.Install(AirportDataDir, stagingDir, airportDataFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.flightRepository.ImportAirports(domain.AirportMapValues(data.airports), dataset)
+	if err != nil {
+		service.uninstall(replaced)
+		return nil, err
+	}
+
+	service.logger.Infof("Updated airport data to version %d, with %d airports in %d countries", dataset.Version,
+		dataset.Airports, dataset.Countries)
+	logWarnings(service.logger, data.warnings)
+	dataset.Warnings = warningMessages(data.warnings)
+	return dataset, nil
+}
+
+// uninstall puts back the files replaced by the install, or removes the installed files if there were none before,
+// logging any failure since the update has failed either way.
+func (service *UpdateAirportsService) uninstall(replaced bool) {
+	var err error
+	if replaced {
+		err = service.updater.Rollback(AirportDataDir, airportDataFiles)
+	} else {
+		err = service.updater.Remove(AirportDataDir, airportDataFiles)
+	}
+	if err != nil {
+		service.logger.Errorf("Failed to roll back the airport data files: %s", err)
+	}
+}
+
+// discard removes the staging directory of a download, logging any failure since the update is done either way.
+func (service *UpdateAirportsService) discard(stagingDir string) {
+	err := service.updater.Discard(stagingDir)
+	if err != nil {
+		service.logger.Warnf("Failed to remove %s: %s", stagingDir, err)
+	}
+}
+
+// RollbackAirports replaces the airport data files with the previous version, kept by the last update, imports their
+// airports into the repository, and deletes the record of that update. Returns the record of the dataset now in use,
+// with the warnings of its files, or nil if it isn't known (e.g. the files were downloaded by hand).
+func (service *UpdateAirportsService) RollbackAirports() (*domain.AirportDataset, error) {
+	err := service.updater.Rollback(AirportDataDir, airportDataFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := loadAirportData(service.loader, AirportDataDir)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := service.flightRepository.ReadLatestAirportDataset()
+	if err != nil {
+		return nil, err
+	}
+	err = service.flightRepository.ImportAirports(domain.AirportMapValues(data.airports), nil)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		err = service.flightRepository.DeleteAirportDataset(latest.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := service.flightRepository.ReadLatestAirportDataset()
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		service.logger.Info("Rolled back airport data to the previous version, which has no recorded version")
+	} else {
+		service.logger.Infof("Rolled back airport data to version %d, downloaded %s", current.Version,
+			current.Downloaded.Local().Format("2006-01-02 15:04"))
+		current.Warnings = warningMessages(data.warnings)
+	}
+	logWarnings(service.logger, data.warnings)
+	return current, nil
+}
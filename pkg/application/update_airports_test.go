@@ -0,0 +1,137 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestUpdateService returns a service with mock dependencies, whose updater downloads the files to "staging".
+func newTestUpdateService() (*UpdateAirportsService, *mocks.AirportDataUpdater, *mocks.AirportDataLoader,
+	*mocks.FlightRepository) {
+	mockLogger := &mocks.Logger{}
+	allowLogging(mockLogger)
+	mockUpdater := &mocks.AirportDataUpdater{}
+	mockLoader := newMockLoader(errors.New("airports.csv line 3: wrong number of fields"))
+	mockRepository := &mocks.FlightRepository{}
+	service := NewUpdateAirportsService(mockLogger, mockUpdater, mockLoader, mockRepository)
+
+	dataset := &domain.AirportDataset{Source: "https://example.com/", Checksum: "abc"}
+	mockUpdater.On("Download", mock.Anything, AirportDataDir, airportDataFiles).Return(dataset, "staging", nil)
+	mockUpdater.On("Discard", "staging").Return(nil)
+	mockLoader.On("LoadCountries", filepath.Join("staging", CountriesFile)).Return(dummyCountries, nil)
+	mockLoader.On("LoadRegions", filepath.Join("staging", RegionsFile)).Return(dummyRegions, nil)
+	return service, mockUpdater, mockLoader, mockRepository
+}
+
+// TestUpdateAirports_HappyPath tests the downloaded files are installed, and their dataset recorded.
+func TestUpdateAirports_HappyPath(t *testing.T) {
+	service, mockUpdater, mockLoader, mockRepository := newTestUpdateService()
+	mockLoader.On("LoadAirports", filepath.Join("staging", AirportsFile), dummyCountries, dummyRegions).
+		Return(dummyAirports, nil)
+	mockUpdater.On("Install", AirportDataDir, "staging", airportDataFiles).Return(true, nil)
+	mockRepository.On("ImportAirports", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		assert.ElementsMatch(t, []domain.Airport{airport1, airport2}, args.Get(0), "Wrong airports")
+		args.Get(1).(*domain.AirportDataset).Version = 3
+	})
+
+	dataset, err := service.UpdateAirports(context.Background())
+	assert.Nil(t, err, "Expected no error")
+	expected := &domain.AirportDataset{Version: 3, Source: "https://example.com/", Checksum: "abc", Countries: 2,
+		Regions: 4, Airports: 2, Warnings: []string{"airports.csv line 3: wrong number of fields"}}
+	assert.Equal(t, expected, dataset, "Wrong dataset")
+	mockUpdater.AssertExpectations(t)
+}
+
+// TestUpdateAirports_Invalid tests downloaded files that fail to load aren't installed.
+func TestUpdateAirports_Invalid(t *testing.T) {
+	service, mockUpdater, mockLoader, mockRepository := newTestUpdateService()
+	mockLoader.On("LoadAirports", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("airports.csv line 3: Missing name"))
+
+	_, err := service.UpdateAirports(context.Background())
+	assert.EqualError(t, err, "Downloaded airport data is invalid, so was not installed: airports.csv line 3: "+
+		"Missing name", "Wrong error")
+	mockUpdater.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+	mockUpdater.AssertCalled(t, "Discard", "staging")
+	mockRepository.AssertNotCalled(t, "ImportAirports", mock.Anything, mock.Anything)
+}
+
+// TestUpdateAirports_NoAirports tests downloaded files without any airports aren't installed.
+func TestUpdateAirports_NoAirports(t *testing.T) {
+	service, mockUpdater, mockLoader, _ := newTestUpdateService()
+	mockLoader.On("LoadAirports", mock.Anything, mock.Anything, mock.Anything).
+		Return(map[string]domain.Airport{}, nil)
+
+	_, err := service.UpdateAirports(context.Background())
+	assert.EqualError(t, err, "Downloaded airport data has no airports with IATA codes, so was not installed",
+		"Wrong error")
+	mockUpdater.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestUpdateAirports_ImportFails tests the installed files are rolled back if their airports can't be imported.
+func TestUpdateAirports_ImportFails(t *testing.T) {
+	service, mockUpdater, mockLoader, mockRepository := newTestUpdateService()
+	mockLoader.On("LoadAirports", mock.Anything, mock.Anything, mock.Anything).Return(dummyAirports, nil)
+	mockUpdater.On("Install", AirportDataDir, "staging", airportDataFiles).Return(true, nil)
+	mockUpdater.On("Rollback", AirportDataDir, airportDataFiles).Return(nil)
+	mockRepository.On("ImportAirports", mock.Anything, mock.Anything).Return(errors.New("disk full"))
+
+	_, err := service.UpdateAirports(context.Background())
+	assert.EqualError(t, err, "disk full", "Wrong error")
+	mockUpdater.AssertExpectations(t)
+}
+
+// TestUpdateAirports_FirstImportFails tests the installed files are removed if their airports can't be imported, and
+// there were no files before to roll back to.
+func TestUpdateAirports_FirstImportFails(t *testing.T) {
+	service, mockUpdater, mockLoader, mockRepository := newTestUpdateService()
+	mockLoader.On("LoadAirports", mock.Anything, mock.Anything, mock.Anything).Return(dummyAirports, nil)
+	mockUpdater.On("Install", AirportDataDir, "staging", airportDataFiles).Return(false, nil)
+	mockUpdater.On("Remove", AirportDataDir, airportDataFiles).Return(nil)
+	mockRepository.On("ImportAirports", mock.Anything, mock.Anything).Return(errors.New("disk full"))
+
+	_, err := service.UpdateAirports(context.Background())
+	assert.EqualError(t, err, "disk full", "Wrong error")
+	mockUpdater.AssertExpectations(t)
+	mockUpdater.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything)
+}
+
+// TestRollbackAirports tests the previous airports are imported, and the record of the rolled back dataset deleted.
+func TestRollbackAirports(t *testing.T) {
+	service, mockUpdater, mockLoader, mockRepository := newTestUpdateService()
+	previous := &domain.AirportDataset{Version: 1, Downloaded: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)}
+	latest := &domain.AirportDataset{Version: 2}
+	mockUpdater.On("Rollback", AirportDataDir, airportDataFiles).Return(nil)
+	mockLoader.On("LoadCountries", filepath.Join(AirportDataDir, CountriesFile)).Return(dummyCountries, nil)
+	mockLoader.On("LoadRegions", filepath.Join(AirportDataDir, RegionsFile)).Return(dummyRegions, nil)
+	mockLoader.On("LoadAirports", filepath.Join(AirportDataDir, AirportsFile), dummyCountries, dummyRegions).
+		Return(dummyAirports, nil)
+	mockRepository.On("ImportAirports", mock.Anything, (*domain.AirportDataset)(nil)).Return(nil)
+	mockRepository.On("ReadLatestAirportDataset").Return(latest, nil).Once()
+	mockRepository.On("DeleteAirportDataset", int64(2)).Return(nil)
+	mockRepository.On("ReadLatestAirportDataset").Return(previous, nil).Once()
+
+	dataset, err := service.RollbackAirports()
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, previous, dataset, "Wrong dataset")
+	mockRepository.AssertExpectations(t)
+}
+
+// TestRollbackAirports_NoPrevious tests nothing is deleted if there's no previous version.
+func TestRollbackAirports_NoPrevious(t *testing.T) {
+	service, mockUpdater, _, mockRepository := newTestUpdateService()
+	mockUpdater.On("Rollback", AirportDataDir, airportDataFiles).
+		Return(errors.New("No previous airport data files in data/airports to roll back to"))
+
+	_, err := service.RollbackAirports()
+	assert.EqualError(t, err, "No previous airport data files in data/airports to roll back to", "Wrong error")
+	mockRepository.AssertNotCalled(t, "DeleteAirportDataset", mock.Anything)
+}
@@ -0,0 +1,225 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// AirportMatch is an airport found by SearchAirports, with how well it matches the search text.
+type AirportMatch struct {
+	Airport Airport
+	Score   int // from 100 (the airport's IATA code) down to 1
+}
+
+// Scores of matching search text against an airport.
+const (
+	iataCodeScore    = 100
+	icaoCodeScore    = 95
+	maxTextScore     = 90 // every word the same as a word of the airport's name or municipality
+	countryWeight    = 60 // percentage of the score of matching a word of the name or municipality
+	sameWordScore    = 100
+	wordPrefixScore  = 80
+	oneTypoScore     = 70
+	typoPenaltyScore = 20 // for each further typo
+)
+
+// SearchAirports returns the airports that best match free text such as "heathrow", "los angeles" or "LAX", best
+// first, up to the limit (if not zero). The text is matched against each airport's IATA and ICAO codes, name,
+// municipality and country, ignoring case and diacritics (so "sao paulo" matches "São Paulo"), and allowing typos in
+// longer words. Every word of the text must match a word of the airport, or the start of one. Equally good matches
+// are ranked by whether the airport has scheduled services, then its size.
+func SearchAirports(airports []Airport, text string, limit int) []AirportMatch {
+	words := searchWords(text)
+	matches := []AirportMatch{}
+	if len(words) == 0 {
+		return matches
+	}
+
+	for _, airport := range airports {
+		score := matchAirport(airport, words)
+		if score > 0 {
+			matches = append(matches, AirportMatch{airport, score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		first, second := matches[i], matches[j]
+		if first.Score != second.Score {
+			return first.Score > second.Score
+		} else if first.Airport.ScheduledService != second.Airport.ScheduledService {
+			return first.Airport.ScheduledService
+		} else if airportTypeRank(first.Airport.Type) != airportTypeRank(second.Airport.Type) {
+			return airportTypeRank(first.Airport.Type) < airportTypeRank(second.Airport.Type)
+		} else if first.Airport.Name != second.Airport.Name {
+			return first.Airport.Name < second.Airport.Name
+		}
+		return first.Airport.IataCode < second.Airport.IataCode
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// matchAirport returns how well the words of the search text match the airport, or 0 if any word doesn't match.
+func matchAirport(airport Airport, words []string) int {
+	if len(words) == 1 && words[0] == searchWord(airport.IataCode) {
+		return iataCodeScore
+	} else if len(words) == 1 && airport.IcaoCode != "" && words[0] == searchWord(airport.IcaoCode) {
+		return icaoCodeScore
+	}
+
+	fields := []struct {
+		words  []string
+		weight int // percentage
+	}{
+		{searchWords(airport.Name), 100},
+		{searchWords(airport.Municipality), 100},
+		{searchWords(airport.Country), countryWeight},
+	}
+
+	total := 0
+	for _, word := range words {
+		best := 0
+		for _, field := range fields {
+			for _, candidate := range field.words {
+				score := matchWord(word, candidate) * field.weight / 100
+				if score > best {
+					best = score
+				}
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total * maxTextScore / (sameWordScore * len(words))
+}
+
+// matchWord returns how well a word of the search text matches a word of an airport: best if they are the same, then
+// if the word is the start of it, then if the word has one or two typos (as allowed by its length); otherwise 0.
+func matchWord(word string, candidate string) int {
+	if word == candidate {
+		return sameWordScore
+	} else if len(word) >= 2 && strings.HasPrefix(candidate, word) {
+		return wordPrefixScore
+	}
+
+	allowed := typosAllowed(word)
+	wordRunes, candidateRunes := []rune(word), []rune(candidate)
+	if allowed == 0 || len(wordRunes)-len(candidateRunes) > allowed || len(candidateRunes)-len(wordRunes) > allowed {
+		return 0 // too many typos, without working out how many
+	}
+	typos := editDistance(wordRunes, candidateRunes)
+	if typos > allowed {
+		return 0
+	}
+	return oneTypoScore - typoPenaltyScore*(typos-1)
+}
+
+// typosAllowed returns how many typos a word of the search text can have: none in short words, which would match too
+// many others.
+func typosAllowed(word string) int {
+	switch length := len([]rune(word)); {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance returns how many single character insertions, deletions, substitutions or swaps of adjacent characters
+// change one word into the other (the optimal string alignment distance).
+func editDistance(first []rune, second []rune) int {
+	distances := make([][]int, len(first)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(second)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+			distance := minInt(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && first[i-1] == second[j-2] && first[i-2] == second[j-1] {
+				distance = minInt(distance, distances[i-2][j-2]+1)
+			}
+			distances[i][j] = distance
+		}
+	}
+	return distances[len(first)][len(second)]
+}
+
+// minInt returns the smallest of the values.
+func minInt(first int, others ...int) int {
+	for _, value := range others {
+		if value < first {
+			first = value
+		}
+	}
+	return first
+}
+
+// airportTypeRank orders airport types largest first, then any others.
+func airportTypeRank(airportType AirportType) int {
+	switch airportType {
+	case LargeAirport:
+		return 0
+	case MediumAirport:
+		return 1
+	case SmallAirport:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// searchWords returns the words of the text in lower case without diacritics, splitting on anything other than
+// letters and digits, e.g. "São Paulo/Guarulhos" gives "sao", "paulo" and "guarulhos".
+func searchWords(text string) []string {
+	return strings.FieldsFunc(searchWord(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchWord returns the text in lower case, with the diacritics of Latin letters removed.
+func searchWord(text string) string {
+	var folded strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if replacement, exists := foldedLetters[r]; exists {
+			folded.WriteString(replacement)
+		} else {
+			folded.WriteRune(r)
+		}
+	}
+	return folded.String()
+}
+
+// foldedLetters maps lower case Latin letters with diacritics (and ligatures) to the plain letters they are written
+// as in English.
+var foldedLetters = func() map[rune]string {
+	letters := map[string]string{
+		"àáâãäåāăą": "a", "çćĉċč": "c", "ďđ": "d", "èéêëēĕėęě": "e", "ĝğġģ": "g", "ĥħ": "h",
+		"ìíîïĩīĭįı": "i", "ĵ": "j", "ķ": "k", "ĺļľŀł": "l", "ñńņňŉ": "n", "òóôõöøōŏő": "o", "ŕŗř": "r",
+		"śŝşšș": "s", "ţťŧț": "t", "ùúûüũūŭůűų": "u", "ŵ": "w", "ýÿŷ": "y", "źżž": "z",
+		"ß": "ss", "æ": "ae", "œ": "oe", "þ": "th", "ð": "d",
+	}
+	folded := make(map[rune]string)
+	for accented, plain := range letters {
+		for _, r := range accented {
+			folded[r] = plain
+		}
+	}
+	return folded
+}()
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var searchAirports = []Airport{
+	{Name: "London Heathrow Airport", IataCode: "LHR", IcaoCode: "EGLL", Municipality: "London",
+		Country: "United Kingdom", Type: LargeAirport, ScheduledService: true},
+	{Name: "London Gatwick Airport", IataCode: "LGW", IcaoCode: "EGKK", Municipality: "London",
+		Country: "United Kingdom", Type: LargeAirport, ScheduledService: true},
+	{Name: "RAF Northolt", IataCode: "NHT", IcaoCode: "EGWU", Municipality: "London", Country: "United Kingdom",
+		Type: MediumAirport},
+	{Name: "Los Angeles International Airport", IataCode: "LAX", IcaoCode: "KLAX", Municipality: "Los Angeles",
+		Country: "United States", Type: LargeAirport, ScheduledService: true},
+	{Name: "Guarulhos - Governador André Franco Montoro International Airport", IataCode: "GRU",
+		IcaoCode: "SBGR", Municipality: "São Paulo", Country: "Brazil", Type: LargeAirport, ScheduledService: true},
+	{Name: "Zürich Airport", IataCode: "ZRH", IcaoCode: "LSZH", Municipality: "Zurich", Country: "Switzerland",
+		Type: LargeAirport, ScheduledService: true},
+}
+
+// TestSearchAirports tests the codes of the best matches of search text, best first.
+func TestSearchAirports(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected []string
+	}{
+		{"LAX", []string{"LAX"}},
+		{"lhr", []string{"LHR"}},
+		{"egkk", []string{"LGW"}},
+		{"heathrow", []string{"LHR"}},
+		{"Heatrow", []string{"LHR"}},              // a missing letter
+		{"heathorw", []string{"LHR"}},             // swapped letters
+		{"los angeles", []string{"LAX"}},          // both name and municipality
+		{"los angelos", []string{"LAX"}},          // a typo
+		{"sao paulo", []string{"GRU"}},            // municipality without diacritics
+		{"andré", []string{"GRU"}},                // name with diacritics
+		{"zurich", []string{"ZRH"}},               // name without diacritics
+		{"ZÜRICH", []string{"ZRH"}},               // municipality with diacritics
+		{"london", []string{"LGW", "LHR", "NHT"}}, // scheduled and larger airports first
+		{"lon", []string{"LGW", "LHR", "NHT"}},    // the start of a word
+		{"london heathrow", []string{"LHR"}},
+		{"gatwick united kingdom", []string{"LGW"}},
+		{"brazil", []string{"GRU"}},
+		{"lax airport", []string{}}, // codes only match on their own
+		{"heathrow paris", []string{}},
+		{"xyz", []string{}},
+		{"  ", []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.text, func(t *testing.T) {
+			codes := []string{}
+			for _, match := range SearchAirports(searchAirports, testCase.text, 0) {
+				codes = append(codes, match.Airport.IataCode)
+			}
+			assert.Equal(t, testCase.expected, codes, "Wrong airports")
+		})
+	}
+}
+
+// TestSearchAirports_Ranking tests codes rank above names, exact words above the start of words, and both above typos.
+func TestSearchAirports_Ranking(t *testing.T) {
+	airports := []Airport{
+		{Name: "Lax Lake", IataCode: "AAA"},
+		{Name: "Laxford", IataCode: "BBB"},
+		{Name: "Somewhere", IataCode: "LAX"},
+	}
+	matches := SearchAirports(airports, "lax", 0)
+	assert.Equal(t, []AirportMatch{{airports[2], 100}, {airports[0], 90}, {airports[1], 72}}, matches,
+		"Wrong matches")
+
+	airports = []Airport{
+		{Name: "Newark", IataCode: "AAA"},
+		{Name: "Newquay", IataCode: "BBB"},
+		{Name: "Newqay", IataCode: "CCC"},
+	}
+	matches = SearchAirports(airports, "newquay", 2)
+	assert.Equal(t, []AirportMatch{{airports[1], 90}, {airports[2], 63}}, matches, "Wrong matches")
+}
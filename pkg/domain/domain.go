@@ -1,15 +1,38 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
 
 // Airport includes details of each airport.
 type Airport struct {
-	Name     string
-	IataCode string
-	Country  string
-	Region   string
+	Name             string
+	IataCode         string
+	Country          string
+	Region           string
+	IcaoCode         string      // optional, the GPS code, which is the ICAO code of most airports
+	Type             AirportType // optional, e.g. large_airport
+	Latitude         float64     // in decimal degrees, north is positive
+	Longitude        float64     // in decimal degrees, east is positive
+	Municipality     string      // optional, the town or city served
+	ScheduledService bool        // whether airlines fly scheduled services from the airport
 }
 
+// AirportType classifies airports by size, as per ourairports.com. Airports can also be a heliport, seaplane_base,
+// balloonport or closed.
+type AirportType string
+
+// Types of airport, largest first.
+const (
+	LargeAirport  AirportType = "large_airport"
+	MediumAirport AirportType = "medium_airport"
+	SmallAirport  AirportType = "small_airport"
+)
+
 // AirportMapFilter filters a map of airports, returning an array of values that pass the filter function.
 func AirportMapFilter(airports map[string]Airport, f func(Airport) bool) []Airport {
 	filteredValues := make([]Airport, 0)
@@ -30,17 +53,378 @@ func AirportMapValues(airports map[string]Airport) []Airport {
 	return values
 }
 
+// NewAirportMap returns a map of the airports, keyed by IATA code.
+func NewAirportMap(airports []Airport) map[string]Airport {
+	values := make(map[string]Airport)
+	for _, airport := range airports {
+		values[airport.IataCode] = airport
+	}
+	return values
+}
+
+// AirportFilter selects airports by country and region, e.g. all airports in England excluding those whose names
+// start with "RAF ".
+type AirportFilter struct {
+	Country       string // optional, matches every country if not set
+	Region        string // optional, matches every region if not set
+	ExcludePrefix string // optional, excludes airports whose names start with this
+}
+
+// Matches returns whether the airport passes the filter.
+func (filter *AirportFilter) Matches(airport Airport) bool {
+	if filter.Country != "" && airport.Country != filter.Country {
+		return false
+	}
+	if filter.Region != "" && airport.Region != filter.Region {
+		return false
+	}
+	return filter.ExcludePrefix == "" || !strings.HasPrefix(airport.Name, filter.ExcludePrefix)
+}
+
+// AirportDataset records a version of the airport data files, as downloaded from ourairports.com or a mirror of it.
+type AirportDataset struct {
+	Version    int64  // increases with each update
+	Source     string // the base URL the files were downloaded from
+	Downloaded time.Time
+	Published  time.Time // when the files were last modified, zero if not known
+	Checksum   string    // SHA-256 of the contents of every file, in hex
+	Countries  int
+	Regions    int
+	Airports   int      // with IATA codes
+	Warnings   []string // problems with the files ignored in lenient mode, when loaded (not saved)
+}
+
+// TripType indicates which journeys are searched for.
+type TripType string
+
+const (
+	// ReturnTrip searches for outbound and inbound journeys between the same airports
+	ReturnTrip TripType = "return"
+
+	// OneWayTrip searches for an outbound journey only
+	OneWayTrip TripType = "oneway"
+
+	// OpenJawTrip searches for an outbound journey, and an inbound journey home from a different airport
+	OpenJawTrip TripType = "openjaw"
+)
+
 // Arguments encapsulates all quote criteria and supporting info needed.
 type Arguments struct {
-	Origin          string // IATA airport code
-	Destination     string // IATA airport code
-	Adults          int    // adults are over 16
-	Children        int    // children are 1-16
-	Infants         int    // infants are 0-12 months
-	OutboundDate    string // must be YYYY-MM-DD
-	HolidayDuration int    // in nights
-	APIHost         string // from your rapidapi account
-	APIKey          string // from your rapidapi account
+	Origin             string         // IATA airport code
+	Origins            []string       // optional, IATA airport codes to also search from
+	OriginFilter       *AirportFilter // optional, to also search from every airport that matches
+	Destination        string         // IATA airport code
+	Destinations       []string       // optional, IATA airport codes to also search to
+	DestinationFilter  *AirportFilter // optional, to also search to every airport that matches
+	TripType           TripType       // optional, return (the default), oneway or openjaw
+	ReturnOrigin       string         // IATA airport code, open-jaw trips only, to fly home from
+	Adults             int            // adults are over 16
+	Children           int            // children are 1-16
+	Infants            int            // infants are 0-12 months
+	OutboundDate       string         // must be YYYY-MM-DD
+	LatestOutboundDate string         // optional, YYYY-MM-DD, to also search every outbound date up to this
+	HolidayDuration    int            // in nights, ignored by one-way trips
+	MaxHolidayDuration int            // optional, in nights, to also search every duration up to this
+	CabinClass         string         // optional, economy (the default), premiumeconomy, business or first
+	Currency           string         // optional, ISO 4217 code to price in, e.g. GBP (the default), USD or EUR
+	Country            string         // optional, ISO 3166 code of the market to search, e.g. GB (the default)
+	Locale             string         // optional, of the results, e.g. en-GB (the default)
+	GroupPricing       *bool          // optional, price for all passengers (the default), or false for 1 adult
+	APIHost            string         // from your rapidapi account
+	APIKey             string         // from your rapidapi account
+}
+
+// Defaults of the optional Arguments fields.
+const (
+	DefaultCabinClass   = "economy"
+	DefaultCurrency     = "GBP"
+	DefaultCountry      = "GB"
+	DefaultLocale       = "en-GB"
+	DefaultGroupPricing = true
+)
+
+// CabinClasses lists every valid cabin class.
+var CabinClasses = []string{"economy", "premiumeconomy", "business", "first"}
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	localePattern   = regexp.MustCompile(`^[a-z]{2}-[A-Z]{2}$`)
+)
+
+// WithDefaults returns a copy of the arguments, with every optional field that isn't set given its default value.
+func (arguments *Arguments) WithDefaults() *Arguments {
+	result := *arguments
+	if result.CabinClass == "" {
+		result.CabinClass = DefaultCabinClass
+	}
+	if result.Currency == "" {
+		result.Currency = DefaultCurrency
+	}
+	if result.Country == "" {
+		result.Country = DefaultCountry
+	}
+	if result.Locale == "" {
+		result.Locale = DefaultLocale
+	}
+	if result.GroupPricing == nil {
+		groupPricing := DefaultGroupPricing
+		result.GroupPricing = &groupPricing
+	}
+	return &result
+}
+
+// Validate checks the trip type, cabin class, currency, country and locale are valid. Optional fields must already
+// have their defaults set.
+func (arguments *Arguments) Validate() error {
+	err := arguments.ValidateTrip()
+	if err != nil {
+		return err
+	}
+
+	validCabinClass := false
+	for _, cabinClass := range CabinClasses {
+		validCabinClass = validCabinClass || arguments.CabinClass == cabinClass
+	}
+	if !validCabinClass {
+		return fmt.Errorf("Unknown cabin class %s, expected one of %s", arguments.CabinClass,
+			strings.Join(CabinClasses, ", "))
+	}
+	if !currencyPattern.MatchString(arguments.Currency) {
+		return fmt.Errorf("Invalid currency %s, expected an ISO 4217 code such as GBP", arguments.Currency)
+	}
+	if !countryPattern.MatchString(arguments.Country) {
+		return fmt.Errorf("Invalid country %s, expected an ISO 3166 code such as GB", arguments.Country)
+	}
+	if !localePattern.MatchString(arguments.Locale) {
+		return fmt.Errorf("Invalid locale %s, expected a code such as en-GB", arguments.Locale)
+	}
+	if arguments.GroupPricing == nil {
+		return fmt.Errorf("Group pricing not set")
+	}
+	return nil
+}
+
+// Trip returns the trip type, defaulting to a return trip if not set.
+func (arguments *Arguments) Trip() TripType {
+	if arguments.TripType == "" {
+		return ReturnTrip
+	}
+	return arguments.TripType
+}
+
+// Nights returns the holiday duration, which is always zero for one-way trips.
+func (arguments *Arguments) Nights() int {
+	if arguments.Trip() == OneWayTrip {
+		return 0
+	}
+	return arguments.HolidayDuration
+}
+
+// ValidateTrip checks the trip type is known, and the return origin is set only for open-jaw trips.
+func (arguments *Arguments) ValidateTrip() error {
+	switch arguments.Trip() {
+	case ReturnTrip, OneWayTrip:
+		if arguments.ReturnOrigin != "" {
+			return fmt.Errorf("Return origin %s is only valid for open-jaw trips", arguments.ReturnOrigin)
+		}
+	case OpenJawTrip:
+		if arguments.ReturnOrigin == "" {
+			return fmt.Errorf("Open-jaw trips need a return origin")
+		}
+	default:
+		return fmt.Errorf("Unknown trip type %s", arguments.TripType)
+	}
+	return nil
+}
+
+// InboundDate returns the date of the inbound journey, i.e. HolidayDuration nights after OutboundDate.
+func (arguments *Arguments) InboundDate() (string, error) {
+	outboundDate, err := time.Parse(DateFormat, arguments.OutboundDate)
+	if err != nil {
+		return "", err
+	}
+	return outboundDate.AddDate(0, 0, arguments.HolidayDuration).Format(DateFormat), nil
+}
+
+// OpenJawLegs returns the arguments of the two one-way searches that make up an open-jaw trip, i.e. from the origin
+// to the destination, then from the return origin back to the origin once the holiday is over.
+func (arguments *Arguments) OpenJawLegs() (*Arguments, *Arguments, error) {
+	inboundDate, err := arguments.InboundDate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outbound := *arguments
+	outbound.TripType = OneWayTrip
+	outbound.ReturnOrigin = ""
+	outbound.HolidayDuration = 0
+
+	inbound := outbound
+	inbound.Origin = arguments.ReturnOrigin
+	inbound.Destination = arguments.Origin
+	inbound.OutboundDate = inboundDate
+	return &outbound, &inbound, nil
+}
+
+// DateFormat is the format of all dates within Arguments, i.e. YYYY-MM-DD.
+const DateFormat = "2006-01-02"
+
+// OutboundDates returns every outbound date to search, from OutboundDate to LatestOutboundDate (if set) inclusive.
+func (arguments *Arguments) OutboundDates() ([]string, error) {
+	first, err := time.Parse(DateFormat, arguments.OutboundDate)
+	if err != nil {
+		return nil, err
+	}
+	if arguments.LatestOutboundDate == "" {
+		return []string{arguments.OutboundDate}, nil
+	}
+
+	last, err := time.Parse(DateFormat, arguments.LatestOutboundDate)
+	if err != nil {
+		return nil, err
+	}
+	if last.Before(first) {
+		return nil, fmt.Errorf("Latest outbound date %s is before outbound date %s",
+			arguments.LatestOutboundDate, arguments.OutboundDate)
+	}
+
+	dates := []string{}
+	for date := first; !date.After(last); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date.Format(DateFormat))
+	}
+	return dates, nil
+}
+
+// HolidayDurations returns every holiday duration to search, from HolidayDuration to MaxHolidayDuration (if set)
+// inclusive. One-way trips have a single duration of zero.
+func (arguments *Arguments) HolidayDurations() ([]int, error) {
+	if arguments.Trip() == OneWayTrip {
+		return []int{0}, nil
+	}
+	if arguments.MaxHolidayDuration == 0 {
+		return []int{arguments.HolidayDuration}, nil
+	}
+	if arguments.MaxHolidayDuration < arguments.HolidayDuration {
+		return nil, fmt.Errorf("Max holiday duration %d is less than holiday duration %d",
+			arguments.MaxHolidayDuration, arguments.HolidayDuration)
+	}
+
+	durations := []int{}
+	for duration := arguments.HolidayDuration; duration <= arguments.MaxHolidayDuration; duration++ {
+		durations = append(durations, duration)
+	}
+	return durations, nil
+}
+
+// ExpandDates returns arguments for each individual search within the date and duration ranges, i.e. each with a
+// single outbound date and holiday duration.
+func (arguments *Arguments) ExpandDates() ([]*Arguments, error) {
+	dates, err := arguments.OutboundDates()
+	if err != nil {
+		return nil, err
+	}
+	durations, err := arguments.HolidayDurations()
+	if err != nil {
+		return nil, err
+	}
+
+	searches := []*Arguments{}
+	for _, date := range dates {
+		for _, duration := range durations {
+			search := *arguments
+			search.OutboundDate = date
+			search.LatestOutboundDate = ""
+			search.HolidayDuration = duration
+			search.MaxHolidayDuration = 0
+			searches = append(searches, &search)
+		}
+	}
+	return searches, nil
+}
+
+// OriginAirports returns every airport to search from, i.e. Origin, Origins and those matching OriginFilter, sorted by
+// IATA code. Returns an error if any code is unknown, or there are no airports.
+func (arguments *Arguments) OriginAirports(airports map[string]Airport) ([]Airport, error) {
+	return selectAirports("Origin", arguments.Origin, arguments.Origins, arguments.OriginFilter, airports)
+}
+
+// DestinationAirports returns every airport to search to, i.e. Destination, Destinations and those matching
+// DestinationFilter, sorted by IATA code. Returns an error if any code is unknown, or there are no airports.
+func (arguments *Arguments) DestinationAirports(airports map[string]Airport) ([]Airport, error) {
+	return selectAirports("Destination", arguments.Destination, arguments.Destinations, arguments.DestinationFilter,
+		airports)
+}
+
+// selectAirports returns the airports with the code or codes, or that match the filter (if set), without duplicates.
+func selectAirports(kind string, code string, codes []string, filter *AirportFilter,
+	airports map[string]Airport) ([]Airport, error) {
+	selected := map[string]Airport{}
+	for _, code := range append([]string{code}, codes...) {
+		if code == "" {
+			continue
+		}
+		airport, exists := airports[code]
+		if !exists {
+			return nil, fmt.Errorf("%s airport code %s unknown", kind, code)
+		}
+		selected[code] = airport
+	}
+
+	if filter != nil {
+		for _, airport := range AirportMapFilter(airports, filter.Matches) {
+			selected[airport.IataCode] = airport
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("No %s airports", strings.ToLower(kind))
+	}
+
+	values := AirportMapValues(selected)
+	sort.Slice(values, func(i, j int) bool { return values[i].IataCode < values[j].IataCode })
+	return values, nil
+}
+
+// ExpandAirports returns arguments for each individual search between the origin and destination airports, i.e. each
+// with a single origin and destination. Routes from an airport to itself are skipped.
+func (arguments *Arguments) ExpandAirports(airports map[string]Airport) ([]*Arguments, error) {
+	origins, err := arguments.OriginAirports(airports)
+	if err != nil {
+		return nil, err
+	}
+	destinations, err := arguments.DestinationAirports(airports)
+	if err != nil {
+		return nil, err
+	}
+
+	if arguments.Trip() == OpenJawTrip {
+		_, exists := airports[arguments.ReturnOrigin]
+		if !exists {
+			return nil, fmt.Errorf("Return origin airport code %s unknown", arguments.ReturnOrigin)
+		}
+	}
+
+	searches := []*Arguments{}
+	for _, origin := range origins {
+		for _, destination := range destinations {
+			if origin.IataCode == destination.IataCode {
+				continue
+			}
+			search := *arguments
+			search.Origin = origin.IataCode
+			search.Origins = nil
+			search.OriginFilter = nil
+			search.Destination = destination.IataCode
+			search.Destinations = nil
+			search.DestinationFilter = nil
+			searches = append(searches, &search)
+		}
+	}
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("No routes between different origin and destination airports")
+	}
+	return searches, nil
 }
 
 // FlightNumber details the carrier number for a flight (can be several).
@@ -83,16 +467,303 @@ type Journey struct {
 }
 
 // Itinerary details a holiday travel quote for outbound and inbound journeys.
+// One-way trips have no inbound journey.
 type Itinerary struct {
-	SupplierName    string
-	SupplierType    string
-	Amount          int // monetary type??
-	OutboundJourney *Journey
-	InboundJourney  *Journey
+	Origin             string // IATA code of the airport searched from
+	Destination        string // IATA code of the airport searched to
+	Provider           string // name of the source of the quote, e.g. "skyscanner"
+	SupplierName       string
+	SupplierType       string
+	Price              Money
+	DeeplinkURL        string              // booking page of the supplier, empty if not known or booked separately
+	QuoteAge           time.Duration       // how old the supplier's price was when quoted
+	BookingDetailsLink *BookingDetailsLink // nil if not known, or once the search session has ended (not saved)
+	OutboundJourney    *Journey
+	InboundJourney     *Journey // nil for one-way trips
+}
+
+// BookingDetailsLink is the request that fetches the booking options of an itinerary, while its search session lasts.
+type BookingDetailsLink struct {
+	URI    string // relative to the API, e.g. "/apiservices/pricing/v1.0/abc/booking"
+	Body   string // form parameters, e.g. "OutboundLegId=leg1&InboundLegId=leg2"
+	Method string // e.g. "PUT"
+}
+
+// BookingDetails lists the ways to book an itinerary.
+type BookingDetails struct {
+	Options  []*BookingOption // cheapest first
+	Complete bool             // whether every supplier has confirmed its price
+}
+
+// BookingOption is a way to book an itinerary, either all with one supplier or split across several.
+type BookingOption struct {
+	Items []*BookingItem
+}
+
+// BookingItem is part (or all) of an itinerary, booked with a single supplier.
+type BookingItem struct {
+	SupplierName string
+	SupplierType string
+	Price        Money
+	DeeplinkURL  string // booking page of the supplier
+	Status       string // e.g. "Current", "Pending", "Estimated" or "Failed"
+}
+
+// Price returns the total price of every item of the booking option, or an error if the items have different
+// currencies.
+func (option *BookingOption) Price() (Money, error) {
+	total := Money{}
+	for index, item := range option.Items {
+		if index == 0 {
+			total = item.Price
+			continue
+		}
+		var err error
+		total, err = total.Add(item.Price)
+		if err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
 }
 
 // Quote details several itineraries
 type Quote struct {
-	Itineraries []*Itinerary
-	Complete    bool
+	ID             int64           // repository id, zero until saved
+	Currency       string          // ISO 4217 code of every itinerary price
+	CurrencyFormat *CurrencyFormat // how to format prices, nil if not known
+	Itineraries    []*Itinerary
+	Complete       bool
+	Agents         int // agents asked to quote, while searching (not saved)
+	AgentsPending  int // agents yet to quote, while searching (not saved)
+}
+
+// Cheapest returns the cheapest itinerary of the quote, or nil if it has no itineraries.
+func (quote *Quote) Cheapest() *Itinerary {
+	var cheapest *Itinerary
+	for _, itinerary := range quote.Itineraries {
+		if cheapest == nil || itinerary.Price.Amount < cheapest.Price.Amount {
+			cheapest = itinerary
+		}
+	}
+	return cheapest
+}
+
+// MergeQuotes combines the itineraries of several quotes into one, ranked cheapest first. The quotes must all have the
+// same currency, otherwise an error is returned. The merged quote is complete only if every quote is, and has no
+// repository id.
+func MergeQuotes(quotes []*Quote) (*Quote, error) {
+	merged := Quote{Itineraries: []*Itinerary{}, Complete: true}
+	for index, quote := range quotes {
+		if index > 0 && quote.Currency != merged.Currency {
+			return nil, fmt.Errorf("Can't merge quotes priced in %s and %s", merged.Currency, quote.Currency)
+		}
+		merged.Currency = quote.Currency
+		if quote.CurrencyFormat != nil {
+			merged.CurrencyFormat = quote.CurrencyFormat
+		}
+		merged.Itineraries = append(merged.Itineraries, quote.Itineraries...)
+		merged.Complete = merged.Complete && quote.Complete
+	}
+	sort.SliceStable(merged.Itineraries, func(i, j int) bool {
+		return merged.Itineraries[i].Price.Amount < merged.Itineraries[j].Price.Amount
+	})
+	return &merged, nil
+}
+
+// DeduplicateItineraries returns the itineraries without duplicates, e.g. the same itinerary found by several
+// providers. Itineraries are duplicates if they have the same supplier and flights, and only the cheapest is kept, in
+// the place of the first.
+func DeduplicateItineraries(itineraries []*Itinerary) []*Itinerary {
+	unique := []*Itinerary{}
+	indexes := make(map[string]int) // maps key to index in unique
+	for _, itinerary := range itineraries {
+		key := itinerary.key()
+		index, exists := indexes[key]
+		if !exists {
+			indexes[key] = len(unique)
+			unique = append(unique, itinerary)
+		} else if itinerary.Price.Amount < unique[index].Price.Amount {
+			unique[index] = itinerary
+		}
+	}
+	return unique
+}
+
+// key identifies the supplier and flights of the itinerary, regardless of its price or provider.
+func (itinerary *Itinerary) key() string {
+	parts := []string{strings.ToLower(itinerary.SupplierName)}
+	for _, journey := range []*Journey{itinerary.OutboundJourney, itinerary.InboundJourney} {
+		if journey == nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("journey%d", journey.Direction))
+		for _, flight := range journey.Flights {
+			number := ""
+			if flight.FlightNumber != nil {
+				number = flight.FlightNumber.CarrierCode + flight.FlightNumber.FlightNumber
+			}
+			parts = append(parts, number+"@"+flight.StartTime.Format(time.RFC3339))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// CombineOpenJaw combines the quotes of the two one-way searches of an open-jaw trip, pairing each outbound itinerary
+// with the cheapest inbound itinerary, ranked cheapest first. The combined quote is complete only if both quotes are.
+// Each combined itinerary is booked as two one-way trips, so has no single deeplink. The quotes must have the same
+// currency, otherwise an error is returned.
+func CombineOpenJaw(outbound *Quote, inbound *Quote) (*Quote, error) {
+	combined := Quote{Currency: outbound.Currency, CurrencyFormat: outbound.CurrencyFormat,
+		Itineraries: []*Itinerary{}, Complete: outbound.Complete && inbound.Complete}
+	cheapestInbound := inbound.Cheapest()
+	if cheapestInbound == nil {
+		return &combined, nil
+	}
+
+	inboundJourney := *cheapestInbound.OutboundJourney
+	inboundJourney.Direction = Inbound
+	for _, itinerary := range outbound.Itineraries {
+		price, err := itinerary.Price.Add(cheapestInbound.Price)
+		if err != nil {
+			return nil, err
+		}
+		combined.Itineraries = append(combined.Itineraries, &Itinerary{
+			Origin:          itinerary.Origin,
+			Destination:     itinerary.Destination,
+			Provider:        itinerary.Provider,
+			SupplierName:    combineSuppliers(itinerary.SupplierName, cheapestInbound.SupplierName),
+			SupplierType:    combineSuppliers(itinerary.SupplierType, cheapestInbound.SupplierType),
+			Price:           price,
+			QuoteAge:        maxDuration(itinerary.QuoteAge, cheapestInbound.QuoteAge),
+			OutboundJourney: itinerary.OutboundJourney,
+			InboundJourney:  &inboundJourney,
+		})
+	}
+	sort.SliceStable(combined.Itineraries, func(i, j int) bool {
+		return combined.Itineraries[i].Price.Amount < combined.Itineraries[j].Price.Amount
+	})
+	return &combined, nil
+}
+
+// maxDuration returns the longer of two durations.
+func maxDuration(first time.Duration, second time.Duration) time.Duration {
+	if first > second {
+		return first
+	}
+	return second
+}
+
+// combineSuppliers returns the outbound supplier, followed by the inbound supplier if different.
+func combineSuppliers(outbound string, inbound string) string {
+	if outbound == inbound {
+		return outbound
+	}
+	return outbound + " + " + inbound
+}
+
+// PriceRange returns the cheapest, median and most expensive itinerary amounts of the quote, in minor units, or zeros
+// if it has no itineraries.
+func (quote *Quote) PriceRange() (int, int, int) {
+	if len(quote.Itineraries) == 0 {
+		return 0, 0, 0
+	}
+
+	amounts := make([]int, len(quote.Itineraries))
+	for index, itinerary := range quote.Itineraries {
+		amounts[index] = itinerary.Price.Amount
+	}
+	sort.Ints(amounts)
+
+	middle := len(amounts) / 2
+	median := amounts[middle]
+	if len(amounts)%2 == 0 {
+		median = (amounts[middle-1] + amounts[middle]) / 2
+	}
+	return amounts[0], median, amounts[len(amounts)-1]
+}
+
+// SearchRun records the prices found by a single search, so prices can be tracked over time.
+type SearchRun struct {
+	ID            int64
+	QuoteID       int64
+	Arguments     Arguments // API details are not recorded
+	Created       time.Time
+	Cheapest      int // in minor units of the arguments currency
+	Median        int
+	MostExpensive int
+}
+
+// NewSearchRun creates a search run summarising the prices of a saved quote.
+func NewSearchRun(arguments *Arguments, quote *Quote, created time.Time) *SearchRun {
+	cheapest, median, mostExpensive := quote.PriceRange()
+	run := SearchRun{
+		QuoteID:       quote.ID,
+		Arguments:     *arguments,
+		Created:       created,
+		Cheapest:      cheapest,
+		Median:        median,
+		MostExpensive: mostExpensive,
+	}
+	run.Arguments.TripType = arguments.Trip()
+	run.Arguments.HolidayDuration = arguments.Nights()
+	run.Arguments.APIHost = ""
+	run.Arguments.APIKey = ""
+	return &run
+}
+
+// SearchSession records a search that was started but whose quotes are not yet complete, so it can be resumed if
+// interrupted, rather than starting a new search.
+type SearchSession struct {
+	SessionKey string
+	Arguments  Arguments // API details are not recorded
+	Created    time.Time
+}
+
+// NewSearchSession creates a search session for the arguments of a search that has just started.
+func NewSearchSession(sessionKey string, arguments *Arguments, created time.Time) *SearchSession {
+	session := SearchSession{SessionKey: sessionKey, Arguments: *arguments, Created: created}
+	session.Arguments.TripType = arguments.Trip()
+	session.Arguments.HolidayDuration = arguments.Nights()
+	session.Arguments.APIHost = ""
+	session.Arguments.APIKey = ""
+	return &session
+}
+
+// FareCalendar details the cheapest itinerary for each combination of outbound date and holiday duration.
+type FareCalendar struct {
+	TripType         TripType
+	Currency         string          // ISO 4217 code of every itinerary price
+	CurrencyFormat   *CurrencyFormat // how to format prices, nil if not known
+	OutboundDates    []string
+	HolidayDurations []int
+	Cheapest         [][]*Itinerary // indexed by outbound date then holiday duration, nil if no flights found
+}
+
+// NewFareCalendar creates an empty fare calendar for the specified trip type, currency, outbound dates and holiday
+// durations.
+func NewFareCalendar(tripType TripType, currency string, outboundDates []string, holidayDurations []int) *FareCalendar {
+	cheapest := make([][]*Itinerary, len(outboundDates))
+	for index := range cheapest {
+		cheapest[index] = make([]*Itinerary, len(holidayDurations))
+	}
+	return &FareCalendar{TripType: tripType, Currency: currency, OutboundDates: outboundDates,
+		HolidayDurations: holidayDurations, Cheapest: cheapest}
+}
+
+// Add records the itinerary for its outbound date and holiday duration, if it is cheaper than any already recorded.
+func (calendar *FareCalendar) Add(outboundDate string, holidayDuration int, itinerary *Itinerary) {
+	if itinerary == nil {
+		return
+	}
+	for dateIndex, date := range calendar.OutboundDates {
+		for durationIndex, duration := range calendar.HolidayDurations {
+			if date == outboundDate && duration == holidayDuration {
+				current := calendar.Cheapest[dateIndex][durationIndex]
+				if current == nil || itinerary.Price.Amount < current.Price.Amount {
+					calendar.Cheapest[dateIndex][durationIndex] = itinerary
+				}
+			}
+		}
+	}
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -47,7 +48,7 @@ func TestAirportMapFilter_NotMatching(t *testing.T) {
 func TestAirportMapValues_Populated(t *testing.T) {
 	expected := []Airport{airport1, airport2}
 	result := AirportMapValues(dummyAirports)
-	assert.EqualValues(t, result, expected, "Wrong result")
+	assert.ElementsMatch(t, result, expected, "Wrong result") // map iteration order is random
 }
 
 // TestAirportMapValues_Empty tests when entries are not populated.
@@ -57,3 +58,367 @@ func TestAirportMapValues_Empty(t *testing.T) {
 	result := AirportMapValues(input)
 	assert.EqualValues(t, result, expected, "Wrong result")
 }
+
+// TestNewAirportMap tests airports are keyed by their IATA codes.
+func TestNewAirportMap(t *testing.T) {
+	assert.Equal(t, dummyAirports, NewAirportMap([]Airport{airport1, airport2}), "Wrong result")
+}
+
+// newQuote returns a quote with an itinerary for each amount.
+func newQuote(amounts ...int) *Quote {
+	quote := Quote{ID: 12, Currency: "GBP", Itineraries: []*Itinerary{}, Complete: true}
+	for _, amount := range amounts {
+		quote.Itineraries = append(quote.Itineraries, &Itinerary{Price: Money{Amount: amount, Currency: "GBP"}})
+	}
+	return &quote
+}
+
+// TestPriceRange tests price ranges of odd, even and empty numbers of itineraries.
+func TestPriceRange(t *testing.T) {
+	testCases := []struct {
+		quote         *Quote
+		cheapest      int
+		median        int
+		mostExpensive int
+	}{
+		{newQuote(300, 100, 200), 100, 200, 300},
+		{newQuote(400, 100, 300, 200), 100, 250, 400},
+		{newQuote(500), 500, 500, 500},
+		{newQuote(), 0, 0, 0},
+	}
+
+	for _, testCase := range testCases {
+		cheapest, median, mostExpensive := testCase.quote.PriceRange()
+		assert.Equal(t, testCase.cheapest, cheapest, "Wrong cheapest")
+		assert.Equal(t, testCase.median, median, "Wrong median")
+		assert.Equal(t, testCase.mostExpensive, mostExpensive, "Wrong most expensive")
+	}
+}
+
+// TestNewSearchRun tests a search run is populated from the quote, without the API details, and with the default
+// trip type.
+func TestNewSearchRun(t *testing.T) {
+	arguments := Arguments{Origin: "LHR", Destination: "LAX", APIHost: "host", APIKey: "key"}
+	created := time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)
+
+	expected := &SearchRun{
+		QuoteID:       12,
+		Arguments:     Arguments{Origin: "LHR", Destination: "LAX", TripType: ReturnTrip},
+		Created:       created,
+		Cheapest:      100,
+		Median:        200,
+		MostExpensive: 300,
+	}
+	assert.Equal(t, expected, NewSearchRun(&arguments, newQuote(100, 200, 300), created), "Wrong search run")
+	assert.Equal(t, "key", arguments.APIKey, "Arguments should not be changed")
+}
+
+// TestNewSearchSession tests a search session records the arguments without the API details, and with the nights of
+// a one-way trip ignored.
+func TestNewSearchSession(t *testing.T) {
+	arguments := Arguments{Origin: "LHR", Destination: "LAX", TripType: OneWayTrip, HolidayDuration: 7,
+		APIHost: "host", APIKey: "key"}
+	created := time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)
+
+	expected := &SearchSession{
+		SessionKey: "abc",
+		Arguments:  Arguments{Origin: "LHR", Destination: "LAX", TripType: OneWayTrip},
+		Created:    created,
+	}
+	assert.Equal(t, expected, NewSearchSession("abc", &arguments, created), "Wrong search session")
+	assert.Equal(t, "key", arguments.APIKey, "Arguments should not be changed")
+}
+
+// TestExpandDates_Ranges tests expanding ranges of outbound dates and holiday durations, across a month end.
+func TestExpandDates_Ranges(t *testing.T) {
+	arguments := Arguments{Origin: "LHR", OutboundDate: "2019-10-31", LatestOutboundDate: "2019-11-01",
+		HolidayDuration: 7, MaxHolidayDuration: 8}
+
+	expected := []*Arguments{
+		&Arguments{Origin: "LHR", OutboundDate: "2019-10-31", HolidayDuration: 7},
+		&Arguments{Origin: "LHR", OutboundDate: "2019-10-31", HolidayDuration: 8},
+		&Arguments{Origin: "LHR", OutboundDate: "2019-11-01", HolidayDuration: 7},
+		&Arguments{Origin: "LHR", OutboundDate: "2019-11-01", HolidayDuration: 8},
+	}
+	result, err := arguments.ExpandDates()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, expected, result, "Wrong result")
+}
+
+// TestExpandDates_Single tests expanding when no ranges are set.
+func TestExpandDates_Single(t *testing.T) {
+	arguments := Arguments{Origin: "LHR", OutboundDate: "2019-11-01", HolidayDuration: 7}
+
+	result, err := arguments.ExpandDates()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []*Arguments{&arguments}, result, "Wrong result")
+}
+
+// TestExpandDates_Invalid tests expanding invalid ranges.
+func TestExpandDates_Invalid(t *testing.T) {
+	testCases := []Arguments{
+		Arguments{OutboundDate: "01/11/2019"},
+		Arguments{OutboundDate: "2019-11-01", LatestOutboundDate: "wibble"},
+		Arguments{OutboundDate: "2019-11-01", LatestOutboundDate: "2019-10-31"},
+		Arguments{OutboundDate: "2019-11-01", HolidayDuration: 7, MaxHolidayDuration: 6},
+	}
+
+	for _, testCase := range testCases {
+		result, err := testCase.ExpandDates()
+		assert.Nil(t, result, "No result expected")
+		assert.Error(t, err, "Error expected")
+	}
+}
+
+// TestCheapest tests finding the cheapest itinerary.
+func TestCheapest(t *testing.T) {
+	quote := newQuote(300, 100, 200)
+	assert.Equal(t, quote.Itineraries[1], quote.Cheapest(), "Wrong itinerary")
+	assert.Nil(t, newQuote().Cheapest(), "No itinerary expected")
+}
+
+// TestFareCalendar_Add tests only the cheapest itinerary is kept for each cell.
+func TestFareCalendar_Add(t *testing.T) {
+	calendar := NewFareCalendar(ReturnTrip, "GBP", []string{"2019-11-01", "2019-11-02"}, []int{7, 8})
+	cheap := &Itinerary{Price: Money{Amount: 100, Currency: "GBP"}}
+	expensive := &Itinerary{Price: Money{Amount: 200, Currency: "GBP"}}
+
+	calendar.Add("2019-11-02", 7, expensive)
+	calendar.Add("2019-11-02", 7, cheap)
+	calendar.Add("2019-11-02", 7, expensive)
+	calendar.Add("2019-11-01", 8, nil)
+	calendar.Add("2019-11-03", 7, cheap) // not in the calendar
+
+	expected := [][]*Itinerary{
+		[]*Itinerary{nil, nil},
+		[]*Itinerary{cheap, nil},
+	}
+	assert.Equal(t, expected, calendar.Cheapest, "Wrong calendar")
+}
+
+// TestAirportFilter_Matches tests matching airports by country, region and name prefix.
+func TestAirportFilter_Matches(t *testing.T) {
+	raf := Airport{Name: "RAF Base", Region: "Region1", Country: "Country1", IataCode: "Code3"}
+
+	filter := AirportFilter{Country: "Country1", Region: "Region1", ExcludePrefix: "RAF "}
+	assert.True(t, filter.Matches(airport1), "Expected a match")
+	assert.False(t, filter.Matches(airport2), "Expected no match")
+	assert.False(t, filter.Matches(raf), "Expected no match")
+
+	anyRegion := AirportFilter{Country: "Country2"}
+	assert.True(t, anyRegion.Matches(airport2), "Expected a match")
+}
+
+// TestExpandAirports tests expanding codes and a filter into every route, skipping an airport to itself.
+func TestExpandAirports(t *testing.T) {
+	airport3 := Airport{Name: "Airport3", Region: "Region2", Country: "Country2", IataCode: "Code3"}
+	airports := map[string]Airport{airport1.IataCode: airport1, airport2.IataCode: airport2, "Code3": airport3}
+
+	arguments := Arguments{Origin: "Code1", Origins: []string{"Code2", "Code1"},
+		DestinationFilter: &AirportFilter{Country: "Country2"}, Adults: 2}
+	searches, err := arguments.ExpandAirports(airports)
+	assert.Nil(t, err, "Expected no error")
+
+	routes := []string{}
+	for _, search := range searches {
+		assert.Nil(t, search.Origins, "Expected no origins")
+		assert.Nil(t, search.DestinationFilter, "Expected no filter")
+		assert.Equal(t, 2, search.Adults, "Wrong adults")
+		routes = append(routes, search.Origin+"-"+search.Destination)
+	}
+	assert.Equal(t, []string{"Code1-Code2", "Code1-Code3", "Code2-Code3"}, routes, "Wrong routes")
+}
+
+// TestExpandAirports_Invalid tests expanding unknown or missing airports.
+func TestExpandAirports_Invalid(t *testing.T) {
+	arguments := Arguments{Origin: "Code1", Destinations: []string{"XYZ"}}
+	_, err := arguments.ExpandAirports(dummyAirports)
+	assert.EqualError(t, err, "Destination airport code XYZ unknown")
+
+	arguments = Arguments{Destination: "Code1", OriginFilter: &AirportFilter{Country: "Nowhere"}}
+	_, err = arguments.ExpandAirports(dummyAirports)
+	assert.EqualError(t, err, "No origin airports")
+
+	arguments = Arguments{Origin: "Code1", Destination: "Code1"}
+	_, err = arguments.ExpandAirports(dummyAirports)
+	assert.Error(t, err, "Expected an error")
+}
+
+// TestMergeQuotes tests merging quotes ranks every itinerary cheapest first.
+func TestMergeQuotes(t *testing.T) {
+	first := newQuote(300, 100)
+	second := newQuote(200)
+	second.Complete = false
+
+	merged, err := MergeQuotes([]*Quote{first, second})
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, []*Itinerary{first.Itineraries[1], second.Itineraries[0], first.Itineraries[0]},
+		merged.Itineraries, "Wrong itineraries")
+	assert.False(t, merged.Complete, "Expected incomplete")
+	assert.Zero(t, merged.ID, "Expected no id")
+
+	dollars := newQuote(100)
+	dollars.Currency = "USD"
+	_, err = MergeQuotes([]*Quote{first, dollars})
+	assert.EqualError(t, err, "Can't merge quotes priced in GBP and USD")
+}
+
+// TestValidateTrip tests validating each trip type.
+func TestValidateTrip(t *testing.T) {
+	testCases := []struct {
+		arguments Arguments
+		message   string
+	}{
+		{Arguments{}, ""},
+		{Arguments{TripType: OneWayTrip}, ""},
+		{Arguments{TripType: OpenJawTrip, ReturnOrigin: "Code2"}, ""},
+		{Arguments{TripType: OpenJawTrip}, "Open-jaw trips need a return origin"},
+		{Arguments{ReturnOrigin: "Code2"}, "Return origin Code2 is only valid for open-jaw trips"},
+		{Arguments{TripType: "circular"}, "Unknown trip type circular"},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.arguments.ValidateTrip()
+		if testCase.message == "" {
+			assert.Nil(t, err, "Expected no error")
+		} else {
+			assert.EqualError(t, err, testCase.message)
+		}
+	}
+}
+
+// TestOpenJawLegs tests splitting an open-jaw trip into two one-way trips.
+func TestOpenJawLegs(t *testing.T) {
+	arguments := Arguments{Origin: "LHR", Destination: "LAX", TripType: OpenJawTrip, ReturnOrigin: "SFO", Adults: 2,
+		OutboundDate: "2019-10-30", HolidayDuration: 7}
+
+	outbound, inbound, err := arguments.OpenJawLegs()
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, &Arguments{Origin: "LHR", Destination: "LAX", TripType: OneWayTrip, Adults: 2,
+		OutboundDate: "2019-10-30"}, outbound, "Wrong outbound")
+	assert.Equal(t, &Arguments{Origin: "SFO", Destination: "LHR", TripType: OneWayTrip, Adults: 2,
+		OutboundDate: "2019-11-06"}, inbound, "Wrong inbound")
+}
+
+// TestCombineOpenJaw tests pairing each outbound itinerary with the cheapest inbound itinerary.
+func TestCombineOpenJaw(t *testing.T) {
+	leg1 := &Journey{ID: "leg1", Direction: Outbound}
+	leg2 := &Journey{ID: "leg2", Direction: Outbound}
+	leg3 := &Journey{ID: "leg3", Direction: Outbound}
+	outbound := &Quote{Itineraries: []*Itinerary{
+		&Itinerary{Origin: "LHR", Destination: "LAX", SupplierName: "Agent1", Price: Money{Amount: 300, Currency: "GBP"}, OutboundJourney: leg1},
+		&Itinerary{Origin: "LHR", Destination: "LAX", SupplierName: "Agent2", Price: Money{Amount: 200, Currency: "GBP"}, OutboundJourney: leg2},
+	}, Complete: true}
+	inbound := &Quote{Itineraries: []*Itinerary{
+		&Itinerary{SupplierName: "Agent2", Price: Money{Amount: 50, Currency: "GBP"}, OutboundJourney: leg3,
+			DeeplinkURL: "http://test.com/book", QuoteAge: 20 * time.Minute},
+	}, Complete: true}
+
+	combined, err := CombineOpenJaw(outbound, inbound)
+	assert.Nil(t, err, "Expected no error")
+	assert.True(t, combined.Complete, "Expected complete")
+	assert.Len(t, combined.Itineraries, 2, "Wrong number of itineraries")
+	assert.Equal(t, "Agent2", combined.Itineraries[0].SupplierName, "Wrong supplier")
+	assert.Equal(t, 250, combined.Itineraries[0].Price.Amount, "Wrong amount")
+	assert.Equal(t, "Agent1 + Agent2", combined.Itineraries[1].SupplierName, "Wrong supplier")
+	assert.Equal(t, 350, combined.Itineraries[1].Price.Amount, "Wrong amount")
+	assert.Equal(t, "LHR", combined.Itineraries[1].Origin, "Wrong origin")
+	assert.Equal(t, "leg3", combined.Itineraries[1].InboundJourney.ID, "Wrong inbound journey")
+	assert.Equal(t, Inbound, combined.Itineraries[1].InboundJourney.Direction, "Wrong direction")
+	assert.Equal(t, Outbound, leg3.Direction, "Inbound quote should not be changed")
+	assert.Empty(t, combined.Itineraries[1].DeeplinkURL, "Expected no deeplink, booked separately")
+	assert.Equal(t, 20*time.Minute, combined.Itineraries[1].QuoteAge, "Wrong quote age")
+
+	empty, err := CombineOpenJaw(outbound, &Quote{Complete: true})
+	assert.Nil(t, err, "Expected no error")
+	assert.Empty(t, empty.Itineraries, "Expected no itineraries")
+
+	inbound.Itineraries[0].Price.Currency = "USD"
+	_, err = CombineOpenJaw(outbound, inbound)
+	assert.EqualError(t, err, "Can't add 0.50 USD to 3.00 GBP, which has a different currency")
+}
+
+// TestDeduplicateItineraries tests only the cheapest of itineraries with the same supplier and flights is kept.
+func TestDeduplicateItineraries(t *testing.T) {
+	flight := func(number string) *Journey {
+		return &Journey{Flights: []*Flight{&Flight{FlightNumber: &FlightNumber{CarrierCode: "BA", FlightNumber: number},
+			StartTime: time.Date(2019, time.November, 1, 8, 30, 0, 0, time.UTC)}}}
+	}
+	first := &Itinerary{SupplierName: "Agent1", Price: Money{Amount: 300}, OutboundJourney: flight("1"),
+		Provider: "skyscanner"}
+	other := &Itinerary{SupplierName: "Agent1", Price: Money{Amount: 100}, OutboundJourney: flight("2")}
+	cheaper := &Itinerary{SupplierName: "AGENT1", Price: Money{Amount: 250}, OutboundJourney: flight("1"),
+		Provider: "farefile"}
+	dearer := &Itinerary{SupplierName: "Agent1", Price: Money{Amount: 400}, OutboundJourney: flight("1")}
+	withInbound := &Itinerary{SupplierName: "Agent1", Price: Money{Amount: 500}, OutboundJourney: flight("1"),
+		InboundJourney: &Journey{Direction: Inbound}}
+
+	assert.Equal(t, []*Itinerary{cheaper, other, withInbound},
+		DeduplicateItineraries([]*Itinerary{first, other, cheaper, dearer, withInbound}), "Wrong itineraries")
+}
+
+// TestBookingOption_Price tests totalling the prices of every item of a booking option.
+func TestBookingOption_Price(t *testing.T) {
+	option := &BookingOption{Items: []*BookingItem{
+		&BookingItem{SupplierName: "Agent1", Price: Money{Amount: 300, Currency: "GBP"}},
+		&BookingItem{SupplierName: "Agent2", Price: Money{Amount: 50, Currency: "GBP"}},
+	}}
+	price, err := option.Price()
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, Money{Amount: 350, Currency: "GBP"}, price, "Wrong price")
+	price, err = (&BookingOption{}).Price()
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, Money{}, price, "Wrong price")
+
+	option.Items[1].Price.Currency = "USD"
+	_, err = option.Price()
+	assert.EqualError(t, err, "Can't add 0.50 USD to 3.00 GBP, which has a different currency")
+}
+
+// TestWithDefaults tests only optional fields that aren't set are given their defaults.
+func TestWithDefaults(t *testing.T) {
+	groupPricing := false
+	arguments := Arguments{Origin: "LHR", Currency: "USD", GroupPricing: &groupPricing}
+
+	result := arguments.WithDefaults()
+	assert.Equal(t, "LHR", result.Origin, "Wrong origin")
+	assert.Equal(t, DefaultCabinClass, result.CabinClass, "Wrong cabin class")
+	assert.Equal(t, "USD", result.Currency, "Wrong currency")
+	assert.Equal(t, DefaultCountry, result.Country, "Wrong country")
+	assert.Equal(t, DefaultLocale, result.Locale, "Wrong locale")
+	assert.False(t, *result.GroupPricing, "Wrong group pricing")
+	assert.Equal(t, "", arguments.CabinClass, "Arguments should not be changed")
+
+	assert.True(t, *(&Arguments{}).WithDefaults().GroupPricing, "Wrong default group pricing")
+}
+
+// TestValidate tests validating the cabin class, currency, country and locale.
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		change  func(arguments *Arguments)
+		message string
+	}{
+		{func(arguments *Arguments) {}, ""},
+		{func(arguments *Arguments) { arguments.CabinClass = "business" }, ""},
+		{func(arguments *Arguments) { arguments.CabinClass = "steerage" },
+			"Unknown cabin class steerage, expected one of economy, premiumeconomy, business, first"},
+		{func(arguments *Arguments) { arguments.Currency = "usd" },
+			"Invalid currency usd, expected an ISO 4217 code such as GBP"},
+		{func(arguments *Arguments) { arguments.Country = "GBR" },
+			"Invalid country GBR, expected an ISO 3166 code such as GB"},
+		{func(arguments *Arguments) { arguments.Locale = "english" },
+			"Invalid locale english, expected a code such as en-GB"},
+		{func(arguments *Arguments) { arguments.TripType = "circular" }, "Unknown trip type circular"},
+	}
+
+	for _, testCase := range testCases {
+		arguments := (&Arguments{}).WithDefaults()
+		testCase.change(arguments)
+		err := arguments.Validate()
+		if testCase.message == "" {
+			assert.Nil(t, err, "Expected no error")
+		} else {
+			assert.EqualError(t, err, testCase.message)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "errors"
+
+// Errors returned when a flight quote API rejects a request, wrapped with more detail, so callers can react to each
+// kind of failure with errors.Is.
+var (
+	// ErrRateLimited is returned when too many requests have been made, and retrying hasn't helped.
+	ErrRateLimited = errors.New("Too many requests")
+
+	// ErrUnauthorized is returned when the API key is missing, invalid or not subscribed, which affects every request.
+	ErrUnauthorized = errors.New("Not authorized, check the API key")
+
+	// ErrSessionExpired is returned when polling a search session that no longer exists, so a new search is needed.
+	ErrSessionExpired = errors.New("Search session expired")
+
+	// ErrBadRequest is returned when the search parameters are rejected, so retrying the same search won't help.
+	ErrBadRequest = errors.New("Request rejected as invalid")
+
+	// ErrUnavailable is returned when the API keeps failing with server errors, and retrying hasn't helped.
+	ErrUnavailable = errors.New("Service unavailable")
+)
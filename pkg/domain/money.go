@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Money is an amount of a currency, in its minor units (e.g. pence) to avoid rounding errors.
+type Money struct {
+	Amount   int    // in minor units, e.g. 123456 is £1,234.56
+	Currency string // ISO 4217 code, e.g. "GBP"
+}
+
+// Add returns the sum of two amounts of the same currency, or an error if their currencies differ.
+func (money Money) Add(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return Money{}, fmt.Errorf("Can't add %s to %s, which has a different currency", other, money)
+	}
+	return Money{money.Amount + other.Amount, money.Currency}, nil
+}
+
+// Format returns the amount formatted as specified, or as String if the format is nil or for a different currency.
+func (money Money) Format(format *CurrencyFormat) string {
+	if format == nil || format.Code != money.Currency {
+		return money.String()
+	}
+	return format.Format(money.Amount)
+}
+
+// String returns the amount with its currency code, with the decimal digits of the currency, e.g. "1234.56 GBP" or
+// "1234 JPY".
+func (money Money) String() string {
+	format := CurrencyFormat{Code: money.Currency, DecimalSeparator: ".",
+		DecimalDigits: CurrencyDecimalDigits(money.Currency)}
+	return format.Format(money.Amount) + " " + money.Currency
+}
+
+// CurrencyFormat details how to format amounts of a currency.
+type CurrencyFormat struct {
+	Code                        string // ISO 4217 code, e.g. "GBP"
+	Symbol                      string // e.g. "£"
+	ThousandsSeparator          string // e.g. ","
+	DecimalSeparator            string // e.g. "."
+	SymbolOnLeft                bool
+	SpaceBetweenAmountAndSymbol bool
+	DecimalDigits               int // number of minor unit digits, e.g. 2
+}
+
+// DefaultDecimalDigits is the number of minor unit digits of most currencies.
+const DefaultDecimalDigits = 2
+
+// decimalDigits are the number of minor unit digits of the ISO 4217 currencies that don't have the default.
+var decimalDigits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0,
+	"UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyDecimalDigits returns the number of minor unit digits of the currency, as per ISO 4217, for when its format
+// isn't known, e.g. 0 for "JPY". Unknown currencies have the default.
+func CurrencyDecimalDigits(code string) int {
+	digits, exists := decimalDigits[code]
+	if !exists {
+		return DefaultDecimalDigits
+	}
+	return digits
+}
+
+// Format returns the amount, in minor units, formatted with the symbol and separators, e.g. "£1,234.56" or
+// "1.234,56 €".
+func (format *CurrencyFormat) Format(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	unit := 1
+	for digit := 0; digit < format.DecimalDigits; digit++ {
+		unit *= 10
+	}
+
+	number := groupThousands(amount/unit, format.ThousandsSeparator)
+	if format.DecimalDigits > 0 {
+		number += format.DecimalSeparator + fmt.Sprintf("%0*d", format.DecimalDigits, amount%unit)
+	}
+
+	space := ""
+	if format.SpaceBetweenAmountAndSymbol {
+		space = " "
+	}
+	if format.SymbolOnLeft {
+		return sign + format.Symbol + space + number
+	}
+	return sign + number + space + format.Symbol
+}
+
+// groupThousands returns the whole number with the separator between each group of three digits.
+func groupThousands(number int, separator string) string {
+	digits := fmt.Sprintf("%d", number)
+	groups := []string{}
+	for len(digits) > 3 {
+		groups = append([]string{digits[len(digits)-3:]}, groups...)
+		digits = digits[:len(digits)-3]
+	}
+	groups = append([]string{digits}, groups...)
+	return strings.Join(groups, separator)
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCurrencyFormat_Format tests formatting amounts with various symbols, separators and decimal digits.
+func TestCurrencyFormat_Format(t *testing.T) {
+	pound := &CurrencyFormat{Code: "GBP", Symbol: "£", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 2}
+	euro := &CurrencyFormat{Code: "EUR", Symbol: "€", ThousandsSeparator: ".", DecimalSeparator: ",",
+		SpaceBetweenAmountAndSymbol: true, DecimalDigits: 2}
+	yen := &CurrencyFormat{Code: "JPY", Symbol: "¥", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 0}
+
+	testCases := []struct {
+		format   *CurrencyFormat
+		amount   int
+		expected string
+	}{
+		{pound, 123456, "£1,234.56"},
+		{pound, 5, "£0.05"},
+		{pound, -123456, "-£1,234.56"},
+		{pound, 100000000, "£1,000,000.00"},
+		{euro, 123456, "1.234,56 €"},
+		{euro, 99900, "999,00 €"},
+		{yen, 1234567, "¥1,234,567"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.format.Format(testCase.amount), "Wrong format")
+	}
+}
+
+// TestMoney_Format tests formatting money, falling back to the currency code when the format isn't known.
+func TestMoney_Format(t *testing.T) {
+	pound := &CurrencyFormat{Code: "GBP", Symbol: "£", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 2}
+
+	assert.Equal(t, "£12.34", Money{1234, "GBP"}.Format(pound), "Wrong format")
+	assert.Equal(t, "12.34 GBP", Money{1234, "GBP"}.Format(nil), "Wrong format")
+	assert.Equal(t, "12.34 USD", Money{1234, "USD"}.Format(pound), "Wrong format")
+	assert.Equal(t, "-0.05 GBP", Money{-5, "GBP"}.String(), "Wrong format")
+	assert.Equal(t, "1234 JPY", Money{1234, "JPY"}.String(), "Wrong format")
+	assert.Equal(t, "1.234 KWD", Money{1234, "KWD"}.String(), "Wrong format")
+
+	sum, err := Money{1000, "GBP"}.Add(Money{500, "GBP"})
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, Money{1500, "GBP"}, sum, "Wrong sum")
+	_, err = Money{1000, "GBP"}.Add(Money{500, "USD"})
+	assert.EqualError(t, err, "Can't add 5.00 USD to 10.00 GBP, which has a different currency")
+}
@@ -2,120 +2,245 @@ package framework
 
 import (
 	"bufio"
-	"encoding/csv"
-	"io"
+	"errors"
 	"os"
+	"strconv"
+	"sync"
 
 	"github.com/chrisnappin/flightchecker/pkg/domain"
 )
 
 // AirportDataLoaderService handles loading airport data from static CSV files.
 type AirportDataLoaderService struct {
-	logger domain.Logger
+	logger   domain.Logger
+	options  AirportDataOptions
+	mutex    sync.Mutex
+	warnings []*CSVError
 }
 
-// NewAirportDataLoader creates a new instance.
+// AirportDataOptions configures how rows of the airport data with problems are handled.
+type AirportDataOptions struct {
+	// Lenient keeps airports whose country or region code is unknown, naming the country or region by its code, and
+	// skips any other rows with missing or invalid values, or the wrong number of values, recording a warning for
+	// each. Otherwise (strict mode) the first such row fails the load.
+	Lenient bool
+}
+
+// NewAirportDataLoader creates a new instance, in strict mode.
 func NewAirportDataLoader(logger domain.Logger) *AirportDataLoaderService {
-	return &AirportDataLoaderService{logger}
+	return NewAirportDataLoaderWithOptions(logger, AirportDataOptions{})
 }
 
-// LoadCountries returns a map of countries, keyed by country code.
-// The data is read from the specified CSV file.
-func (service *AirportDataLoaderService) LoadCountries(filename string) (map[string]string, error) {
-	const indexCode = 1
-	const indexName = 2
+// NewAirportDataLoaderWithOptions creates a new instance with the options.
+func NewAirportDataLoaderWithOptions(logger domain.Logger, options AirportDataOptions) *AirportDataLoaderService {
+	return &AirportDataLoaderService{logger: logger, options: options}
+}
 
-	csvFile, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// Warnings returns a warning (a *CSVError) for every problem found in lenient mode since the warnings were last
+// cleared, in the order found. Warnings are kept across loads, so cover every file of an import.
+func (service *AirportDataLoaderService) Warnings() []error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	warnings := make([]error, len(service.warnings))
+	for index, warning := range service.warnings {
+		warnings[index] = warning
 	}
-	defer csvFile.Close()
-	reader := csv.NewReader(bufio.NewReader(csvFile))
-	countries := make(map[string]string)
+	return warnings
+}
+
+// ClearWarnings forgets the warnings found so far, e.g. at the start of an import.
+func (service *AirportDataLoaderService) ClearWarnings() {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.warnings = nil
+}
+
+// problem fails the load in strict mode by returning the problem, or in lenient mode logs and records it as a warning
+// then returns nil.
+func (service *AirportDataLoaderService) problem(problem *CSVError) error {
+	if !service.options.Lenient {
+		return problem
+	}
+	service.logger.Warnf("Ignoring %s", problem)
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.warnings = append(service.warnings, problem)
+	return nil
+}
+
+// nextRow reads the next row of the table, returning false once there are none left. A row with the wrong number of
+// values is a problem, so skipped in lenient mode. Rows that aren't valid CSV (e.g. an unterminated quote) always fail
+// the load, since where the next row starts isn't known.
+func (service *AirportDataLoaderService) nextRow(table *csvTable) (bool, error) {
 	for {
-		line, err := reader.Read()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
+		found, err := table.next()
+		var problem *CSVError
+		if found && errors.As(err, &problem) {
+			err = service.problem(problem)
+			if err == nil {
+				continue // skips the row
+			}
 		}
-		countries[line[indexCode]] = line[indexName]
+		return found, err
+	}
+}
+
+// LoadCountries returns a map of countries, keyed by country code.
+// The data is read from the specified CSV file, which has a header row naming its columns, including code and name.
+func (service *AirportDataLoaderService) LoadCountries(filename string) (map[string]string, error) {
+	countries, err := service.loadNames(filename)
+	if err != nil {
+		return nil, err
 	}
 	service.logger.Debugf("Read %d countries", len(countries))
 	return countries, nil
 }
 
 // LoadRegions returns a map of regions, keyed by region code.
-// The data is read from the specified CSV file.
+// The data is read from the specified CSV file, which has a header row naming its columns, including code and name.
 func (service *AirportDataLoaderService) LoadRegions(filename string) (map[string]string, error) {
-	const indexCode = 1
-	const indexName = 3
+	regions, err := service.loadNames(filename)
+	if err != nil {
+		return nil, err
+	}
+	service.logger.Debugf("Read %d regions", len(regions))
+	return regions, nil
+}
 
+// loadNames returns the name column of every row of the CSV file, keyed by the code column.
+func (service *AirportDataLoaderService) loadNames(filename string) (map[string]string, error) {
 	csvFile, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer csvFile.Close()
-	reader := csv.NewReader(bufio.NewReader(csvFile))
-	regions := make(map[string]string)
+	table, err := newCSVTable(bufio.NewReader(csvFile), filename, "code", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
 	for {
-		line, err := reader.Read()
-		if err == io.EOF {
+		found, err := service.nextRow(table)
+		if err != nil {
+			return nil, err
+		} else if !found {
 			break
-		} else if err != nil {
+		}
+
+		code := table.value("code")
+		if code == "" {
+			err = service.problem(table.errorf("code", "Missing code"))
+		} else if table.value("name") == "" {
+			err = service.problem(table.errorf("name", "Missing name of %s", code))
+		} else {
+			names[code] = table.value("name")
+		}
+		if err != nil {
 			return nil, err
 		}
-		regions[line[indexCode]] = line[indexName]
 	}
-	service.logger.Debugf("Read %d regions", len(regions))
-	return regions, nil
+	return names, nil
 }
 
-// LoadAirports returns a slice of Airports, with country and region names populated.
-// The data is read from the specified CSV file.
+// airportFileColumns are the columns of the airports CSV file that are read.
+var airportFileColumns = []string{"type", "name", "latitude_deg", "longitude_deg", "iso_country", "iso_region",
+	"municipality", "scheduled_service", "gps_code", "iata_code"}
+
+// LoadAirports returns a map of major airports (those with IATA codes) keyed by IATA code, with country and region
+// names populated.
+// The data is read from the specified CSV file, which has a header row naming its columns.
 func (service *AirportDataLoaderService) LoadAirports(filename string, countries map[string]string,
 	regions map[string]string) (map[string]domain.Airport, error) {
-	const indexName = 3
-	const indexCountryCode = 8
-	const indexRegionCode = 9
-	const indexIataCode = 13
-
 	csvFile, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer csvFile.Close()
-	reader := csv.NewReader(bufio.NewReader(csvFile))
+	table, err := newCSVTable(bufio.NewReader(csvFile), filename, airportFileColumns...)
+	if err != nil {
+		return nil, err
+	}
+
 	airports := make(map[string]domain.Airport)
 	for {
-		line, err := reader.Read()
-		if err == io.EOF {
-			break
-		} else if err != nil {
+		found, err := service.nextRow(table)
+		if err != nil {
 			return nil, err
+		} else if !found {
+			break
+		}
+		iataCode := table.value("iata_code")
+		if iataCode == "" {
+			continue // only include major airports (assigned IATA codes)
 		}
-		iataCode := line[indexIataCode]
 
-		// only include major airports (assigned IATA codes), miss out header row
-		if len(iataCode) > 0 && iataCode != "iata_code" {
-			countryName, exists := countries[line[indexCountryCode]]
-			if !exists {
-				service.logger.Fatalf("Countries missing name for code %s", line[indexCountryCode])
+		airport, problem := parseAirport(table, iataCode)
+		if problem != nil {
+			err = service.problem(problem)
+			if err != nil {
+				return nil, err
 			}
+			continue // skips the airport
+		}
 
-			regionName, exists := regions[line[indexRegionCode]]
-			if !exists {
-				service.logger.Fatalf("Regions missing name for code %s", line[indexRegionCode])
+		// an unknown country or region keeps the airport, with the country or region named by its code
+		var countryProblem, regionProblem *CSVError
+		airport.Country, countryProblem = lookupName(table, "iso_country", countries, "country", iataCode)
+		airport.Region, regionProblem = lookupName(table, "iso_region", regions, "region", iataCode)
+		for _, problem := range []*CSVError{countryProblem, regionProblem} {
+			if problem == nil {
+				continue
 			}
-
-			airports[iataCode] = domain.Airport{
-				Name:     line[indexName],
-				IataCode: iataCode,
-				Country:  countryName,
-				Region:   regionName,
+			err = service.problem(problem)
+			if err != nil {
+				return nil, err
 			}
 		}
+		airports[iataCode] = airport
 	}
 	service.logger.Debugf("Read %d airports", len(airports))
 	return airports, nil
 }
+
+// parseAirport returns the airport of the current row, without its country and region, or a problem if any of its
+// values are missing or invalid.
+func parseAirport(table *csvTable, iataCode string) (domain.Airport, *CSVError) {
+	airport := domain.Airport{
+		Name:             table.value("name"),
+		IataCode:         iataCode,
+		IcaoCode:         table.value("gps_code"),
+		Type:             domain.AirportType(table.value("type")),
+		Municipality:     table.value("municipality"),
+		ScheduledService: table.value("scheduled_service") == "yes",
+	}
+	if airport.Name == "" {
+		return airport, table.errorf("name", "Missing name of airport %s", iataCode)
+	}
+
+	var err error
+	airport.Latitude, err = strconv.ParseFloat(table.value("latitude_deg"), 64)
+	if err != nil || airport.Latitude < -90 || airport.Latitude > 90 {
+		return airport, table.errorf("latitude_deg", "Invalid latitude %s of airport %s", table.value("latitude_deg"),
+			iataCode)
+	}
+	airport.Longitude, err = strconv.ParseFloat(table.value("longitude_deg"), 64)
+	if err != nil || airport.Longitude < -180 || airport.Longitude > 180 {
+		return airport, table.errorf("longitude_deg", "Invalid longitude %s of airport %s",
+			table.value("longitude_deg"), iataCode)
+	}
+	return airport, nil
+}
+
+// lookupName returns the name of the code in the column of the current row, or the code itself and a problem if it is
+// unknown.
+func lookupName(table *csvTable, column string, names map[string]string, kind string, iataCode string) (string,
+	*CSVError) {
+	code := table.value(column)
+	name, exists := names[code]
+	if !exists {
+		return code, table.errorf(column, "Unknown %s code %s of airport %s", kind, code, iataCode)
+	}
+	return name, nil
+}
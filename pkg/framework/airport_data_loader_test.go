@@ -0,0 +1,193 @@
+package framework
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const airportsCSV = `"id","ident","type","name","latitude_deg","longitude_deg","elevation_ft","continent","iso_country","iso_region","municipality","scheduled_service","gps_code","iata_code","local_code","home_link","wikipedia_link","keywords"
+2434,"EGLL","large_airport","London Heathrow Airport",51.4706,-0.461941,83,"EU","GB","GB-ENG","London","yes","EGLL","LHR",,"http://www.heathrowairport.com/","https://en.wikipedia.org/wiki/Heathrow_Airport","LON, Londres"
+6523,"00A","heliport","Total Rf Heliport",40.07080078125,-74.93360137939453,11,"NA","US","US-PA","Bensalem","no","00A",,"00A",,,
+26385,"EGXC","medium_airport","RAF Coningsby",53.0929985046,-0.166014000773,25,"EU","GB","GB-ENG","Coningsby","no","EGXC","QCY",,,"https://en.wikipedia.org/wiki/RAF_Coningsby",
+`
+
+// writeAirportsFile writes the airports to a temporary CSV file, returning its name and a function to remove it.
+func writeAirportsFile(t *testing.T, airports string) (string, func()) {
+	dir, err := ioutil.TempDir("", "airports")
+	assert.Nil(t, err, "No error expected")
+	filename := filepath.Join(dir, "airports.csv")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(airports), 0644), "No error expected")
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+// newTestLoader returns a loader with a mock logger.
+func newTestLoader() *AirportDataLoaderService {
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+	return NewAirportDataLoader(mockLogger)
+}
+
+// loadTestAirports loads the airports from a temporary CSV file, in United Kingdom and England.
+func loadTestAirports(t *testing.T, airports string) (map[string]domain.Airport, error) {
+	filename, remove := writeAirportsFile(t, airports)
+	defer remove()
+	return newTestLoader().LoadAirports(filename, map[string]string{"GB": "United Kingdom"},
+		map[string]string{"GB-ENG": "England"})
+}
+
+// TestLoadAirports tests loading every detail of the airports with IATA codes.
+func TestLoadAirports(t *testing.T) {
+	airports, err := loadTestAirports(t, airportsCSV)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, map[string]domain.Airport{
+		"LHR": domain.Airport{Name: "London Heathrow Airport", IataCode: "LHR", Country: "United Kingdom",
+			Region: "England", IcaoCode: "EGLL", Type: domain.LargeAirport, Latitude: 51.4706, Longitude: -0.461941,
+			Municipality: "London", ScheduledService: true},
+		"QCY": domain.Airport{Name: "RAF Coningsby", IataCode: "QCY", Country: "United Kingdom", Region: "England",
+			IcaoCode: "EGXC", Type: domain.MediumAirport, Latitude: 53.0929985046, Longitude: -0.166014000773,
+			Municipality: "Coningsby", ScheduledService: false},
+	}, airports, "Wrong airports")
+}
+
+// TestLoadAirports_ColumnOrder tests columns are read by the names in the header row, whatever their order.
+func TestLoadAirports_ColumnOrder(t *testing.T) {
+	airports, err := loadTestAirports(t, `iata_code,name,gps_code,iso_region,iso_country,type,longitude_deg,latitude_deg,scheduled_service,municipality
+LHR,London Heathrow Airport,EGLL,GB-ENG,GB,large_airport,-0.461941,51.4706,yes,London
+`)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, domain.Airport{Name: "London Heathrow Airport", IataCode: "LHR", Country: "United Kingdom",
+		Region: "England", IcaoCode: "EGLL", Type: domain.LargeAirport, Latitude: 51.4706, Longitude: -0.461941,
+		Municipality: "London", ScheduledService: true}, airports["LHR"], "Wrong airport")
+}
+
+// TestLoadAirports_Invalid tests the file, line and column of malformed rows are reported.
+func TestLoadAirports_Invalid(t *testing.T) {
+	const header = "iata_code,name,gps_code,iso_region,iso_country,type,longitude_deg,latitude_deg," +
+		"scheduled_service,municipality\n"
+	testCases := []struct {
+		airports string
+		message  string
+	}{
+		{"", "airports.csv is empty, expected a header row"},
+		{"iata_code,name\n", "airports.csv is missing columns type, latitude_deg, longitude_deg, iso_country"},
+		{header + "LHR,,EGLL,GB-ENG,GB,large_airport,-0.46,51.47,yes,London\n",
+			"airports.csv line 2, column name: Missing name of airport LHR"},
+		{header + "LHR,Heathrow,EGLL,GB-ENG,GB,large_airport,-0.46,north,yes,London\n",
+			"airports.csv line 2, column latitude_deg: Invalid latitude north of airport LHR"},
+		{header + "LHR,\"Heathrow\nAirport\",EGLL,GB-ENG,GB,large_airport,-0.46,51.47,yes,London\n" +
+			"LGW,Gatwick,EGKK,GB-ENG,GB,large_airport,-190,51.14,yes,London\n",
+			"airports.csv line 4, column longitude_deg: Invalid longitude -190 of airport LGW"},
+		{header + "\nLHR,Heathrow,EGLL,GB-ENG,GB,large_airport,-0.46,51.47,yes,London\n\n" +
+			"LGW,Gatwick,EGKK,GB-ENG,GB,large_airport,-190,51.14,yes,London\n",
+			"airports.csv line 5, column longitude_deg: Invalid longitude -190 of airport LGW"},
+		{header + "LHR,\"Heathrow\r\nAirport\",EGLL,GB-ENG,GB,large_airport,-0.46,51.47,yes,London\r\n\r\n" +
+			"LGW,Gatwick,EGKK,GB-ENG,GB,large_airport,-190,51.14,yes,London\r\n",
+			"airports.csv line 5, column longitude_deg: Invalid longitude -190 of airport LGW"},
+		{header + "LHR,Heathrow,EGLL,GB-ENG,GB,large_airport,-0.46,51.47,yes,London\nLGW,Gatwick\n",
+			"airports.csv line 3: wrong number of fields"},
+		{header + "LHR,\"Heathrow,EGLL\n", "airports.csv line 2: extraneous or missing \" in quoted-field"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := loadTestAirports(t, testCase.airports)
+		assert.Error(t, err, "Expected an error for %s", testCase.airports)
+		if err != nil {
+			assert.Contains(t, err.Error(), testCase.message, "Wrong error")
+		}
+	}
+}
+
+// TestLoadCountries tests loading country names by code, without the header row.
+func TestLoadCountries(t *testing.T) {
+	filename, remove := writeAirportsFile(t, `"id","code","name","continent","wikipedia_link","keywords"
+302672,"GB","United Kingdom","EU","https://en.wikipedia.org/wiki/United_Kingdom","Great Britain"
+302755,"US","United States","NA","https://en.wikipedia.org/wiki/United_States","America"
+`)
+	defer remove()
+
+	countries, err := newTestLoader().LoadCountries(filename)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, map[string]string{"GB": "United Kingdom", "US": "United States"}, countries, "Wrong countries")
+}
+
+// unknownCodesCSV has an airport with an unknown country and region, and another with an invalid latitude.
+const unknownCodesCSV = `iata_code,name,gps_code,iso_region,iso_country,type,longitude_deg,latitude_deg,scheduled_service,municipality
+LHR,London Heathrow Airport,EGLL,GB-ENG,GB,large_airport,-0.461941,51.4706,yes,London
+JFK,John F Kennedy International Airport,KJFK,US-NY,US,large_airport,-73.7789,40.6398,yes,New York
+LGW,London Gatwick Airport,EGKK,GB-ENG,GB,large_airport,-0.190278,north,yes,London
+`
+
+// TestLoadAirports_Strict tests an unknown country code fails the load, with where it is.
+func TestLoadAirports_Strict(t *testing.T) {
+	_, err := loadTestAirports(t, unknownCodesCSV)
+
+	var problem *CSVError
+	assert.True(t, errors.As(err, &problem), "Wrong error %s", err)
+	assert.Equal(t, 3, problem.Line, "Wrong line")
+	assert.Equal(t, "iso_country", problem.Column, "Wrong column")
+	assert.Equal(t, "Unknown country code US of airport JFK", problem.Message, "Wrong message")
+}
+
+// TestLoadAirports_Lenient tests an airport with unknown codes is kept, named by its codes, and an airport with an
+// invalid value is skipped, each with a warning.
+func TestLoadAirports_Lenient(t *testing.T) {
+	filename, remove := writeAirportsFile(t, unknownCodesCSV)
+	defer remove()
+
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+	mockLogger.On("Warnf", "Ignoring %s", mock.Anything)
+	loader := NewAirportDataLoaderWithOptions(mockLogger, AirportDataOptions{Lenient: true})
+
+	airports, err := loader.LoadAirports(filename, map[string]string{"GB": "United Kingdom"},
+		map[string]string{"GB-ENG": "England"})
+	assert.Nil(t, err, "No error expected")
+	assert.Len(t, airports, 2, "Wrong number of airports")
+	assert.Equal(t, "US", airports["JFK"].Country, "Wrong country")
+	assert.Equal(t, "US-NY", airports["JFK"].Region, "Wrong region")
+	assert.Equal(t, "United Kingdom", airports["LHR"].Country, "Wrong country")
+
+	warnings := loader.Warnings()
+	assert.Len(t, warnings, 3, "Wrong number of warnings")
+	assert.Equal(t, "iso_country", warnings[0].(*CSVError).Column, "Wrong column")
+	assert.Equal(t, "iso_region", warnings[1].(*CSVError).Column, "Wrong column")
+	assert.Equal(t, 4, warnings[2].(*CSVError).Line, "Wrong line")
+	assert.Equal(t, "latitude_deg", warnings[2].(*CSVError).Column, "Wrong column")
+	mockLogger.AssertNumberOfCalls(t, "Warnf", 3)
+}
+
+// TestLoadAirports_LenientWarnings tests the warnings of every file loaded are kept until cleared, including for a
+// row with the wrong number of values, which is skipped.
+func TestLoadAirports_LenientWarnings(t *testing.T) {
+	countriesFile, removeCountries := writeAirportsFile(t, "code,name\nGB,United Kingdom\nUS\n")
+	defer removeCountries()
+	airportsFile, removeAirports := writeAirportsFile(t, unknownCodesCSV)
+	defer removeAirports()
+
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+	mockLogger.On("Warnf", "Ignoring %s", mock.Anything)
+	loader := NewAirportDataLoaderWithOptions(mockLogger, AirportDataOptions{Lenient: true})
+
+	countries, err := loader.LoadCountries(countriesFile)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, map[string]string{"GB": "United Kingdom"}, countries, "Wrong countries")
+	_, err = loader.LoadAirports(airportsFile, countries, map[string]string{"GB-ENG": "England"})
+	assert.Nil(t, err, "No error expected")
+
+	warnings := loader.Warnings()
+	assert.Len(t, warnings, 4, "Wrong number of warnings")
+	assert.Equal(t, &CSVError{File: countriesFile, Line: 3, Message: "wrong number of fields"}, warnings[0],
+		"Wrong warning")
+
+	loader.ClearWarnings()
+	assert.Empty(t, loader.Warnings(), "Expected no warnings")
+}
@@ -0,0 +1,284 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// DefaultAirportDataURL is where ourairports.com publishes its data files.
+const DefaultAirportDataURL = "https://ourairports.com/data/"
+
+// ChecksumsFile is the optional file, alongside the data files, of the SHA-256 of each as written by sha256sum.
+const ChecksumsFile = "SHA256SUMS"
+
+// previousDir is the directory, within the directory of the data files, that holds the version replaced by the last
+// update.
+const previousDir = "previous"
+
+// AirportDataUpdaterOptions configures where the airport data files are downloaded from.
+type AirportDataUpdaterOptions struct {
+	Client  *http.Client // nil uses a client that can also read file:// URLs
+	BaseURL string       // e.g. DefaultAirportDataURL, or "file:///srv/mirror/" for a local mirror
+}
+
+// AirportDataUpdaterService handles downloading new versions of the airport data files, and replacing the current
+// files with them while keeping the previous version.
+type AirportDataUpdaterService struct {
+	logger  domain.Logger
+	options AirportDataUpdaterOptions
+}
+
+// NewAirportDataUpdater creates a new instance, configured by the options.
+func NewAirportDataUpdater(logger domain.Logger, options AirportDataUpdaterOptions) *AirportDataUpdaterService {
+	if options.Client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+		options.Client = &http.Client{Transport: transport}
+	}
+	if !strings.HasSuffix(options.BaseURL, "/") {
+		options.BaseURL += "/"
+	}
+	return &AirportDataUpdaterService{logger, options}
+}
+
+// Download fetches the files from the base URL into a new staging directory within the directory, returning the
+// dataset they make up (without counts) and the staging directory. Each file must be complete, and if the base URL
+// has a checksums file, must match its checksum. The staging directory is removed if any file fails.
+func (service *AirportDataUpdaterService) Download(ctx context.Context, dir string, filenames []string) (
+	*domain.AirportDataset, string, error) {
+	checksums, err := service.readChecksums(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, "", err
+	}
+	stagingDir, err := ioutil.TempDir(dir, ".download-")
+	if err != nil {
+		return nil, "", err
+	}
+
+	dataset := &domain.AirportDataset{Source: service.options.BaseURL, Downloaded: time.Now()}
+	datasetHash := sha256.New()
+	for _, filename := range filenames {
+		checksum, modified, err := service.download(ctx, filename, filepath.Join(stagingDir, filename), datasetHash)
+		if err == nil && checksums != nil {
+			err = verifyChecksum(filename, checksum, checksums)
+		}
+		if err != nil {
+			os.RemoveAll(stagingDir)
+			return nil, "", err
+		}
+		if modified.After(dataset.Published) {
+			dataset.Published = modified
+		}
+	}
+	dataset.Checksum = hex.EncodeToString(datasetHash.Sum(nil))
+	return dataset, stagingDir, nil
+}
+
+// download fetches the file from the base URL to the path, also writing its contents to the dataset hash. Returns the
+// SHA-256 of the file in hex, and when it was last modified (zero if not known).
+func (service *AirportDataUpdaterService) download(ctx context.Context, filename string, path string,
+	datasetHash hash.Hash) (string, time.Time, error) {
+	response, err := service.get(ctx, filename)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("Failed to download %s%s: %s", service.options.BaseURL, filename,
+			response.Status)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer file.Close()
+
+	fileHash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, fileHash, datasetHash), response.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Failed to download %s%s: %w", service.options.BaseURL, filename, err)
+	}
+	if response.ContentLength >= 0 && size != response.ContentLength {
+		return "", time.Time{}, fmt.Errorf("Download of %s%s is incomplete, got %d of %d bytes",
+			service.options.BaseURL, filename, size, response.ContentLength)
+	}
+	err = file.Close()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	modified, _ := http.ParseTime(response.Header.Get("Last-Modified")) // zero if missing or invalid
+	service.logger.Debugf("Downloaded %s%s, %d bytes", service.options.BaseURL, filename, size)
+	return hex.EncodeToString(fileHash.Sum(nil)), modified, nil
+}
+
+// readChecksums fetches the checksums file from the base URL, returning the SHA-256 of each file in hex keyed by
+// filename, or nil if there isn't a checksums file.
+func (service *AirportDataUpdaterService) readChecksums(ctx context.Context) (map[string]string, error) {
+	response, err := service.get(ctx, ChecksumsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		service.logger.Debugf("No %s at %s, so files are only checked for being complete", ChecksumsFile,
+			service.options.BaseURL)
+		return nil, nil
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to download %s%s: %s", service.options.BaseURL, ChecksumsFile,
+			response.Status)
+	}
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(response.Body)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		} else if len(fields) != 2 {
+			return nil, fmt.Errorf("%s line %d: expected a checksum then a filename", ChecksumsFile, line)
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0]) // * marks binary mode
+	}
+	return checksums, scanner.Err()
+}
+
+// verifyChecksum checks the file has the checksum listed for it.
+func verifyChecksum(filename string, checksum string, checksums map[string]string) error {
+	expected, exists := checksums[filename]
+	if !exists {
+		return fmt.Errorf("%s has no checksum for %s", ChecksumsFile, filename)
+	} else if checksum != expected {
+		return fmt.Errorf("Checksum of %s is %s, expected %s", filename, checksum, expected)
+	}
+	return nil
+}
+
+// get requests the file from the base URL.
+func (service *AirportDataUpdaterService) get(ctx context.Context, filename string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, service.options.BaseURL+filename, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", DefaultUserAgent)
+	return service.options.Client.Do(request)
+}
+
+// Install replaces the files in the directory with those in the staging directory, moving the current files (if any)
+// to the previous directory within it, in place of any older version there. If any file can't be replaced, the
+// current files are restored. Returns whether there were current files, so kept as the previous version.
+func (service *AirportDataUpdaterService) Install(dir string, stagingDir string, filenames []string) (bool, error) {
+	backupDir, err := ioutil.TempDir(dir, ".previous-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(backupDir)
+
+	current, err := moveFiles(dir, backupDir, filenames)
+	if err == nil {
+		var installed []string
+		installed, err = moveFiles(stagingDir, dir, filenames)
+		if err != nil {
+			for _, filename := range installed {
+				os.Remove(filepath.Join(dir, filename))
+			}
+		}
+	}
+	if err != nil {
+		_, restoreErr := moveFiles(backupDir, dir, current)
+		if restoreErr != nil {
+			service.logger.Errorf("Failed to restore the airport data files in %s: %s", dir, restoreErr)
+		}
+		return false, fmt.Errorf("Failed to install the airport data files in %s: %w", dir, err)
+	}
+
+	if len(current) > 0 {
+		err = os.RemoveAll(filepath.Join(dir, previousDir))
+		if err != nil {
+			return false, err
+		}
+		err = os.Rename(backupDir, filepath.Join(dir, previousDir))
+		if err != nil {
+			return false, err
+		}
+	}
+	service.logger.Debugf("Installed airport data files in %s", dir)
+	return len(current) > 0, nil
+}
+
+// Discard removes the staging directory, and any files left in it.
+func (service *AirportDataUpdaterService) Discard(stagingDir string) error {
+	return os.RemoveAll(stagingDir)
+}
+
+// Rollback replaces the files in the directory with the previous version kept by Install, which is then removed, so
+// only the last update can be rolled back.
+func (service *AirportDataUpdaterService) Rollback(dir string, filenames []string) error {
+	_, err := os.Stat(filepath.Join(dir, previousDir))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("No previous airport data files in %s to roll back to", dir)
+	} else if err != nil {
+		return err
+	}
+
+	_, err = moveFiles(filepath.Join(dir, previousDir), dir, filenames)
+	if err != nil {
+		return err
+	}
+	service.logger.Debugf("Restored the previous airport data files in %s", dir)
+	return os.RemoveAll(filepath.Join(dir, previousDir))
+}
+
+// Remove removes those of the files that exist from the directory, e.g. to undo the first install, which has no
+// previous version to roll back to.
+func (service *AirportDataUpdaterService) Remove(dir string, filenames []string) error {
+	for _, filename := range filenames {
+		err := os.Remove(filepath.Join(dir, filename))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	service.logger.Debugf("Removed the airport data files in %s", dir)
+	return nil
+}
+
+// moveFiles moves those of the files that exist from one directory to another, replacing any there, returning the
+// files moved.
+func moveFiles(fromDir string, toDir string, filenames []string) ([]string, error) {
+	moved := []string{}
+	for _, filename := range filenames {
+		from := filepath.Join(fromDir, filename)
+		_, err := os.Stat(from)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return moved, err
+		}
+
+		err = os.Rename(from, filepath.Join(toDir, filename))
+		if err != nil {
+			return moved, err
+		}
+		moved = append(moved, filename)
+	}
+	return moved, nil
+}
@@ -0,0 +1,179 @@
+package framework
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testDataFiles = map[string]string{
+	"countries.csv": "code,name\nGB,United Kingdom\n",
+	"regions.csv":   "code,name\nGB-ENG,England\n",
+}
+
+var testDataFilenames = []string{"countries.csv", "regions.csv"}
+
+// newTestUpdater returns an updater of the files served from the base URL, and a directory for them that must be
+// removed after the test.
+func newTestUpdater(t *testing.T, baseURL string) (*AirportDataUpdaterService, string) {
+	dir, err := ioutil.TempDir("", "airports")
+	assert.Nil(t, err, "Error not expected")
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	return NewAirportDataUpdater(mockLogger, AirportDataUpdaterOptions{BaseURL: baseURL}), dir
+}
+
+// serveDataFiles returns a server of the files, last modified on 6 Oct 2019.
+func serveDataFiles(files map[string]string) *httptest.Server {
+	modified := time.Date(2019, time.October, 6, 0, 0, 0, 0, time.UTC)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contents, exists := files[filepath.Base(r.URL.Path)]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		fmt.Fprint(w, contents)
+	}))
+}
+
+// readFile returns the contents of the file, or "" if it doesn't exist.
+func readFile(path string) string {
+	contents, _ := ioutil.ReadFile(path)
+	return string(contents)
+}
+
+// TestAirportDataUpdater_Download tests downloading the files to a staging directory.
+func TestAirportDataUpdater_Download(t *testing.T) {
+	server := serveDataFiles(testDataFiles)
+	defer server.Close()
+	updater, dir := newTestUpdater(t, server.URL)
+	defer os.RemoveAll(dir)
+
+	dataset, stagingDir, err := updater.Download(context.Background(), dir, testDataFilenames)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, dir, filepath.Dir(stagingDir), "Wrong staging directory")
+	for _, filename := range testDataFilenames {
+		assert.Equal(t, testDataFiles[filename], readFile(filepath.Join(stagingDir, filename)), "Wrong file")
+	}
+
+	checksum := sha256.Sum256([]byte(testDataFiles["countries.csv"] + testDataFiles["regions.csv"]))
+	assert.Equal(t, server.URL+"/", dataset.Source, "Wrong source")
+	assert.Equal(t, hex.EncodeToString(checksum[:]), dataset.Checksum, "Wrong checksum")
+	assert.True(t, dataset.Published.Equal(time.Date(2019, time.October, 6, 0, 0, 0, 0, time.UTC)),
+		"Wrong published")
+	assert.False(t, dataset.Downloaded.IsZero(), "Expected downloaded")
+}
+
+// TestAirportDataUpdater_DownloadFails tests a failed download, or one that doesn't match its checksum, leaves no
+// staging directory.
+func TestAirportDataUpdater_DownloadFails(t *testing.T) {
+	countriesChecksum := sha256.Sum256([]byte(testDataFiles["countries.csv"]))
+	regionsChecksum := sha256.Sum256([]byte(testDataFiles["regions.csv"]))
+	countriesLine := hex.EncodeToString(countriesChecksum[:]) + "  countries.csv\n"
+	wrongRegionsLine := hex.EncodeToString(countriesChecksum[:]) + " *regions.csv\n"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{"Missing", map[string]string{"countries.csv": testDataFiles["countries.csv"]},
+			"Failed to download {url}/regions.csv: 404 Not Found"},
+		{"WrongChecksum", map[string]string{"countries.csv": testDataFiles["countries.csv"],
+			"regions.csv": testDataFiles["regions.csv"], ChecksumsFile: countriesLine + wrongRegionsLine},
+			"Checksum of regions.csv is " + hex.EncodeToString(regionsChecksum[:]) + ", expected " +
+				hex.EncodeToString(countriesChecksum[:])},
+		{"NoChecksum", map[string]string{"countries.csv": testDataFiles["countries.csv"],
+			"regions.csv": testDataFiles["regions.csv"], ChecksumsFile: countriesLine},
+			"SHA256SUMS has no checksum for regions.csv"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := serveDataFiles(test.files)
+			defer server.Close()
+			updater, dir := newTestUpdater(t, server.URL+"/")
+			defer os.RemoveAll(dir)
+
+			_, _, err := updater.Download(context.Background(), dir, testDataFilenames)
+			assert.EqualError(t, err, strings.Replace(test.expected, "{url}", server.URL, 1), "Wrong error")
+
+			entries, _ := ioutil.ReadDir(dir)
+			assert.Empty(t, entries, "Expected the staging directory to be removed")
+		})
+	}
+}
+
+// TestAirportDataUpdater_FileURL tests downloading the files from a local mirror.
+func TestAirportDataUpdater_FileURL(t *testing.T) {
+	mirror, err := ioutil.TempDir("", "mirror")
+	assert.Nil(t, err, "Error not expected")
+	defer os.RemoveAll(mirror)
+	for filename, contents := range testDataFiles {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(mirror, filename), []byte(contents), 0644), "Error not expected")
+	}
+	updater, dir := newTestUpdater(t, "file://"+filepath.ToSlash(mirror))
+	defer os.RemoveAll(dir)
+
+	_, stagingDir, err := updater.Download(context.Background(), dir, testDataFilenames)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, testDataFiles["regions.csv"], readFile(filepath.Join(stagingDir, "regions.csv")), "Wrong file")
+}
+
+// TestAirportDataUpdater_InstallAndRollback tests installing two updates keeps the version before the last, which can
+// be rolled back to once.
+func TestAirportDataUpdater_InstallAndRollback(t *testing.T) {
+	updater, dir := newTestUpdater(t, "https://example.com/")
+	defer os.RemoveAll(dir)
+
+	install := func(version string) bool {
+		stagingDir, err := ioutil.TempDir(dir, ".download-")
+		assert.Nil(t, err, "Error not expected")
+		for _, filename := range testDataFilenames {
+			err = ioutil.WriteFile(filepath.Join(stagingDir, filename), []byte(version), 0644)
+			assert.Nil(t, err, "Error not expected")
+		}
+		replaced, err := updater.Install(dir, stagingDir, testDataFilenames)
+		assert.Nil(t, err, "Error not expected")
+		assert.Nil(t, updater.Discard(stagingDir), "Error not expected")
+		return replaced
+	}
+
+	assert.False(t, install("v1"), "Expected nothing replaced")
+	assert.Equal(t, "v1", readFile(filepath.Join(dir, "regions.csv")), "Wrong current version")
+	assert.Equal(t, "", readFile(filepath.Join(dir, previousDir, "regions.csv")), "Expected no previous version")
+
+	assert.True(t, install("v2"), "Expected the current version replaced")
+	install("v3")
+	assert.Equal(t, "v3", readFile(filepath.Join(dir, "regions.csv")), "Wrong current version")
+	assert.Equal(t, "v2", readFile(filepath.Join(dir, previousDir, "countries.csv")), "Wrong previous version")
+
+	assert.Nil(t, updater.Rollback(dir, testDataFilenames), "Error not expected")
+	assert.Equal(t, "v2", readFile(filepath.Join(dir, "countries.csv")), "Wrong current version")
+	assert.Equal(t, "v2", readFile(filepath.Join(dir, "regions.csv")), "Wrong current version")
+
+	err := updater.Rollback(dir, testDataFilenames)
+	assert.EqualError(t, err, fmt.Sprintf("No previous airport data files in %s to roll back to", dir), "Wrong error")
+
+	entries, _ := ioutil.ReadDir(dir)
+	assert.Len(t, entries, 2, "Expected only the data files")
+
+	assert.Nil(t, updater.Remove(dir, append(testDataFilenames, "airports.csv")), "Error not expected")
+	entries, _ = ioutil.ReadDir(dir)
+	assert.Empty(t, entries, "Expected the data files removed")
+}
@@ -0,0 +1,188 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// redacted replaces the values of secret headers in recorded requests.
+const redacted = "REDACTED"
+
+// secretHeaders are never written to a cassette.
+var secretHeaders = []string{"x-rapidapi-key"}
+
+// Cassette holds the request and response pairs of recorded API traffic, oldest first.
+type Cassette struct {
+	Interactions []CassetteInteraction
+}
+
+// CassetteInteraction is a single recorded request, and its response.
+type CassetteInteraction struct {
+	Request  CassetteRequest
+	Response CassetteResponse
+}
+
+// CassetteRequest is a recorded request, with any secret headers redacted.
+type CassetteRequest struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    string
+}
+
+// CassetteResponse is a recorded response.
+type CassetteResponse struct {
+	StatusCode int
+	Status     string
+	Headers    http.Header
+	Body       string
+}
+
+// LoadCassette reads a cassette from the file.
+func LoadCassette(filename string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cassette := Cassette{}
+	err = json.Unmarshal(data, &cassette)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cassette %s: %w", filename, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to the file, replacing any existing contents.
+func (cassette *Cassette) Save(filename string) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// RecordingTransport makes requests with another transport, and records each request and response to a cassette
+// file. The file is saved after every request, so is complete even if the program is interrupted. It is safe for
+// concurrent use.
+type RecordingTransport struct {
+	next     http.RoundTripper
+	filename string
+	mutex    sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingTransport creates a new instance, that makes requests with next (or http.DefaultTransport if nil) and
+// records them to the file.
+func NewRecordingTransport(next http.RoundTripper, filename string) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RecordingTransport{next: next, filename: filename}
+}
+
+// RoundTrip makes the request, then records it along with its response.
+func (transport *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := transport.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := readBody(&res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := req.Header.Clone()
+	for _, name := range secretHeaders {
+		if headers.Get(name) != "" {
+			headers.Set(name, redacted)
+		}
+	}
+
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	transport.cassette.Interactions = append(transport.cassette.Interactions, CassetteInteraction{
+		Request: CassetteRequest{Method: req.Method, URL: req.URL.String(), Headers: headers, Body: requestBody},
+		Response: CassetteResponse{StatusCode: res.StatusCode, Status: res.Status, Headers: res.Header,
+			Body: responseBody},
+	})
+	err = transport.cassette.Save(transport.filename)
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// ReplayTransport answers requests with the responses recorded in a cassette, without any network access. Each
+// recorded response is used once, for a request with the same method, URL and body, in the order they were recorded.
+// It is safe for concurrent use.
+type ReplayTransport struct {
+	mutex        sync.Mutex
+	interactions []CassetteInteraction
+	used         []bool
+}
+
+// NewReplayTransport creates a new instance, that replays the cassette in the file.
+func NewReplayTransport(filename string) (*ReplayTransport, error) {
+	cassette, err := LoadCassette(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTransport{interactions: cassette.Interactions, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+// RoundTrip returns the next recorded response for the request, or an error if there are none left.
+func (transport *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	for index, interaction := range transport.interactions {
+		recorded := interaction.Request
+		if transport.used[index] || recorded.Method != req.Method || recorded.URL != req.URL.String() ||
+			recorded.Body != requestBody {
+			continue
+		}
+
+		transport.used[index] = true
+		return &http.Response{
+			StatusCode:    interaction.Response.StatusCode,
+			Status:        interaction.Response.Status,
+			Header:        interaction.Response.Headers,
+			Body:          ioutil.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("No recorded response left for %s %s", req.Method, req.URL)
+}
+
+// readBody reads all of a request or response body, and replaces it so it can be read again.
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	data, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
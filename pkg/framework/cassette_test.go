@@ -0,0 +1,104 @@
+package framework
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestCassette_RecordThenReplay tests recording a search against a server, then replaying it once the server has gone.
+func TestCassette_RecordThenReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	assert.Nil(t, err, "No error expected")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cassette.json")
+
+	server, recordingService := newFakeServer(FakeSkyScannerOptions{PendingPolls: 1, Itineraries: 4, Seed: 1})
+	recordingService.options.Client = &http.Client{Transport: NewRecordingTransport(nil, filename)}
+
+	sessionKey, err := recordingService.StartSearch(context.Background(), fakeArguments())
+	assert.Nil(t, err, "No error expected")
+	recorded := []interface{}{}
+	for poll := 0; poll < 2; poll++ {
+		quote, err := recordingService.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey",
+			fakeAirports)
+		assert.Nil(t, err, "No error expected")
+		recorded = append(recorded, quote)
+	}
+	server.Close()
+
+	data, err := ioutil.ReadFile(filename)
+	assert.Nil(t, err, "No error expected")
+	assert.False(t, strings.Contains(string(data), "testKey"), "API key should be redacted")
+	assert.True(t, strings.Contains(string(data), redacted), "API key should be redacted")
+
+	replay, err := NewReplayTransport(filename)
+	assert.Nil(t, err, "No error expected")
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything)
+	replayService := NewSkyScannerService(mockLogger, SkyScannerOptions{Client: &http.Client{Transport: replay},
+		BaseURL: server.URL, PageSize: 3, Retry: RetryPolicy{MaxAttempts: 1}})
+
+	replayedKey, err := replayService.StartSearch(context.Background(), fakeArguments())
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, sessionKey, replayedKey, "Wrong session key")
+	for poll := 0; poll < 2; poll++ {
+		quote, err := replayService.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey",
+			fakeAirports)
+		assert.Nil(t, err, "No error expected")
+		assert.Equal(t, recorded[poll], quote, "Wrong quote for poll %d", poll)
+	}
+
+	_, err = replayService.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Error(t, err, "Expected no recorded response left")
+}
+
+// TestReplayTransport_Unmatched tests replaying a request that was never recorded.
+func TestReplayTransport_Unmatched(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	assert.Nil(t, err, "No error expected")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cassette.json")
+
+	cassette := Cassette{Interactions: []CassetteInteraction{
+		CassetteInteraction{
+			Request:  CassetteRequest{Method: "GET", URL: "http://test.com/a"},
+			Response: CassetteResponse{StatusCode: 200, Status: "200 OK", Body: "{}"},
+		},
+	}}
+	assert.Nil(t, cassette.Save(filename), "No error expected")
+
+	replay, err := NewReplayTransport(filename)
+	assert.Nil(t, err, "No error expected")
+	client := &http.Client{Transport: replay}
+
+	_, err = client.Post("http://test.com/a", "text/plain", strings.NewReader("body"))
+	assert.Error(t, err, "Expected a different method not to match")
+
+	res, err := client.Get("http://test.com/a")
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, 200, res.StatusCode, "Wrong status")
+	res.Body.Close()
+}
+
+// TestLoadCassette_Invalid tests loading a file that isn't a cassette.
+func TestLoadCassette_Invalid(t *testing.T) {
+	file, err := ioutil.TempFile("", "cassette")
+	assert.Nil(t, err, "No error expected")
+	defer os.Remove(file.Name())
+	file.WriteString("not json")
+	file.Close()
+
+	_, err = LoadCassette(file.Name())
+	assert.Error(t, err, "Expected an error")
+}
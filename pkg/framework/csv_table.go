@@ -0,0 +1,139 @@
+package framework
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVError reports a malformed row of a CSV file, with where it is.
+type CSVError struct {
+	File    string
+	Line    int    // of the start of the row, counting from 1
+	Column  string // optional, the name of the column in the header row
+	Message string
+}
+
+// Error returns the location and message of the error, e.g. "airports.csv line 12, column latitude_deg: Invalid
+// number abc".
+func (err *CSVError) Error() string {
+	if err.Column == "" {
+		return fmt.Sprintf("%s line %d: %s", err.File, err.Line, err.Message)
+	}
+	return fmt.Sprintf("%s line %d, column %s: %s", err.File, err.Line, err.Column, err.Message)
+}
+
+// csvTable reads a CSV file whose header row names its columns, so the values of each row are read by column name
+// whatever order the columns are in. Every row must have the same number of values as the header.
+type csvTable struct {
+	filename string
+	lines    *lineReader
+	reader   *csv.Reader
+	columns  map[string]int
+	record   []string
+	line     int // of the current row
+}
+
+// newCSVTable reads the header row of the CSV file, returning an error naming any of the required columns that are
+// missing.
+func newCSVTable(reader io.Reader, filename string, required ...string) (*csvTable, error) {
+	lines := &lineReader{reader: bufio.NewReader(reader)}
+	table := &csvTable{filename: filename, lines: lines, reader: csv.NewReader(lines), columns: make(map[string]int)}
+	header, err := table.reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s is empty, expected a header row", filename)
+	} else if err != nil {
+		return nil, table.parseError(err)
+	}
+	table.advance(header)
+
+	for index, name := range header {
+		table.columns[strings.TrimSpace(name)] = index
+	}
+	missing := []string{}
+	for _, name := range required {
+		if _, exists := table.columns[name]; !exists {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s is missing columns %s", filename, strings.Join(missing, ", "))
+	}
+	return table, nil
+}
+
+// next reads the next row, returning false once there are none left. A row with the wrong number of values returns
+// true with a *CSVError, so the caller can skip it and carry on.
+func (table *csvTable) next() (bool, error) {
+	record, err := table.reader.Read()
+	if err == io.EOF {
+		return false, nil
+	} else if errors.Is(err, csv.ErrFieldCount) {
+		table.advance(record)
+		return true, table.parseError(err)
+	} else if err != nil {
+		return false, table.parseError(err)
+	}
+	table.advance(record)
+	return true, nil
+}
+
+// advance makes the record the current row. The csv reader has read up to the last line of the row, and skipped any
+// blank lines before it, so the line the row starts on is found by counting back the lines of its quoted values.
+func (table *csvTable) advance(record []string) {
+	table.record = record
+	table.line = table.lines.count
+	for _, value := range record {
+		table.line -= strings.Count(value, "\n")
+	}
+}
+
+// value returns the value of the column in the current row, without leading or trailing spaces, or "" if the header
+// row has no such column.
+func (table *csvTable) value(column string) string {
+	index, exists := table.columns[column]
+	if !exists {
+		return ""
+	}
+	return strings.TrimSpace(table.record[index])
+}
+
+// errorf returns an error for the column of the current row.
+func (table *csvTable) errorf(column string, format string, args ...interface{}) *CSVError {
+	return &CSVError{File: table.filename, Line: table.line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+// parseError returns an error for a row that isn't valid CSV.
+func (table *csvTable) parseError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &CSVError{File: table.filename, Line: parseErr.StartLine, Message: parseErr.Err.Error()}
+	}
+	return err
+}
+
+// lineReader reads at most one line at a time, counting the lines read. The csv reader only reads more once it has
+// used every line read so far, so the count is the last line it has reached.
+type lineReader struct {
+	reader  *bufio.Reader
+	pending []byte // rest of the current line
+	count   int
+}
+
+// Read reads the rest of the current line, or the next line, into the buffer.
+func (lines *lineReader) Read(buffer []byte) (int, error) {
+	if len(lines.pending) == 0 {
+		line, err := lines.reader.ReadBytes('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		lines.pending = line
+		lines.count++
+	}
+	read := copy(buffer, lines.pending)
+	lines.pending = lines.pending[read:]
+	return read, nil
+}
@@ -2,16 +2,19 @@ package framework
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/chrisnappin/flightchecker/pkg/domain"
 	_ "github.com/mattn/go-sqlite3" // use sqlite3 driver
 )
 
-// OpenDatabase deletes the database (if recreate is true), then returns a connection to a new SQLite database, stored
-// in the specified database file
+// OpenDatabase deletes the database (if recreate is true), then returns a connection to the SQLite database stored in
+// the specified database file, creating it (and its directory) if needed.
 func OpenDatabase(filename string, recreate bool) (*sql.DB, error) {
 	_, err := os.Stat(filename)
-	if err == nil {
+	if err == nil && recreate {
 		// file exists, so remove it
 		err = os.Remove(filename)
 		if err != nil {
@@ -19,9 +22,41 @@ func OpenDatabase(filename string, recreate bool) (*sql.DB, error) {
 		}
 	}
 
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return nil, err
+	}
+
 	database, err := sql.Open("sqlite3", filename)
 	if err != nil {
 		return nil, err
 	}
+
+	// SQLite only allows one writer at a time, so serialise all access rather than fail with "database is locked"
+	database.SetMaxOpenConns(1)
 	return database, nil
 }
+
+// OpenFlightRepository returns a connection to the SQLite database stored in the specified database file, and a
+// repository of it, creating the schema if the database is new. An existing database with an older schema must be
+// upgraded first, using "flightchecker db migrate".
+func OpenFlightRepository(logger domain.Logger, filename string) (*sql.DB, *FlightRepository, error) {
+	db, err := OpenDatabase(filename, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	flightRepository := NewFlightRepository(logger, db)
+
+	version, err := flightRepository.SchemaVersion()
+	if err == nil && version == 0 {
+		err = flightRepository.MigrateSchema()
+	} else if err == nil && version != flightRepository.LatestSchemaVersion() {
+		err = fmt.Errorf("Database %s has schema version %d, run \"flightchecker db migrate\" to upgrade it to %d",
+			filename, version, flightRepository.LatestSchemaVersion())
+	}
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, flightRepository, nil
+}
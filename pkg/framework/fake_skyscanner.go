@@ -0,0 +1,547 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+const (
+	createSessionPath = "/apiservices/pricing/v1.0"
+	pollSessionPath   = "/apiservices/pricing/uk2/v1.0/"
+	bookingPath       = "/booking"
+	skyScannerTime    = "2006-01-02T15:04:05"
+)
+
+// FakeSkyScannerOptions configures how the fake sky scanner API behaves.
+type FakeSkyScannerOptions struct {
+	PendingPolls int            // polls of each session answered as still pending, before its results are complete
+	Itineraries  int            // itineraries found by each session
+	Faults       map[int]string // scripted faults keyed by request number (from 1), e.g. "429", "500" or "malformed"
+	Seed         int64          // seeds the random itineraries, so runs can be repeated
+}
+
+// FakeSkyScanner is a stand-in for the sky scanner API, that finds random but realistic itineraries between known
+// airports. It handles the "Create session", "Poll session results", "Create booking details" and "Poll booking
+// details" operations, and is safe for concurrent use.
+type FakeSkyScanner struct {
+	logger   domain.Logger
+	airports map[string]domain.Airport
+	stops    []domain.Airport // airports that connecting flights can stop at, in code order
+	options  FakeSkyScannerOptions
+	mutex    sync.Mutex
+	random   *rand.Rand
+	requests int
+	sessions map[string]*fakeSession
+}
+
+// fakeSession holds the complete results of a session, and how many times it has been polled.
+type fakeSession struct {
+	polls    int
+	response SkyScannerResponse
+}
+
+// fakeCarriers are the airlines that fly every fake itinerary.
+var fakeCarriers = []SkyScannerCarrier{
+	SkyScannerCarrier{ID: 881, Code: "BA", Name: "British Airways", DisplayCode: "BA"},
+	SkyScannerCarrier{ID: 1218, Code: "VS", Name: "Virgin Atlantic", DisplayCode: "VS"},
+	SkyScannerCarrier{ID: 838, Code: "AA", Name: "American Airlines", DisplayCode: "AA"},
+	SkyScannerCarrier{ID: 1324, Code: "LH", Name: "Lufthansa", DisplayCode: "LH"},
+	SkyScannerCarrier{ID: 1090, Code: "U2", Name: "easyJet", DisplayCode: "U2"},
+	SkyScannerCarrier{ID: 1368, Code: "KL", Name: "KLM", DisplayCode: "KL"},
+}
+
+// fakeTravelAgents sell tickets for every carrier, alongside the carrier itself.
+var fakeTravelAgents = []SkyScannerAgent{
+	SkyScannerAgent{ID: 2001, Name: "Expedia", Type: "TravelAgent"},
+	SkyScannerAgent{ID: 2002, Name: "Opodo", Type: "TravelAgent"},
+	SkyScannerAgent{ID: 2003, Name: "Trip.com", Type: "TravelAgent"},
+}
+
+// fakeCurrencies are the formats of well known currencies, others are shown with their code.
+var fakeCurrencies = map[string]SkyScannerCurrency{
+	"GBP": SkyScannerCurrency{Code: "GBP", Symbol: "£", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 2},
+	"EUR": SkyScannerCurrency{Code: "EUR", Symbol: "€", ThousandsSeparator: ".", DecimalSeparator: ",",
+		SpaceBetweenAmountAndSymbol: true, DecimalDigits: 2},
+	"USD": SkyScannerCurrency{Code: "USD", Symbol: "$", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 2},
+	"JPY": SkyScannerCurrency{Code: "JPY", Symbol: "¥", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 0},
+}
+
+// fakeExchangeRates convert prices in GBP to other currencies, roughly.
+var fakeExchangeRates = map[string]float64{"GBP": 1, "EUR": 1.15, "USD": 1.25, "JPY": 180}
+
+// fakeCabinPrices multiply the price of an economy ticket for other cabin classes.
+var fakeCabinPrices = map[string]float64{"economy": 1, "premiumeconomy": 1.6, "business": 3.5, "first": 6}
+
+// NewFakeSkyScanner creates a new instance, that finds itineraries between the airports.
+func NewFakeSkyScanner(logger domain.Logger, airports map[string]domain.Airport,
+	options FakeSkyScannerOptions) *FakeSkyScanner {
+	stops := domain.AirportMapValues(airports)
+	sort.Slice(stops, func(i, j int) bool {
+		return stops[i].IataCode < stops[j].IataCode
+	})
+	return &FakeSkyScanner{
+		logger:   logger,
+		airports: airports,
+		stops:    stops,
+		options:  options,
+		random:   rand.New(rand.NewSource(options.Seed)),
+		sessions: make(map[string]*fakeSession),
+	}
+}
+
+// ParseFaults parses a fault script of comma separated request numbers and faults, e.g. "2:429,5:500,7:malformed".
+// A fault is either an HTTP status code, or "malformed" for a response of invalid JSON.
+func ParseFaults(script string) (map[int]string, error) {
+	faults := make(map[int]string)
+	if strings.TrimSpace(script) == "" {
+		return faults, nil
+	}
+	for _, entry := range strings.Split(script, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid fault %s, expected e.g. 2:429", entry)
+		}
+		request, err := strconv.Atoi(parts[0])
+		if err != nil || request < 1 {
+			return nil, fmt.Errorf("Invalid request number %s in fault %s", parts[0], entry)
+		}
+		if parts[1] != "malformed" {
+			status, err := strconv.Atoi(parts[1])
+			if err != nil || status < 100 || status > 599 {
+				return nil, fmt.Errorf("Invalid fault %s in %s, expected an HTTP status or malformed", parts[1], entry)
+			}
+		}
+		faults[request] = parts[1]
+	}
+	return faults, nil
+}
+
+// ServeHTTP handles a request to the fake API.
+func (fake *FakeSkyScanner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.requests++
+	fake.logger.Infof("Request %d: %s %s", fake.requests, r.Method, r.URL.Path)
+
+	fault, exists := fake.options.Faults[fake.requests]
+	switch {
+	case exists:
+		fake.injectFault(w, fault)
+	case r.Header.Get("x-rapidapi-key") == "":
+		fake.writeError(w, http.StatusUnauthorized, "Missing API key")
+	case r.Method == http.MethodPost && r.URL.Path == createSessionPath:
+		fake.createSession(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, pollSessionPath):
+		fake.pollSession(w, r)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, createSessionPath+"/") &&
+		strings.HasSuffix(r.URL.Path, bookingPath):
+		fake.createBookingDetails(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, createSessionPath+"/") &&
+		strings.Contains(r.URL.Path, bookingPath+"/"):
+		fake.pollBookingDetails(w, r)
+	default:
+		fake.writeError(w, http.StatusNotFound, "Unknown operation")
+	}
+}
+
+// injectFault responds with the scripted fault instead of handling the request.
+func (fake *FakeSkyScanner) injectFault(w http.ResponseWriter, fault string) {
+	fake.logger.Infof("Injecting fault %s", fault)
+	if fault == "malformed" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"SessionKey": "abc", "Itineraries": [`)
+		return
+	}
+
+	status, _ := strconv.Atoi(fault) // validated by ParseFaults
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+	}
+	fake.writeError(w, status, http.StatusText(status))
+}
+
+// writeError responds with the status, and a JSON body with the message.
+func (fake *FakeSkyScanner) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// createSession handles the "Create session" operation, finding every itinerary straight away.
+func (fake *FakeSkyScanner) createSession(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		fake.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query, err := fake.parseQuery(r)
+	if err != nil {
+		fake.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := fmt.Sprintf("%08x-fake-%d", fake.random.Uint32(), len(fake.sessions)+1)
+	response := fake.findItineraries(key, query)
+	fake.sessions[key] = &fakeSession{response: response}
+	fake.logger.Infof("Created session %s from %s to %s, with %d itineraries", key, query.OriginPlace,
+		query.DestinationPlace, len(response.Itineraries))
+
+	w.Header().Set("Location", "http://"+r.Host+pollSessionPath+key)
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, "{}")
+}
+
+// parseQuery returns the search parameters of a "Create session" request, or an error if any are invalid.
+func (fake *FakeSkyScanner) parseQuery(r *http.Request) (SkyScannerQuery, error) {
+	query := SkyScannerQuery{
+		Country:          r.PostForm.Get("country"),
+		Currency:         r.PostForm.Get("currency"),
+		Locale:           r.PostForm.Get("locale"),
+		OriginPlace:      strings.TrimSuffix(r.PostForm.Get("originPlace"), "-sky"),
+		DestinationPlace: strings.TrimSuffix(r.PostForm.Get("destinationPlace"), "-sky"),
+		OutboundDate:     r.PostForm.Get("outboundDate"),
+		InboundDate:      r.PostForm.Get("inboundDate"),
+		LocationSchema:   "Default",
+		CabinClass:       r.PostForm.Get("cabinClass"),
+		GroupPricing:     r.PostForm.Get("groupPricing") == "true",
+	}
+
+	for _, place := range []string{query.OriginPlace, query.DestinationPlace} {
+		if _, exists := fake.airports[place]; !exists {
+			return query, fmt.Errorf("Unknown place %s", place)
+		}
+	}
+	for _, date := range []string{query.OutboundDate, query.InboundDate} {
+		if _, err := time.Parse(domain.DateFormat, date); date != "" && err != nil {
+			return query, fmt.Errorf("Invalid date %s", date)
+		}
+	}
+	if query.OutboundDate == "" {
+		return query, fmt.Errorf("Missing outbound date")
+	}
+	if _, exists := fakeCabinPrices[query.CabinClass]; !exists {
+		return query, fmt.Errorf("Unknown cabin class %s", query.CabinClass)
+	}
+	if query.Currency == "" {
+		return query, fmt.Errorf("Missing currency")
+	}
+
+	var err error
+	for name, value := range map[string]*int{"adults": &query.Adults, "children": &query.Children,
+		"infants": &query.Infants} {
+		*value, err = strconv.Atoi(r.PostForm.Get(name))
+		if err != nil || *value < 0 {
+			return query, fmt.Errorf("Invalid number of %s %s", name, r.PostForm.Get(name))
+		}
+	}
+	if query.Adults < 1 {
+		return query, fmt.Errorf("At least one adult is needed")
+	}
+	return query, nil
+}
+
+// pollSession handles the "Poll session results" operation. Results are pending, with fewer itineraries, until the
+// session has been polled enough times.
+func (fake *FakeSkyScanner) pollSession(w http.ResponseWriter, r *http.Request) {
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, pollSessionPath), "{}")
+	session, exists := fake.sessions[key]
+	if !exists {
+		fake.writeError(w, http.StatusGone, "Session expired")
+		return
+	}
+
+	pageIndex, err := strconv.Atoi(r.URL.Query().Get("pageIndex"))
+	if err != nil || pageIndex < 0 {
+		pageIndex = 0
+	}
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	if pageIndex == 0 {
+		session.polls++
+	}
+	response := session.response
+	response.Agents = append([]SkyScannerAgent{}, session.response.Agents...)
+	if session.polls <= fake.options.PendingPolls {
+		// reveals a growing share of the itineraries, with the agents yet to quote still pending
+		found := len(response.Itineraries) * session.polls / (fake.options.PendingPolls + 1)
+		response.Itineraries = response.Itineraries[:found]
+		response.Status = "UpdatesPending"
+		for index := range response.Agents {
+			if index >= len(response.Agents)*session.polls/(fake.options.PendingPolls+1) {
+				response.Agents[index].Status = "UpdatesPending"
+			}
+		}
+	}
+
+	start := pageIndex * pageSize
+	if start > len(response.Itineraries) {
+		start = len(response.Itineraries)
+	}
+	end := start + pageSize
+	if end > len(response.Itineraries) {
+		end = len(response.Itineraries)
+	}
+	response.Itineraries = response.Itineraries[start:end]
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// createBookingDetails handles the "Create booking details" operation, for the outbound and inbound legs of an
+// itinerary of the session.
+func (fake *FakeSkyScanner) createBookingDetails(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, createSessionPath+"/"), bookingPath)
+	if _, exists := fake.sessions[key]; !exists {
+		fake.writeError(w, http.StatusGone, "Session expired")
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		fake.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	outboundLegID := r.PostForm.Get("OutboundLegId")
+	if outboundLegID == "" {
+		fake.writeError(w, http.StatusBadRequest, "Missing outbound leg id")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("http://%s%s/%s%s/%s;%s", r.Host, createSessionPath, key, bookingPath,
+		outboundLegID, r.PostForm.Get("InboundLegId")))
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, "{}")
+}
+
+// pollBookingDetails handles the "Poll booking details" operation, offering each pricing option of the itinerary as
+// a booking option.
+func (fake *FakeSkyScanner) pollBookingDetails(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, createSessionPath+"/"), bookingPath+"/", 2)
+	session, exists := fake.sessions[parts[0]]
+	if !exists {
+		fake.writeError(w, http.StatusGone, "Session expired")
+		return
+	}
+
+	legs := strings.SplitN(parts[1], ";", 2)
+	for _, itinerary := range session.response.Itineraries {
+		if itinerary.OutboundLegID != legs[0] || (len(legs) > 1 && itinerary.InboundLegID != legs[1]) {
+			continue
+		}
+
+		details := SkyScannerBookingDetails{
+			Query:      session.response.Query,
+			Agents:     session.response.Agents,
+			Currencies: session.response.Currencies,
+		}
+		for _, pricingOption := range itinerary.PricingOptions {
+			details.BookingOptions = append(details.BookingOptions, SkyScannerBookingOption{
+				BookingItems: []SkyScannerBookingItem{SkyScannerBookingItem{
+					AgentID:  pricingOption.Agents[0],
+					Status:   "Current",
+					Price:    pricingOption.Price,
+					Deeplink: pricingOption.DeeplinkURL,
+				}},
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(details)
+		return
+	}
+	fake.writeError(w, http.StatusBadRequest, "Unknown itinerary")
+}
+
+// findItineraries returns the complete results of a session, with random itineraries for the query.
+func (fake *FakeSkyScanner) findItineraries(key string, query SkyScannerQuery) SkyScannerResponse {
+	response := SkyScannerResponse{
+		SessionKey: key,
+		Query:      query,
+		Status:     "UpdatesComplete",
+		Carriers:   fakeCarriers,
+	}
+
+	currency, exists := fakeCurrencies[query.Currency]
+	if !exists {
+		currency = SkyScannerCurrency{Code: query.Currency, Symbol: query.Currency, ThousandsSeparator: ",",
+			DecimalSeparator: ".", SpaceBetweenAmountAndSymbol: true, DecimalDigits: 2}
+	}
+	response.Currencies = []SkyScannerCurrency{currency}
+
+	for _, carrier := range fakeCarriers {
+		response.Agents = append(response.Agents, SkyScannerAgent{ID: carrier.ID, Name: carrier.Name,
+			Status: "UpdatesComplete", Type: "Airline"})
+	}
+	for _, agent := range fakeTravelAgents {
+		agent.Status = "UpdatesComplete"
+		response.Agents = append(response.Agents, agent)
+	}
+
+	places := make(map[string]int) // maps airport code to place id
+	for index := 0; index < fake.options.Itineraries; index++ {
+		carrier := fakeCarriers[fake.random.Intn(len(fakeCarriers))]
+		via := fake.randomStop(query)
+		itinerary := SkyScannerItinerary{}
+
+		outbound := fake.addLeg(&response, places, "Outbound", query.OutboundDate, query.OriginPlace,
+			query.DestinationPlace, via, carrier)
+		itinerary.OutboundLegID = outbound.ID
+		if query.InboundDate != "" {
+			inbound := fake.addLeg(&response, places, "Inbound", query.InboundDate, query.DestinationPlace,
+				query.OriginPlace, via, carrier)
+			itinerary.InboundLegID = inbound.ID
+		}
+		itinerary.BookingDetailsLink = SkyScannerBookingDetailsLink{
+			URI:    createSessionPath + "/" + key + bookingPath,
+			Body:   fmt.Sprintf("OutboundLegId=%s&InboundLegId=%s", itinerary.OutboundLegID, itinerary.InboundLegID),
+			Method: "PUT",
+		}
+
+		price := fake.price(query, via != "", currency.DecimalDigits)
+		itinerary.PricingOptions = []SkyScannerPricingOption{
+			fake.pricingOption(carrier.ID, price, itinerary.OutboundLegID),
+		}
+		for _, agent := range fakeTravelAgents {
+			if fake.random.Intn(2) == 0 {
+				agentPrice := roundPrice(price*(1+fake.random.Float64()/10), currency.DecimalDigits)
+				itinerary.PricingOptions = append(itinerary.PricingOptions,
+					fake.pricingOption(agent.ID, agentPrice, itinerary.OutboundLegID))
+			}
+		}
+		response.Itineraries = append(response.Itineraries, itinerary)
+	}
+
+	sort.SliceStable(response.Itineraries, func(i, j int) bool {
+		return response.Itineraries[i].PricingOptions[0].Price < response.Itineraries[j].PricingOptions[0].Price
+	})
+	return response
+}
+
+// randomStop returns the code of an airport for connecting flights to stop at, or empty for direct flights.
+func (fake *FakeSkyScanner) randomStop(query SkyScannerQuery) string {
+	if fake.random.Intn(3) > 0 || len(fake.stops) < 3 {
+		return ""
+	}
+	for {
+		stop := fake.stops[fake.random.Intn(len(fake.stops))].IataCode
+		if stop != query.OriginPlace && stop != query.DestinationPlace {
+			return stop
+		}
+	}
+}
+
+// addLeg adds a leg (with its segments and places) from the origin to the destination, via the stop if set, on the
+// date. Returns the leg.
+func (fake *FakeSkyScanner) addLeg(response *SkyScannerResponse, places map[string]int, direction string,
+	date string, origin string, destination string, via string, carrier SkyScannerCarrier) SkyScannerLeg {
+	day, _ := time.Parse(domain.DateFormat, date) // validated by parseQuery
+	departure := day.Add(time.Duration(6*60+fake.random.Intn(16*12)*5) * time.Minute)
+
+	airports := []string{origin, destination}
+	if via != "" {
+		airports = []string{origin, via, destination}
+	}
+
+	leg := SkyScannerLeg{
+		ID:                 fmt.Sprintf("%s-leg-%d", response.SessionKey, len(response.Legs)+1),
+		OriginStation:      fake.place(response, places, origin),
+		DestinationStation: fake.place(response, places, destination),
+		Departure:          departure.Format(skyScannerTime),
+		JourneyMode:        "Flight",
+		Carriers:           []int{carrier.ID},
+		OperatingCarriers:  []int{carrier.ID},
+		Directionality:     direction,
+	}
+
+	start := departure
+	for index := 0; index < len(airports)-1; index++ {
+		if index > 0 {
+			start = start.Add(time.Duration(60+fake.random.Intn(25)*5) * time.Minute) // connection
+			leg.Stops = append(leg.Stops, fake.place(response, places, airports[index]))
+		}
+		duration := 60 + fake.random.Intn(120)*5
+		segment := SkyScannerSegment{
+			ID:                 len(response.Segments) + 1,
+			OriginStation:      fake.place(response, places, airports[index]),
+			DestinationStation: fake.place(response, places, airports[index+1]),
+			DepartureDateTime:  start.Format(skyScannerTime),
+			ArrivalDateTime:    start.Add(time.Duration(duration) * time.Minute).Format(skyScannerTime),
+			Carrier:            carrier.ID,
+			OperatingCarrier:   carrier.ID,
+			Duration:           duration,
+			FlightNumber:       strconv.Itoa(100 + fake.random.Intn(9900)),
+			JourneyMode:        "Flight",
+			Directionality:     direction,
+		}
+		response.Segments = append(response.Segments, segment)
+		leg.SegmentIds = append(leg.SegmentIds, segment.ID)
+		leg.FlightNumbers = append(leg.FlightNumbers,
+			SkyScannerFlightNumber{FlightNumber: segment.FlightNumber, CarrierID: carrier.ID})
+		start = start.Add(time.Duration(duration) * time.Minute)
+	}
+
+	leg.Arrival = start.Format(skyScannerTime)
+	leg.Duration = int(start.Sub(departure).Minutes())
+	response.Legs = append(response.Legs, leg)
+	return leg
+}
+
+// place returns the id of the place for the airport, adding it to the response if not already included.
+func (fake *FakeSkyScanner) place(response *SkyScannerResponse, places map[string]int, code string) int {
+	id, exists := places[code]
+	if !exists {
+		id = 10000 + len(places)
+		places[code] = id
+		response.Places = append(response.Places, SkyScannerPlace{ID: id, Code: code, Type: "Airport",
+			Name: fake.airports[code].Name})
+	}
+	return id
+}
+
+// price returns a random total price for all passengers, in the query currency.
+func (fake *FakeSkyScanner) price(query SkyScannerQuery, connecting bool, decimalDigits int) float64 {
+	adultFare := float64(60 + fake.random.Intn(600))
+	if connecting {
+		adultFare *= 0.85
+	}
+	passengers := float64(query.Adults) + 0.75*float64(query.Children) + 0.1*float64(query.Infants)
+	rate, exists := fakeExchangeRates[query.Currency]
+	if !exists {
+		rate = 1
+	}
+	return roundPrice(adultFare*passengers*fakeCabinPrices[query.CabinClass]*rate, decimalDigits)
+}
+
+// pricingOption returns the price quoted by the agent.
+func (fake *FakeSkyScanner) pricingOption(agentID int, price float64, legID string) SkyScannerPricingOption {
+	return SkyScannerPricingOption{
+		Agents:            []int{agentID},
+		QuoteAgeInMinutes: fake.random.Intn(60),
+		Price:             price,
+		DeeplinkURL:       fmt.Sprintf("http://localhost/book?leg=%s&agent=%d", legID, agentID),
+	}
+}
+
+// roundPrice rounds the price to the number of decimal digits.
+func roundPrice(price float64, decimalDigits int) float64 {
+	unit := math.Pow10(decimalDigits)
+	return math.Round(price*unit) / unit
+}
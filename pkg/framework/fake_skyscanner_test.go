@@ -0,0 +1,202 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var airport3 = domain.Airport{
+	Name:     "Airport 3",
+	IataCode: "CODE3",
+	Region:   "Region 3",
+	Country:  "Country 3",
+}
+
+var fakeAirports = map[string]domain.Airport{
+	airport1.IataCode: airport1,
+	airport2.IataCode: airport2,
+	airport3.IataCode: airport3,
+}
+
+// newFakeServer starts a fake sky scanner server, and returns it with a service that calls it without retrying.
+func newFakeServer(options FakeSkyScannerOptions) (*httptest.Server, *SkyScannerService) {
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockLogger.On("Errorf", mock.Anything, mock.Anything)
+
+	server := httptest.NewServer(NewFakeSkyScanner(mockLogger, fakeAirports, options))
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{BaseURL: server.URL, PageSize: 3,
+		Retry: RetryPolicy{MaxAttempts: 1}})
+	return server, service
+}
+
+// fakeArguments returns arguments for a search between airports known to the fake server.
+func fakeArguments() *domain.Arguments {
+	arguments := dummyArguments
+	arguments.Origin = "CODE1"
+	arguments.Destination = "CODE2"
+	return &arguments
+}
+
+// TestFakeSkyScanner_Search tests a search is pending until polled enough times, then every itinerary is returned.
+func TestFakeSkyScanner_Search(t *testing.T) {
+	server, service := newFakeServer(FakeSkyScannerOptions{PendingPolls: 1, Itineraries: 8, Seed: 1})
+	defer server.Close()
+
+	sessionKey, err := service.StartSearch(context.Background(), fakeArguments())
+	assert.Nil(t, err, "No error expected")
+
+	pending, err := service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.False(t, pending.Complete, "Expected pending results")
+
+	complete, err := service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.True(t, complete.Complete, "Expected complete results")
+	assert.True(t, len(complete.Itineraries) >= 8, "Expected every itinerary, from every page")
+	assert.Equal(t, "GBP", complete.CurrencyFormat.Code, "Wrong currency format")
+
+	for _, itinerary := range complete.Itineraries {
+		outbound := itinerary.OutboundJourney.Flights
+		assert.Equal(t, "CODE1", outbound[0].StartAirport.IataCode, "Wrong origin")
+		assert.Equal(t, "CODE2", outbound[len(outbound)-1].DestinationAirport.IataCode, "Wrong destination")
+		assert.Equal(t, "2019-11-01", itinerary.OutboundJourney.StartTime.Format(domain.DateFormat), "Wrong date")
+		assert.NotNil(t, itinerary.InboundJourney, "Expected an inbound journey")
+		assert.True(t, itinerary.Price.Amount > 0, "Expected a price")
+	}
+}
+
+// TestFakeSkyScanner_BookingDetails tests following the booking details link of an itinerary found by a search.
+func TestFakeSkyScanner_BookingDetails(t *testing.T) {
+	server, service := newFakeServer(FakeSkyScannerOptions{Itineraries: 2, Seed: 1})
+	defer server.Close()
+
+	sessionKey, err := service.StartSearch(context.Background(), fakeArguments())
+	assert.Nil(t, err, "No error expected")
+	quote, err := service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Nil(t, err, "No error expected")
+
+	itinerary := quote.Itineraries[0]
+	assert.NotEmpty(t, itinerary.DeeplinkURL, "Expected a deeplink")
+	assert.NotNil(t, itinerary.BookingDetailsLink, "Expected a booking details link")
+
+	details, err := service.GetBookingDetails(context.Background(), itinerary.BookingDetailsLink, "test.com",
+		"testKey")
+	assert.Nil(t, err, "No error expected")
+	assert.True(t, details.Complete, "Expected confirmed prices")
+	assert.NotEmpty(t, details.Options, "Expected booking options")
+	for _, option := range details.Options {
+		assert.Len(t, option.Items, 1, "Wrong number of booking items")
+		assert.NotEmpty(t, option.Items[0].DeeplinkURL, "Expected a deeplink")
+	}
+
+	_, err = service.GetBookingDetails(context.Background(),
+		&domain.BookingDetailsLink{URI: "/apiservices/pricing/v1.0/unknown/booking", Body: "OutboundLegId=leg1"},
+		"test.com", "testKey")
+	assert.True(t, errors.Is(err, domain.ErrSessionExpired), "Wrong error %s", err)
+}
+
+// TestFakeSkyScanner_OneWay tests a search with no inbound date finds one-way itineraries.
+func TestFakeSkyScanner_OneWay(t *testing.T) {
+	server, service := newFakeServer(FakeSkyScannerOptions{Itineraries: 2})
+	defer server.Close()
+
+	arguments := fakeArguments()
+	arguments.TripType = domain.OneWayTrip
+	sessionKey, err := service.StartSearch(context.Background(), arguments)
+	assert.Nil(t, err, "No error expected")
+
+	quote, err := service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	for _, itinerary := range quote.Itineraries {
+		assert.Nil(t, itinerary.InboundJourney, "Expected no inbound journey")
+	}
+}
+
+// TestFakeSkyScanner_Errors tests requests the fake server rejects.
+func TestFakeSkyScanner_Errors(t *testing.T) {
+	server, service := newFakeServer(FakeSkyScannerOptions{Itineraries: 2})
+	defer server.Close()
+
+	arguments := fakeArguments()
+	arguments.Destination = "NOPE"
+	_, err := service.StartSearch(context.Background(), arguments)
+	assert.True(t, errors.Is(err, domain.ErrBadRequest), "Wrong error %s", err)
+
+	arguments = fakeArguments()
+	arguments.APIKey = ""
+	_, err = service.StartSearch(context.Background(), arguments)
+	assert.True(t, errors.Is(err, domain.ErrUnauthorized), "Wrong error %s", err)
+
+	_, err = service.PollForQuotes(context.Background(), "unknown", "test.com", "testKey", fakeAirports)
+	assert.True(t, errors.Is(err, domain.ErrSessionExpired), "Wrong error %s", err)
+}
+
+// TestFakeSkyScanner_Faults tests scripted faults are injected into the numbered requests.
+func TestFakeSkyScanner_Faults(t *testing.T) {
+	server, service := newFakeServer(FakeSkyScannerOptions{Itineraries: 2,
+		Faults: map[int]string{1: "429", 3: "malformed", 4: "500"}})
+	defer server.Close()
+
+	_, err := service.StartSearch(context.Background(), fakeArguments())
+	assert.True(t, errors.Is(err, domain.ErrRateLimited), "Wrong error %s", err)
+
+	sessionKey, err := service.StartSearch(context.Background(), fakeArguments())
+	assert.Nil(t, err, "No error expected")
+
+	_, err = service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Error(t, err, "Expected invalid JSON")
+
+	_, err = service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.True(t, errors.Is(err, domain.ErrUnavailable), "Wrong error %s", err)
+
+	_, err = service.PollForQuotes(context.Background(), sessionKey, "test.com", "testKey", fakeAirports)
+	assert.Nil(t, err, "No error expected")
+}
+
+// TestFakeSkyScanner_Retried tests a rate limited request is retried after the wait the fake server asks for.
+func TestFakeSkyScanner_Retried(t *testing.T) {
+	server, service := newFakeServer(FakeSkyScannerOptions{Itineraries: 2, Faults: map[int]string{1: "429"}})
+	defer server.Close()
+
+	delays := []time.Duration{}
+	service.options.Retry = RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	service.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	service.logger.(*mocks.Logger).On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	_, err := service.StartSearch(context.Background(), fakeArguments())
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, []time.Duration{time.Second}, delays, "Wrong delays")
+}
+
+// TestParseFaults tests parsing valid and invalid fault scripts.
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("2:429, 5:500,7:malformed")
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, map[int]string{2: "429", 5: "500", 7: "malformed"}, faults, "Wrong faults")
+
+	faults, err = ParseFaults("")
+	assert.Nil(t, err, "No error expected")
+	assert.Empty(t, faults, "Expected no faults")
+
+	for _, script := range []string{"429", "0:429", "x:500", "2:wibble", "2:999"} {
+		_, err = ParseFaults(script)
+		assert.Error(t, err, "Expected an error for %s", script)
+	}
+}
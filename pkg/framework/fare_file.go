@@ -0,0 +1,376 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// FareFileProviderName identifies the itineraries read from a fare file.
+const FareFileProviderName = "farefile"
+
+// fareTimeFormat is the format of the local departure and arrival times of fare flights.
+const fareTimeFormat = "2006-01-02T15:04"
+
+// fareFlightNumber matches the flight number of a fare flight: a 2 character IATA airline designator, which isn't
+// all digits, then 1 to 4 digits and an optional operational suffix letter.
+var fareFlightNumber = regexp.MustCompile(`^([A-Z][A-Z0-9]|[0-9][A-Z])([0-9]{1,4}[A-Z]?)$`)
+
+// Fare is a price for a trip, read from a fare file.
+type Fare struct {
+	Origin       string       // IATA airport code
+	Destination  string       // IATA airport code
+	ReturnOrigin string       `json:",omitempty"` // IATA airport code, open-jaw trips only, to fly home from
+	OutboundDate string       // YYYY-MM-DD
+	InboundDate  string       `json:",omitempty"` // YYYY-MM-DD, empty for one-way trips
+	CabinClass   string       `json:",omitempty"` // empty for economy
+	Adults       int          // the passengers the amount is for, at least 1 adult
+	Children     int          `json:",omitempty"`
+	Infants      int          `json:",omitempty"`
+	Supplier     string       // e.g. "British Airways"
+	SupplierType string       `json:",omitempty"` // e.g. "Airline" or "TravelAgent"
+	Amount       int          // total for all passengers, in minor units, e.g. 12345 is £123.45
+	Currency     string       // ISO 4217 code, e.g. "GBP"
+	DeeplinkURL  string       `json:",omitempty"` // booking page of the supplier
+	Outbound     []FareFlight // in order
+	Inbound      []FareFlight `json:",omitempty"` // in order, empty for one-way trips
+}
+
+// FareFlight is a single flight of a fare.
+type FareFlight struct {
+	FlightNumber string // IATA airline designator then number, e.g. "BA123"
+	From         string // IATA airport code
+	Departure    string // local time, e.g. "2019-11-01T08:30"
+	To           string // IATA airport code
+	Arrival      string // local time, e.g. "2019-11-01T11:30"
+}
+
+// FareFileProvider finds quotes from the fares in a local JSON or CSV file, e.g. prices found by hand, or exported
+// from another source, so they can be compared with those of sky scanner.
+type FareFileProvider struct {
+	logger domain.Logger
+	fares  []Fare
+}
+
+// NewFareFileProvider creates a new instance, reading the fares from the file. Files ending in ".csv" are read as CSV,
+// otherwise as JSON.
+//
+// A JSON file holds an array of Fare objects. A CSV file has a header row naming the columns origin, destination,
+// outbound_date, adults, supplier, amount, currency and outbound_flights, and optionally return_origin, inbound_date,
+// cabin_class, children, infants, supplier_type, deeplink_url and inbound_flights, in any order. Flights are
+// separated by ";", and each is its flight number, from, departure, to and arrival separated by spaces, e.g.
+// "BA123 LHR 2019-11-01T08:30 JFK 2019-11-01T11:30".
+func NewFareFileProvider(logger domain.Logger, filename string) (*FareFileProvider, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var fares []Fare
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		fares, err = readFareCSV(file, filename)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = json.NewDecoder(file).Decode(&fares)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fare file %s: %w", filename, err)
+		}
+	}
+
+	for index, fare := range fares {
+		err = fare.validate()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fare %d in %s: %w", index+1, filename, err)
+		}
+	}
+	logger.Debugf("Read %d fares from %s", len(fares), filename)
+	return &FareFileProvider{logger, fares}, nil
+}
+
+// Name returns the name of the provider.
+func (provider *FareFileProvider) Name() string {
+	return FareFileProviderName
+}
+
+// Quote returns an itinerary for every fare of the same trip, passengers, cabin class and currency as the arguments.
+// Without group pricing, only fares for 1 adult match.
+func (provider *FareFileProvider) Quote(ctx context.Context, arguments *domain.Arguments,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	arguments = arguments.WithDefaults()
+	adults, children, infants := arguments.Adults, arguments.Children, arguments.Infants
+	if !*arguments.GroupPricing {
+		adults, children, infants = 1, 0, 0
+	}
+	inboundDate := ""
+	if arguments.Trip() != domain.OneWayTrip {
+		date, err := arguments.InboundDate()
+		if err != nil {
+			return nil, err
+		}
+		inboundDate = date
+	}
+	returnOrigin := arguments.Destination
+	if arguments.Trip() == domain.OpenJawTrip {
+		returnOrigin = arguments.ReturnOrigin
+	}
+
+	quote := domain.Quote{Currency: arguments.Currency, Itineraries: []*domain.Itinerary{}, Complete: true}
+	for index, fare := range provider.fares {
+		if fare.Origin != arguments.Origin || fare.Destination != arguments.Destination ||
+			fare.OutboundDate != arguments.OutboundDate || fare.InboundDate != inboundDate ||
+			(inboundDate != "" && fare.returnOrigin() != returnOrigin) ||
+			fare.Adults != adults || fare.Children != children || fare.Infants != infants ||
+			fare.cabinClass() != arguments.CabinClass || fare.Currency != arguments.Currency {
+			continue
+		}
+
+		itinerary, err := fare.convertToDomain(index, airports)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fare %d: %w", index+1, err)
+		}
+		quote.Itineraries = append(quote.Itineraries, itinerary)
+	}
+	provider.logger.Debugf("Found %d fares from %s to %s", len(quote.Itineraries), arguments.Origin,
+		arguments.Destination)
+	return &quote, nil
+}
+
+// returnOrigin returns the airport the fare flies home from.
+func (fare *Fare) returnOrigin() string {
+	if fare.ReturnOrigin == "" {
+		return fare.Destination
+	}
+	return fare.ReturnOrigin
+}
+
+// cabinClass returns the cabin class of the fare, defaulting to economy.
+func (fare *Fare) cabinClass() string {
+	if fare.CabinClass == "" {
+		return domain.DefaultCabinClass
+	}
+	return fare.CabinClass
+}
+
+// validate returns an error if any field of the fare is missing or invalid.
+func (fare *Fare) validate() error {
+	switch {
+	case fare.Origin == "" || fare.Destination == "":
+		return fmt.Errorf("Missing origin or destination")
+	case fare.Adults < 1 || fare.Children < 0 || fare.Infants < 0:
+		return fmt.Errorf("Invalid passengers %d adults, %d children and %d infants, expected at least 1 adult",
+			fare.Adults, fare.Children, fare.Infants)
+	case fare.Supplier == "":
+		return fmt.Errorf("Missing supplier")
+	case fare.Amount <= 0:
+		return fmt.Errorf("Invalid amount %d", fare.Amount)
+	case len(fare.Currency) != 3:
+		return fmt.Errorf("Invalid currency %s", fare.Currency)
+	case len(fare.Outbound) == 0:
+		return fmt.Errorf("Missing outbound flights")
+	case fare.InboundDate == "" && len(fare.Inbound) > 0:
+		return fmt.Errorf("Inbound flights without an inbound date")
+	case fare.InboundDate != "" && len(fare.Inbound) == 0:
+		return fmt.Errorf("Missing inbound flights")
+	}
+	for _, date := range []string{fare.OutboundDate, fare.InboundDate} {
+		if _, err := time.Parse(domain.DateFormat, date); date != "" && err != nil {
+			return fmt.Errorf("Invalid date %s", date)
+		}
+	}
+	if fare.OutboundDate == "" {
+		return fmt.Errorf("Missing outbound date")
+	}
+	for _, flight := range append(append([]FareFlight{}, fare.Outbound...), fare.Inbound...) {
+		_, err := flight.times()
+		if err != nil {
+			return err
+		}
+		if !fareFlightNumber.MatchString(flight.FlightNumber) {
+			return fmt.Errorf("Invalid flight number %s, expected an IATA airline designator then number, e.g. BA123",
+				flight.FlightNumber)
+		}
+	}
+	return nil
+}
+
+// convertToDomain returns the fare as an itinerary, with the airports of its flights.
+func (fare *Fare) convertToDomain(index int, airports map[string]domain.Airport) (*domain.Itinerary, error) {
+	outbound, err := convertFareJourney(fmt.Sprintf("fare%d-outbound", index+1), domain.Outbound, fare.Outbound,
+		airports)
+	if err != nil {
+		return nil, err
+	}
+
+	var inbound *domain.Journey
+	if len(fare.Inbound) > 0 {
+		inbound, err = convertFareJourney(fmt.Sprintf("fare%d-inbound", index+1), domain.Inbound, fare.Inbound,
+			airports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &domain.Itinerary{
+		Origin:          fare.Origin,
+		Destination:     fare.Destination,
+		Provider:        FareFileProviderName,
+		SupplierName:    fare.Supplier,
+		SupplierType:    fare.SupplierType,
+		Price:           domain.Money{Amount: fare.Amount, Currency: fare.Currency},
+		DeeplinkURL:     fare.DeeplinkURL,
+		OutboundJourney: outbound,
+		InboundJourney:  inbound,
+	}, nil
+}
+
+// convertFareJourney returns the flights as a journey. Durations are taken from the local times, so are only
+// approximate for flights between time zones.
+func convertFareJourney(id string, direction domain.Direction, fareFlights []FareFlight,
+	airports map[string]domain.Airport) (*domain.Journey, error) {
+	journey := domain.Journey{ID: id, Direction: direction, Flights: []*domain.Flight{}}
+	for index, fareFlight := range fareFlights {
+		times, _ := fareFlight.times() // validated when read
+		flightNumber := fareFlightNumber.FindStringSubmatch(fareFlight.FlightNumber)
+		from, exists := airports[fareFlight.From]
+		if !exists {
+			return nil, fmt.Errorf("Unknown airport code %s", fareFlight.From)
+		}
+		to, exists := airports[fareFlight.To]
+		if !exists {
+			return nil, fmt.Errorf("Unknown airport code %s", fareFlight.To)
+		}
+
+		journey.Flights = append(journey.Flights, &domain.Flight{
+			ID: fmt.Sprintf("%s-%d", id, index+1),
+			FlightNumber: &domain.FlightNumber{
+				CarrierCode:  flightNumber[1],
+				FlightNumber: flightNumber[2],
+			},
+			StartAirport:       &from,
+			StartTime:          times[0],
+			DestinationAirport: &to,
+			DestinationTime:    times[1],
+			Duration:           times[1].Sub(times[0]),
+		})
+	}
+
+	journey.StartTime = journey.Flights[0].StartTime
+	journey.EndTime = journey.Flights[len(journey.Flights)-1].DestinationTime
+	journey.Duration = journey.EndTime.Sub(journey.StartTime)
+	return &journey, nil
+}
+
+// times returns the departure and arrival times of the flight.
+func (flight *FareFlight) times() ([2]time.Time, error) {
+	var times [2]time.Time
+	for index, value := range []string{flight.Departure, flight.Arrival} {
+		parsed, err := time.Parse(fareTimeFormat, value)
+		if err != nil {
+			return times, fmt.Errorf("Invalid time %s of flight %s", value, flight.FlightNumber)
+		}
+		times[index] = parsed
+	}
+	if times[1].Before(times[0]) {
+		return times, fmt.Errorf("Flight %s arrives before it departs", flight.FlightNumber)
+	}
+	return times, nil
+}
+
+// readFareCSV reads fares from a CSV file, with a header row naming the columns.
+func readFareCSV(reader io.Reader, filename string) ([]Fare, error) {
+	table, err := newCSVTable(reader, filename, "origin", "destination", "outbound_date", "adults", "supplier",
+		"amount", "currency", "outbound_flights")
+	if err != nil {
+		return nil, err
+	}
+
+	fares := []Fare{}
+	for {
+		found, err := table.next()
+		if err != nil {
+			return nil, err
+		} else if !found {
+			break
+		}
+
+		fare := Fare{
+			Origin:       table.value("origin"),
+			Destination:  table.value("destination"),
+			ReturnOrigin: table.value("return_origin"),
+			OutboundDate: table.value("outbound_date"),
+			InboundDate:  table.value("inbound_date"),
+			CabinClass:   table.value("cabin_class"),
+			Supplier:     table.value("supplier"),
+			SupplierType: table.value("supplier_type"),
+			Currency:     table.value("currency"),
+			DeeplinkURL:  table.value("deeplink_url"),
+		}
+		fare.Amount, err = strconv.Atoi(table.value("amount"))
+		if err != nil {
+			return nil, table.errorf("amount", "Invalid amount %s", table.value("amount"))
+		}
+		passengers := []*int{&fare.Adults, &fare.Children, &fare.Infants}
+		for index, column := range []string{"adults", "children", "infants"} {
+			*passengers[index], err = parsePassengers(table, column)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, column := range []string{"outbound_flights", "inbound_flights"} {
+			flights, err := parseFareFlights(table.value(column))
+			if err != nil {
+				return nil, table.errorf(column, "%s", err)
+			}
+			if column == "outbound_flights" {
+				fare.Outbound = flights
+			} else {
+				fare.Inbound = flights
+			}
+		}
+		fares = append(fares, fare)
+	}
+	return fares, nil
+}
+
+// parsePassengers returns the number of passengers in the column of the current row, or 0 if it is empty.
+func parsePassengers(table *csvTable, column string) (int, error) {
+	value := table.value(column)
+	if value == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, table.errorf(column, "Invalid number of %s %s", column, value)
+	}
+	return count, nil
+}
+
+// parseFareFlights parses flights separated by ";", e.g. "BA123 LHR 2019-11-01T08:30 JFK 2019-11-01T11:30".
+func parseFareFlights(value string) ([]FareFlight, error) {
+	flights := []FareFlight{}
+	if value == "" {
+		return flights, nil
+	}
+	for _, entry := range strings.Split(value, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("Invalid flight %s, expected e.g. BA123 LHR 2019-11-01T08:30 JFK 2019-11-01T11:30",
+				strings.TrimSpace(entry))
+		}
+		flights = append(flights, FareFlight{FlightNumber: fields[0], From: fields[1], Departure: fields[2],
+			To: fields[3], Arrival: fields[4]})
+	}
+	return flights, nil
+}
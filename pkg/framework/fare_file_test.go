@@ -0,0 +1,168 @@
+package framework
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const fareCSV = `origin,destination,outbound_date,inbound_date,adults,children,supplier,supplier_type,amount,currency,outbound_flights,inbound_flights
+CODE1,CODE2,2019-11-01,2019-11-10,2,2,Carrier 1,Airline,12345,GBP,CA1123 CODE1 2019-11-01T08:30 CODE3 2019-11-01T09:30;CA1124 CODE3 2019-11-01T11:00 CODE2 2019-11-01T12:15,CA1125 CODE2 2019-11-10T10:00 CODE1 2019-11-10T12:00
+CODE1,CODE2,2019-11-01,2019-11-10,2,2,Carrier 2,Airline,9999,USD,CA2123 CODE1 2019-11-01T08:30 CODE2 2019-11-01T09:30,CA2124 CODE2 2019-11-10T08:30 CODE1 2019-11-10T09:30
+CODE1,CODE2,2019-11-01,,2,2,Carrier 2,Airline,5000,GBP,CA2123 CODE1 2019-11-01T08:30 CODE2 2019-11-01T09:30,
+CODE1,CODE2,2019-11-01,2019-11-10,1,,Carrier 2,Airline,3000,GBP,CA2123 CODE1 2019-11-01T08:30 CODE2 2019-11-01T09:30,CA2124 CODE2 2019-11-10T08:30 CODE1 2019-11-10T09:30
+`
+
+const fareJSON = `[{"Origin": "CODE1", "Destination": "CODE2", "OutboundDate": "2019-11-01", "Adults": 2,
+	"Children": 2, "Supplier": "Carrier 1", "Amount": 5000, "Currency": "GBP", "DeeplinkURL": "http://test.com/book",
+	"Outbound": [{"FlightNumber": "CA1123", "From": "CODE1", "Departure": "2019-11-01T08:30", "To": "CODE2",
+		"Arrival": "2019-11-01T09:30"}]}]`
+
+// writeFareFile writes the fares to a temporary file with the extension, returning its name and a function to remove
+// it.
+func writeFareFile(t *testing.T, extension string, fares string) (string, func()) {
+	dir, err := ioutil.TempDir("", "fares")
+	assert.Nil(t, err, "No error expected")
+	filename := filepath.Join(dir, "fares"+extension)
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(fares), 0644), "No error expected")
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+// newTestFareFileProvider returns a provider of the fares, in a file with the extension.
+func newTestFareFileProvider(t *testing.T, extension string, fares string) (*FareFileProvider, error) {
+	filename, remove := writeFareFile(t, extension, fares)
+	defer remove()
+
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	return NewFareFileProvider(mockLogger, filename)
+}
+
+// TestFareFileProvider_CSV tests quoting the fares of a CSV file for the same trip, passengers and currency.
+func TestFareFileProvider_CSV(t *testing.T) {
+	provider, err := newTestFareFileProvider(t, ".csv", fareCSV)
+	assert.Nil(t, err, "No error expected")
+
+	quote, err := provider.Quote(context.Background(), fakeArguments(), fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, "farefile", provider.Name(), "Wrong name")
+	assert.True(t, quote.Complete, "Expected complete")
+	assert.Len(t, quote.Itineraries, 1, "Wrong number of itineraries")
+
+	itinerary := quote.Itineraries[0]
+	assert.Equal(t, "Carrier 1", itinerary.SupplierName, "Wrong supplier")
+	assert.Equal(t, domain.Money{Amount: 12345, Currency: "GBP"}, itinerary.Price, "Wrong price")
+	assert.Equal(t, FareFileProviderName, itinerary.Provider, "Wrong provider")
+
+	outbound := itinerary.OutboundJourney
+	assert.Len(t, outbound.Flights, 2, "Wrong number of flights")
+	assert.Equal(t, &domain.FlightNumber{CarrierCode: "CA", FlightNumber: "1124"}, outbound.Flights[1].FlightNumber,
+		"Wrong flight number")
+	assert.Equal(t, airport3, *outbound.Flights[0].DestinationAirport, "Wrong airport")
+	assert.Equal(t, 225*time.Minute, outbound.Duration, "Wrong duration")
+	assert.Equal(t, domain.Inbound, itinerary.InboundJourney.Direction, "Wrong direction")
+}
+
+// TestFareFileProvider_Passengers tests only fares for 1 adult match a search without group pricing.
+func TestFareFileProvider_Passengers(t *testing.T) {
+	provider, err := newTestFareFileProvider(t, ".csv", fareCSV)
+	assert.Nil(t, err, "No error expected")
+
+	groupPricing := false
+	arguments := fakeArguments()
+	arguments.GroupPricing = &groupPricing
+	quote, err := provider.Quote(context.Background(), arguments, fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Len(t, quote.Itineraries, 1, "Wrong number of itineraries")
+	assert.Equal(t, domain.Money{Amount: 3000, Currency: "GBP"}, quote.Itineraries[0].Price, "Wrong price")
+
+	arguments = fakeArguments()
+	arguments.Children = 1
+	quote, err = provider.Quote(context.Background(), arguments, fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Empty(t, quote.Itineraries, "Expected no fares for these passengers")
+}
+
+// TestFareFileProvider_JSON tests quoting the fares of a JSON file for a one-way trip.
+func TestFareFileProvider_JSON(t *testing.T) {
+	provider, err := newTestFareFileProvider(t, ".json", fareJSON)
+	assert.Nil(t, err, "No error expected")
+
+	arguments := fakeArguments()
+	quote, err := provider.Quote(context.Background(), arguments, fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Empty(t, quote.Itineraries, "Expected no return trips")
+
+	arguments.TripType = domain.OneWayTrip
+	quote, err = provider.Quote(context.Background(), arguments, fakeAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Len(t, quote.Itineraries, 1, "Wrong number of itineraries")
+	assert.Equal(t, "http://test.com/book", quote.Itineraries[0].DeeplinkURL, "Wrong deeplink")
+	assert.Nil(t, quote.Itineraries[0].InboundJourney, "Expected no inbound journey")
+}
+
+// TestFareFileProvider_UnknownAirport tests quoting a fare with a flight to an unknown airport.
+func TestFareFileProvider_UnknownAirport(t *testing.T) {
+	provider, err := newTestFareFileProvider(t, ".json", fareJSON)
+	assert.Nil(t, err, "No error expected")
+
+	arguments := fakeArguments()
+	arguments.TripType = domain.OneWayTrip
+	_, err = provider.Quote(context.Background(), arguments, map[string]domain.Airport{airport2.IataCode: airport2})
+	assert.EqualError(t, err, "Invalid fare 1: Unknown airport code CODE1", "Wrong error")
+}
+
+// TestFareFileProvider_Invalid tests reading fare files with missing or invalid values.
+func TestFareFileProvider_Invalid(t *testing.T) {
+	testCases := []struct {
+		extension string
+		fares     string
+		message   string
+	}{
+		{".csv", "origin,destination\n", "fares.csv is missing columns outbound_date, adults, supplier"},
+		{".csv", "origin,destination,outbound_date,adults,supplier,amount,currency,outbound_flights\n" +
+			"CODE1,CODE2,2019-11-01,1,Carrier 1,lots,GBP,CA1123 CODE1 2019-11-01T08:30 CODE2 2019-11-01T09:30\n",
+			"fares.csv line 2, column amount: Invalid amount lots"},
+		{".csv", "origin,destination,outbound_date,adults,supplier,amount,currency,outbound_flights\n" +
+			"CODE1,CODE2,2019-11-01,two,Carrier 1,100,GBP,CA1123 CODE1 2019-11-01T08:30 CODE2 2019-11-01T09:30\n",
+			"fares.csv line 2, column adults: Invalid number of adults two"},
+		{".csv", "origin,destination,outbound_date,adults,supplier,amount,currency,outbound_flights\n" +
+			"CODE1,CODE2,2019-11-01,1,Carrier 1,100,GBP,CA1123 CODE1\n",
+			"fares.csv line 2, column outbound_flights: Invalid flight CA1123 CODE1, expected e.g. BA123 LHR"},
+		{".json", `[{"Origin": "CODE1"}]`, "Missing origin or destination"},
+		{".json", `[{"Origin": "CODE1", "Destination": "CODE2", "Supplier": "Carrier 1", "Amount": 100,
+			"Currency": "GBP", "OutboundDate": "2019-11-01", "Outbound": [{"FlightNumber": "CA1", "From": "CODE1",
+			"Departure": "2019-11-01T08:30", "To": "CODE2", "Arrival": "2019-11-01T09:30"}]}]`,
+			"Invalid passengers 0 adults, 0 children and 0 infants, expected at least 1 adult"},
+		{".json", `[{"Origin": "CODE1", "Destination": "CODE2", "Adults": 1, "Supplier": "Carrier 1", "Amount": 100,
+			"Currency": "GBP", "OutboundDate": "2019-11-01", "Outbound": [{"FlightNumber": "CA1", "From": "CODE1",
+			"Departure": "2019-11-01T08:30", "To": "CODE2", "Arrival": "2019-11-01T07:30"}]}]`,
+			"Flight CA1 arrives before it departs"},
+		{".json", `[{"Origin": "CODE1", "Destination": "CODE2", "Adults": 1, "Supplier": "Carrier 1", "Amount": 100,
+			"Currency": "GBP", "OutboundDate": "2019-11-01", "Outbound": [{"FlightNumber": "BAW123", "From": "CODE1",
+			"Departure": "2019-11-01T08:30", "To": "CODE2", "Arrival": "2019-11-01T09:30"}]}]`,
+			"Invalid flight number BAW123, expected an IATA airline designator then number"},
+		{".json", `[{"Origin": "CODE1", "Destination": "CODE2", "Adults": 1, "Supplier": "Carrier 1", "Amount": 100,
+			"Currency": "GBP", "OutboundDate": "2019-11-01", "Outbound": [{"FlightNumber": "B", "From": "CODE1",
+			"Departure": "2019-11-01T08:30", "To": "CODE2", "Arrival": "2019-11-01T09:30"}]}]`,
+			"Invalid flight number B, expected"},
+		{".json", `{"Origin"`, "unexpected EOF"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := newTestFareFileProvider(t, testCase.extension, testCase.fares)
+		assert.Error(t, err, "Expected an error for %s", testCase.fares)
+		if err != nil {
+			assert.Contains(t, err.Error(), testCase.message, "Wrong error")
+		}
+	}
+}
@@ -2,133 +2,417 @@ package framework
 
 import (
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/chrisnappin/flightchecker/pkg/domain"
 )
 
-// FlightRepository handles CRUD operations on flight data.
-type FlightRepository struct {
-	logger domain.Logger
-	db     *sql.DB
-}
-
-// NewFlightRepository creates a new instance.
-func NewFlightRepository(logger domain.Logger, db *sql.DB) *FlightRepository {
-	return &FlightRepository{logger, db}
-}
-
-// InitialiseSchema populates a blank repository with the schema - ie empty tables.
-func (repo *FlightRepository) InitialiseSchema() error {
-	tables := []string{
-		`CREATE TABLE airport (
+// migrations holds the DDL statements that upgrade the schema to each version, in order. Released migrations must
+// never be changed, only new ones appended.
+var migrations = [][]string{
+	// version 1: airports, quotes and price history
+	{
+		`CREATE TABLE IF NOT EXISTS airport (
 			code TEXT PRIMARY KEY NOT NULL, 
 			name TEXT NOT NULL, 
 			region TEXT NOT NULL, 
 			country TEXT NOT NULL)`,
 
-		`CREATE TABLE flight_number (
-			flight_number TEXT PRIMARY KEY NOT NULL, 
+		`CREATE TABLE IF NOT EXISTS flight_number (
+			carrier_code TEXT NOT NULL,
+			flight_number TEXT NOT NULL, 
 			carrier_name TEXT NOT NULL, 
-			carrier_code TEXT NOT NULL)`,
+			PRIMARY KEY (carrier_code, flight_number))`,
+
+		`CREATE TABLE IF NOT EXISTS quote (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			complete INTEGER NOT NULL)`,
 
-		`CREATE TABLE journey (
-			id TEXT PRIMARY KEY NOT NULL,
-			direction INTEGER NOT NULL CHECK (direction in (1,2)),
+		`CREATE TABLE IF NOT EXISTS journey (
+			quote_id INTEGER NOT NULL,
+			id TEXT NOT NULL,
+			direction INTEGER NOT NULL CHECK (direction in (0,1)),
 			flights INTEGER NOT NULL,
 			duration INTEGER NOT NULL,
 			start_time TEXT NOT NULL,
-			end_time TEXT NOT NULL)`,
+			end_time TEXT NOT NULL,
+			PRIMARY KEY (quote_id, id),
+			FOREIGN KEY (quote_id) REFERENCES quote(id))`,
 
-		`CREATE TABLE flight (
-			id TEXT PRIMARY KEY NOT NULL, 
+		`CREATE TABLE IF NOT EXISTS flight (
+			quote_id INTEGER NOT NULL,
 			journey_id TEXT NOT NULL,
-			flight_number INTEGER NOT NULL, 
+			sequence INTEGER NOT NULL,
+			id TEXT NOT NULL, 
+			carrier_code TEXT NOT NULL,
+			flight_number TEXT NOT NULL, 
 			start_airport TEXT NOT NULL, 
 			start_time TEXT NOT NULL, 
 			dest_airport TEXT NOT NULL, 
 			dest_time TEXT NOT NULL,
 			duration INTEGER NOT NULL,
-			FOREIGN KEY (journey_id) REFERENCES journey(id),
-			FOREIGN KEY (flight_number) REFERENCES flight_number(flight_number),
+			PRIMARY KEY (quote_id, journey_id, sequence),
+			FOREIGN KEY (quote_id, journey_id) REFERENCES journey(quote_id, id),
+			FOREIGN KEY (carrier_code, flight_number) REFERENCES flight_number(carrier_code, flight_number),
 			FOREIGN KEY (start_airport) REFERENCES airport(code),
 			FOREIGN KEY (dest_airport) REFERENCES airport(code))`,
 
-		`CREATE TABLE itinerary (
+		`CREATE TABLE IF NOT EXISTS itinerary (
+			quote_id INTEGER NOT NULL,
+			sequence INTEGER NOT NULL,
 			supplier_name TEXT NOT NULL,
 			supplier_type TEXT NOT NULL,
 			amount INTEGER NOT NULL,
 			outbound_journey TEXT NOT NULL,
 			inbound_journey TEXT NOT NULL,
-			FOREIGN KEY (outbound_journey) REFERENCES journey(id),
-			FOREIGN KEY (inbound_journey) REFERENCES journey(id))`,
+			PRIMARY KEY (quote_id, sequence),
+			FOREIGN KEY (quote_id) REFERENCES quote(id),
+			FOREIGN KEY (quote_id, outbound_journey) REFERENCES journey(quote_id, id),
+			FOREIGN KEY (quote_id, inbound_journey) REFERENCES journey(quote_id, id))`,
+
+		`CREATE TABLE IF NOT EXISTS search_run (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			quote_id INTEGER NOT NULL,
+			created TEXT NOT NULL,
+			origin TEXT NOT NULL,
+			destination TEXT NOT NULL,
+			adults INTEGER NOT NULL,
+			children INTEGER NOT NULL,
+			infants INTEGER NOT NULL,
+			outbound_date TEXT NOT NULL,
+			holiday_duration INTEGER NOT NULL,
+			cheapest INTEGER NOT NULL,
+			median INTEGER NOT NULL,
+			most_expensive INTEGER NOT NULL,
+			FOREIGN KEY (quote_id) REFERENCES quote(id))`,
+
+		`CREATE INDEX IF NOT EXISTS search_run_route ON search_run (origin, destination, outbound_date)`,
+	},
+
+	// version 2: the airports searched from and to by each itinerary
+	{
+		`ALTER TABLE itinerary ADD COLUMN origin TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE itinerary ADD COLUMN destination TEXT NOT NULL DEFAULT ''`,
+	},
+
+	// version 3: one-way and open-jaw trips, so itineraries may have no inbound journey
+	{
+		`CREATE TABLE itinerary_v3 (
+			quote_id INTEGER NOT NULL,
+			sequence INTEGER NOT NULL,
+			origin TEXT NOT NULL,
+			destination TEXT NOT NULL,
+			supplier_name TEXT NOT NULL,
+			supplier_type TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			outbound_journey TEXT NOT NULL,
+			inbound_journey TEXT,
+			PRIMARY KEY (quote_id, sequence),
+			FOREIGN KEY (quote_id) REFERENCES quote(id),
+			FOREIGN KEY (quote_id, outbound_journey) REFERENCES journey(quote_id, id),
+			FOREIGN KEY (quote_id, inbound_journey) REFERENCES journey(quote_id, id))`,
+
+		`INSERT INTO itinerary_v3 (quote_id, sequence, origin, destination, supplier_name, supplier_type, amount,
+			outbound_journey, inbound_journey)
+			SELECT quote_id, sequence, origin, destination, supplier_name, supplier_type, amount,
+			outbound_journey, inbound_journey FROM itinerary`,
+
+		`DROP TABLE itinerary`,
+
+		`ALTER TABLE itinerary_v3 RENAME TO itinerary`,
+
+		`ALTER TABLE search_run ADD COLUMN trip_type TEXT NOT NULL DEFAULT 'return'`,
+
+		`ALTER TABLE search_run ADD COLUMN return_origin TEXT NOT NULL DEFAULT ''`,
+	},
+
+	// version 4: configurable currency, cabin class, country, locale and group pricing (previously always GBP,
+	// economy, GB, en-GB and true)
+	{
+		`ALTER TABLE quote ADD COLUMN currency TEXT NOT NULL DEFAULT 'GBP'`,
+
+		`ALTER TABLE search_run ADD COLUMN cabin_class TEXT NOT NULL DEFAULT 'economy'`,
+
+		`ALTER TABLE search_run ADD COLUMN currency TEXT NOT NULL DEFAULT 'GBP'`,
+
+		`ALTER TABLE search_run ADD COLUMN country TEXT NOT NULL DEFAULT 'GB'`,
+
+		`ALTER TABLE search_run ADD COLUMN locale TEXT NOT NULL DEFAULT 'en-GB'`,
+
+		`ALTER TABLE search_run ADD COLUMN group_pricing INTEGER NOT NULL DEFAULT 1`,
+	},
+
+	// version 5: how to format each currency
+	{
+		`CREATE TABLE IF NOT EXISTS currency (
+			code TEXT PRIMARY KEY NOT NULL,
+			symbol TEXT NOT NULL,
+			thousands_separator TEXT NOT NULL,
+			decimal_separator TEXT NOT NULL,
+			symbol_on_left INTEGER NOT NULL,
+			space_between INTEGER NOT NULL,
+			decimal_digits INTEGER NOT NULL)`,
+	},
+
+	// version 6: where to book each itinerary, and how old its price was when quoted
+	{
+		`ALTER TABLE itinerary ADD COLUMN deeplink_url TEXT NOT NULL DEFAULT ''`,
+
+		`ALTER TABLE itinerary ADD COLUMN quote_age INTEGER NOT NULL DEFAULT 0`,
+	},
+
+	// version 7: the source of each itinerary (previously always sky scanner)
+	{
+		`ALTER TABLE itinerary ADD COLUMN provider TEXT NOT NULL DEFAULT 'skyscanner'`,
+	},
+
+	// version 8: search sessions whose quotes are not yet complete, so interrupted searches can be resumed
+	{
+		`CREATE TABLE IF NOT EXISTS search_session (
+			session_key TEXT PRIMARY KEY NOT NULL,
+			created TEXT NOT NULL,
+			origin TEXT NOT NULL,
+			destination TEXT NOT NULL,
+			trip_type TEXT NOT NULL,
+			return_origin TEXT NOT NULL,
+			adults INTEGER NOT NULL,
+			children INTEGER NOT NULL,
+			infants INTEGER NOT NULL,
+			outbound_date TEXT NOT NULL,
+			holiday_duration INTEGER NOT NULL,
+			cabin_class TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			country TEXT NOT NULL,
+			locale TEXT NOT NULL,
+			group_pricing INTEGER NOT NULL)`,
+	},
+
+	// version 9: more details of each airport, from ourairports.com
+	{
+		`ALTER TABLE airport ADD COLUMN icao_code TEXT NOT NULL DEFAULT ''`,
+
+		`ALTER TABLE airport ADD COLUMN type TEXT NOT NULL DEFAULT ''`,
+
+		`ALTER TABLE airport ADD COLUMN latitude REAL NOT NULL DEFAULT 0`,
+
+		`ALTER TABLE airport ADD COLUMN longitude REAL NOT NULL DEFAULT 0`,
+
+		`ALTER TABLE airport ADD COLUMN municipality TEXT NOT NULL DEFAULT ''`,
+
+		`ALTER TABLE airport ADD COLUMN scheduled_service INTEGER NOT NULL DEFAULT 0`,
+	},
+
+	// version 10: each version of the airport data files downloaded by "airports update"
+	{
+		`CREATE TABLE IF NOT EXISTS airport_dataset (
+			version INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			downloaded TEXT NOT NULL,
+			published TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			countries INTEGER NOT NULL,
+			regions INTEGER NOT NULL,
+			airports INTEGER NOT NULL)`,
+	},
+}
+
+// legacyTables are the tables of the unversioned schema used before migrations, children first. That schema was
+// recreated on every run, so these tables never hold data worth keeping.
+var legacyTables = []string{"itinerary", "flight", "journey", "flight_number", "airport"}
+
+// FlightRepository handles CRUD operations on flight data.
+type FlightRepository struct {
+	logger domain.Logger
+	db     *sql.DB
+}
+
+// NewFlightRepository creates a new instance.
+func NewFlightRepository(logger domain.Logger, db *sql.DB) *FlightRepository {
+	return &FlightRepository{logger, db}
+}
+
+// SchemaVersion returns the version of the repository schema, which is zero for a blank repository.
+func (repo *FlightRepository) SchemaVersion() (int, error) {
+	var version int
+	err := repo.db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// LatestSchemaVersion returns the version of the schema that MigrateSchema upgrades to.
+func (repo *FlightRepository) LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// MigrateSchema upgrades the repository to the latest schema, applying each migration not yet applied in turn.
+// A blank repository is populated with the whole schema - ie empty tables. The tables of a repository created before
+// schema versions were recorded are dropped first.
+func (repo *FlightRepository) MigrateSchema() error {
+	version, err := repo.SchemaVersion()
+	if err != nil {
+		return err
 	}
-	for _, table := range tables {
-		err := repo.executeDDLStatement(table)
+	if version > len(migrations) {
+		return fmt.Errorf("Schema version %d is newer than this program supports (%d)", version, len(migrations))
+	}
+	if version == 0 {
+		err = repo.dropLegacySchema()
+		if err != nil {
+			return err
+		}
+	}
+
+	for index := version; index < len(migrations); index++ {
+		repo.logger.Debugf("Migrating schema to version %d", index+1)
+		err = repo.executeMigration(migrations[index], index+1)
 		if err != nil {
-			repo.logger.Errorf("Error %s when executing table DDL statement: %s", err, table)
 			return err
 		}
 	}
 	return nil
 }
 
-// executeDDLStatement runs a single DDL statement.
-func (repo *FlightRepository) executeDDLStatement(ddl string) error {
+// dropLegacySchema drops any tables of the unversioned schema, whose data was deleted on every run anyway, since
+// they don't match the tables of the first migration.
+func (repo *FlightRepository) dropLegacySchema() error {
 	_, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
-		statement, err := repo.db.Prepare(ddl)
-		if err != nil {
-			return nil, err
-		}
-		_, err = statement.Exec()
-		if err != nil {
-			return nil, err
+		for _, table := range legacyTables {
+			_, err := tx.Exec("DROP TABLE IF EXISTS " + table)
+			if err != nil {
+				return nil, err
+			}
 		}
 		return nil, nil
 	})
 	return err
 }
 
-// CreateAirports inserts all specified airports into the repository.
+// executeMigration runs the DDL statements of a single migration then records the new version, in one transaction.
+func (repo *FlightRepository) executeMigration(statements []string, version int) error {
+	_, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		for _, ddl := range statements {
+			_, err := tx.Exec(ddl)
+			if err != nil {
+				repo.logger.Errorf("Error %s when executing DDL statement: %s", err, ddl)
+				return nil, err
+			}
+		}
+		_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+		return nil, err
+	})
+	return err
+}
+
+// CreateAirports inserts all specified airports into the repository, replacing any with the same code.
 func (repo *FlightRepository) CreateAirports(airports []domain.Airport) error {
 	_, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
-		// inserts all values in the array, in one transaction
-		statement, err := tx.Prepare("INSERT INTO airport (code, name, region, country) VALUES (?, ?, ?, ?)")
+		return nil, insertAirports(tx, airports)
+	})
+	return err
+}
+
+// ImportAirports replaces the airports in the repository with those imported, and records the dataset they came from
+// (if not nil), in one transaction. Airports that are no longer imported are deleted, unless a saved flight refers to
+// them. The dataset version is set to the generated version.
+func (repo *FlightRepository) ImportAirports(airports []domain.Airport, dataset *domain.AirportDataset) error {
+	version, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		err := insertAirports(tx, airports)
 		if err != nil {
 			return nil, err
 		}
 
+		imported := make(map[string]bool)
 		for _, airport := range airports {
-			_, err = statement.Exec(airport.IataCode, airport.Name, airport.Region, airport.Country)
+			imported[airport.IataCode] = true
+		}
+		codes, err := readAirportCodes(tx)
+		if err != nil {
+			return nil, err
+		}
+		for _, code := range codes {
+			if imported[code] {
+				continue
+			}
+			_, err = tx.Exec("DELETE FROM airport WHERE code = ? AND NOT EXISTS (SELECT 1 FROM flight "+
+				"WHERE start_airport = ? OR dest_airport = ?)", code, code, code)
 			if err != nil {
 				return nil, err
 			}
 		}
-		return nil, nil
+
+		if dataset == nil {
+			return int64(0), nil
+		}
+		return insertAirportDataset(tx, dataset)
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	if dataset != nil {
+		dataset.Version = version.(int64)
+	}
+	return nil
+}
+
+// insertAirports inserts the airports, replacing any with the same code.
+func insertAirports(tx *sql.Tx, airports []domain.Airport) error {
+	statement, err := tx.Prepare("INSERT OR REPLACE INTO airport (code, name, region, country, icao_code, type, " +
+		"latitude, longitude, municipality, scheduled_service) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, airport := range airports {
+		_, err = statement.Exec(airport.IataCode, airport.Name, airport.Region, airport.Country, airport.IcaoCode,
+			airport.Type, airport.Latitude, airport.Longitude, airport.Municipality, airport.ScheduledService)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// ReadAllAirports reads all airports from the repository.
+// readAirportCodes reads the code of every airport.
+func readAirportCodes(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query("SELECT code FROM airport")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []string{}
+	for rows.Next() {
+		var code string
+		err = rows.Scan(&code)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// ReadAllAirports reads all airports from the repository, ordered by code.
 func (repo *FlightRepository) ReadAllAirports() ([]domain.Airport, error) {
 	airports, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
-		rows, err := tx.Query("SELECT code, name, region, country FROM airport")
+		rows, err := tx.Query("SELECT " + airportColumns + " FROM airport ORDER BY code")
 		if err != nil {
 			return nil, err
 		}
+		defer rows.Close()
+
 		airports := make([]domain.Airport, 0)
-		var code string
-		var name string
-		var region string
-		var country string
 		for rows.Next() {
-			err = rows.Scan(&code, &name, &region, &country)
+			airport := domain.Airport{}
+			err = scanAirport(rows, &airport)
 			if err != nil {
 				return nil, err
 			}
-			airports = append(airports, domain.Airport{Name: name, IataCode: code, Country: country, Region: region})
-			repo.logger.Infof("%s %s %s %s\n", code, name, region, country)
+			airports = append(airports, airport)
 		}
-		return airports, nil
+		repo.logger.Debugf("Read %d airports", len(airports))
+		return airports, rows.Err()
 	})
 	if err != nil {
 		return nil, err
@@ -136,6 +420,571 @@ func (repo *FlightRepository) ReadAllAirports() ([]domain.Airport, error) {
 	return airports.([]domain.Airport), nil
 }
 
+// SaveQuote inserts the quote, with all of its itineraries, journeys, flights and flight numbers, into the
+// repository in one transaction. The quote ID is set to the generated id.
+func (repo *FlightRepository) SaveQuote(quote *domain.Quote) error {
+	id, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		result, err := tx.Exec("INSERT INTO quote (currency, complete) VALUES (?, ?)", quote.Currency, quote.Complete)
+		if err != nil {
+			return nil, err
+		}
+		quoteID, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+
+		if quote.CurrencyFormat != nil {
+			err = repo.saveCurrencyFormat(tx, quote.CurrencyFormat)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for index, itinerary := range quote.Itineraries {
+			inboundID := sql.NullString{}
+			if itinerary.InboundJourney != nil {
+				inboundID = sql.NullString{String: itinerary.InboundJourney.ID, Valid: true}
+			}
+
+			for _, journey := range []*domain.Journey{itinerary.OutboundJourney, itinerary.InboundJourney} {
+				if journey == nil {
+					continue
+				}
+				err = repo.insertJourney(tx, quoteID, journey)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			_, err = tx.Exec("INSERT INTO itinerary (quote_id, sequence, origin, destination, provider, "+
+				"supplier_name, supplier_type, amount, deeplink_url, quote_age, outbound_journey, inbound_journey) "+
+				"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				quoteID, index, itinerary.Origin, itinerary.Destination, itinerary.Provider, itinerary.SupplierName,
+				itinerary.SupplierType, itinerary.Price.Amount, itinerary.DeeplinkURL,
+				int(itinerary.QuoteAge.Minutes()), itinerary.OutboundJourney.ID, inboundID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return quoteID, nil
+	})
+	if err != nil {
+		return err
+	}
+	quote.ID = id.(int64)
+	return nil
+}
+
+// saveCurrencyFormat inserts the currency format, replacing any with the same code.
+func (repo *FlightRepository) saveCurrencyFormat(tx *sql.Tx, format *domain.CurrencyFormat) error {
+	_, err := tx.Exec("INSERT OR REPLACE INTO currency (code, symbol, thousands_separator, decimal_separator, "+
+		"symbol_on_left, space_between, decimal_digits) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		format.Code, format.Symbol, format.ThousandsSeparator, format.DecimalSeparator, format.SymbolOnLeft,
+		format.SpaceBetweenAmountAndSymbol, format.DecimalDigits)
+	return err
+}
+
+// ReadCurrencyFormat reads the format of the currency with the specified code, or nil if not known.
+func (repo *FlightRepository) ReadCurrencyFormat(code string) (*domain.CurrencyFormat, error) {
+	format, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		return repo.readCurrencyFormat(tx, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.(*domain.CurrencyFormat), nil
+}
+
+// readCurrencyFormat reads the format of a currency within the specified transaction, or nil if not known.
+func (repo *FlightRepository) readCurrencyFormat(tx *sql.Tx, code string) (*domain.CurrencyFormat, error) {
+	format := domain.CurrencyFormat{Code: code}
+	err := tx.QueryRow("SELECT symbol, thousands_separator, decimal_separator, symbol_on_left, space_between, "+
+		"decimal_digits FROM currency WHERE code = ?", code).Scan(&format.Symbol, &format.ThousandsSeparator,
+		&format.DecimalSeparator, &format.SymbolOnLeft, &format.SpaceBetweenAmountAndSymbol, &format.DecimalDigits)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &format, nil
+}
+
+// insertJourney inserts the journey and its flights, unless already saved for this quote (journeys are typically
+// shared by several itineraries).
+func (repo *FlightRepository) insertJourney(tx *sql.Tx, quoteID int64, journey *domain.Journey) error {
+	result, err := tx.Exec("INSERT OR IGNORE INTO journey (quote_id, id, direction, flights, duration, start_time, "+
+		"end_time) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		quoteID, journey.ID, journey.Direction, len(journey.Flights), int(journey.Duration.Minutes()),
+		journey.StartTime.Format(time.RFC3339), journey.EndTime.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return nil
+	}
+
+	for index, flight := range journey.Flights {
+		// flight numbers are shared by every quote, so a flight without a carrier name (e.g. from a fare file)
+		// keeps the name already saved
+		_, err = tx.Exec("INSERT INTO flight_number (carrier_code, flight_number, carrier_name) VALUES (?, ?, ?) "+
+			"ON CONFLICT (carrier_code, flight_number) DO UPDATE SET carrier_name = excluded.carrier_name "+
+			"WHERE excluded.carrier_name != ''",
+			flight.FlightNumber.CarrierCode, flight.FlightNumber.FlightNumber, flight.FlightNumber.CarrierName)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO flight (quote_id, journey_id, sequence, id, carrier_code, flight_number, "+
+			"start_airport, start_time, dest_airport, dest_time, duration) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			quoteID, journey.ID, index, flight.ID, flight.FlightNumber.CarrierCode, flight.FlightNumber.FlightNumber,
+			flight.StartAirport.IataCode, flight.StartTime.Format(time.RFC3339),
+			flight.DestinationAirport.IataCode, flight.DestinationTime.Format(time.RFC3339),
+			int(flight.Duration.Minutes()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadQuote reads the quote with the specified id, with all of its itineraries, from the repository.
+func (repo *FlightRepository) ReadQuote(id int64) (*domain.Quote, error) {
+	quote, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		return repo.readQuote(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quote.(*domain.Quote), nil
+}
+
+// ReadAllQuotes reads all quotes from the repository, oldest first.
+func (repo *FlightRepository) ReadAllQuotes() ([]*domain.Quote, error) {
+	quotes, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		rows, err := tx.Query("SELECT id FROM quote ORDER BY id")
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, 0)
+		var id int64
+		for rows.Next() {
+			err = rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+
+		quotes := make([]*domain.Quote, 0)
+		for _, id := range ids {
+			quote, err := repo.readQuote(tx, id)
+			if err != nil {
+				return nil, err
+			}
+			quotes = append(quotes, quote)
+		}
+		return quotes, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quotes.([]*domain.Quote), nil
+}
+
+// readQuote rebuilds a single quote, within the specified transaction.
+func (repo *FlightRepository) readQuote(tx *sql.Tx, id int64) (*domain.Quote, error) {
+	quote := domain.Quote{ID: id, Itineraries: []*domain.Itinerary{}}
+	err := tx.QueryRow("SELECT currency, complete FROM quote WHERE id = ?", id).Scan(&quote.Currency, &quote.Complete)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("Unknown quote id %d", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	quote.CurrencyFormat, err = repo.readCurrencyFormat(tx, quote.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	journeys, err := repo.readJourneys(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT origin, destination, provider, supplier_name, supplier_type, amount, "+
+		"deeplink_url, quote_age, outbound_journey, inbound_journey FROM itinerary WHERE quote_id = ? "+
+		"ORDER BY sequence", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var quoteAge int
+	var outboundID string
+	var inboundID sql.NullString // null for one-way trips
+	for rows.Next() {
+		itinerary := domain.Itinerary{}
+		err = rows.Scan(&itinerary.Origin, &itinerary.Destination, &itinerary.Provider, &itinerary.SupplierName,
+			&itinerary.SupplierType, &itinerary.Price.Amount, &itinerary.DeeplinkURL, &quoteAge, &outboundID,
+			&inboundID)
+		if err != nil {
+			return nil, err
+		}
+		itinerary.Price.Currency = quote.Currency
+		itinerary.QuoteAge = time.Duration(quoteAge) * time.Minute
+		itinerary.OutboundJourney = journeys[outboundID]
+		if inboundID.Valid {
+			itinerary.InboundJourney = journeys[inboundID.String]
+		}
+		quote.Itineraries = append(quote.Itineraries, &itinerary)
+	}
+	return &quote, rows.Err()
+}
+
+// readJourneys returns a map of all journeys (with their flights) of a quote, keyed by journey id.
+func (repo *FlightRepository) readJourneys(tx *sql.Tx, quoteID int64) (map[string]*domain.Journey, error) {
+	rows, err := tx.Query("SELECT id, direction, duration, start_time, end_time FROM journey WHERE quote_id = ?",
+		quoteID)
+	if err != nil {
+		return nil, err
+	}
+	journeys := make(map[string]*domain.Journey)
+	var duration int
+	var startTime string
+	var endTime string
+	for rows.Next() {
+		journey := domain.Journey{Flights: []*domain.Flight{}}
+		err = rows.Scan(&journey.ID, &journey.Direction, &duration, &startTime, &endTime)
+		if err == nil {
+			journey.Duration = time.Duration(duration) * time.Minute
+			journey.StartTime, err = time.Parse(time.RFC3339, startTime)
+		}
+		if err == nil {
+			journey.EndTime, err = time.Parse(time.RFC3339, endTime)
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		journeys[journey.ID] = &journey
+	}
+	rows.Close()
+
+	rows, err = tx.Query("SELECT f.journey_id, f.id, f.carrier_code, f.flight_number, n.carrier_name, "+
+		"f.start_airport, f.start_time, f.dest_airport, f.dest_time, f.duration "+
+		"FROM flight f JOIN flight_number n ON f.carrier_code = n.carrier_code AND f.flight_number = n.flight_number "+
+		"WHERE f.quote_id = ? ORDER BY f.journey_id, f.sequence", quoteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	airports := make(map[string]*domain.Airport)
+	var journeyID string
+	var startAirport string
+	var destAirport string
+	var destTime string
+	for rows.Next() {
+		flight := domain.Flight{FlightNumber: &domain.FlightNumber{}}
+		err = rows.Scan(&journeyID, &flight.ID, &flight.FlightNumber.CarrierCode, &flight.FlightNumber.FlightNumber,
+			&flight.FlightNumber.CarrierName, &startAirport, &startTime, &destAirport, &destTime, &duration)
+		if err != nil {
+			return nil, err
+		}
+		flight.Duration = time.Duration(duration) * time.Minute
+		flight.StartTime, err = time.Parse(time.RFC3339, startTime)
+		if err != nil {
+			return nil, err
+		}
+		flight.DestinationTime, err = time.Parse(time.RFC3339, destTime)
+		if err != nil {
+			return nil, err
+		}
+		flight.StartAirport, err = repo.readAirport(tx, airports, startAirport)
+		if err != nil {
+			return nil, err
+		}
+		flight.DestinationAirport, err = repo.readAirport(tx, airports, destAirport)
+		if err != nil {
+			return nil, err
+		}
+
+		journey, exists := journeys[journeyID]
+		if !exists {
+			return nil, fmt.Errorf("Unknown journey id %s", journeyID)
+		}
+		journey.Flights = append(journey.Flights, &flight)
+	}
+	return journeys, rows.Err()
+}
+
+// readAirport returns the airport with the specified code, using the cache where possible. Codes not in the airport
+// table result in an airport with just the code populated.
+func (repo *FlightRepository) readAirport(tx *sql.Tx, cache map[string]*domain.Airport, code string) (
+	*domain.Airport, error) {
+	airport, exists := cache[code]
+	if exists {
+		return airport, nil
+	}
+
+	airport = &domain.Airport{IataCode: code}
+	err := scanAirport(tx.QueryRow("SELECT "+airportColumns+" FROM airport WHERE code = ?", code), airport)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	cache[code] = airport
+	return airport, nil
+}
+
+// airportColumns are the columns of the airport table read by scanAirport.
+const airportColumns = "code, name, region, country, icao_code, type, latitude, longitude, municipality, " +
+	"scheduled_service"
+
+// rowScanner reads the columns of a row, and is implemented by both sql.Row and sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAirport reads the airport columns of a row into the airport.
+func scanAirport(row rowScanner, airport *domain.Airport) error {
+	return row.Scan(&airport.IataCode, &airport.Name, &airport.Region, &airport.Country, &airport.IcaoCode,
+		&airport.Type, &airport.Latitude, &airport.Longitude, &airport.Municipality, &airport.ScheduledService)
+}
+
+// SaveSearchRun inserts the search run into the repository. The search run ID is set to the generated id.
+func (repo *FlightRepository) SaveSearchRun(run *domain.SearchRun) error {
+	id, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		result, err := tx.Exec("INSERT INTO search_run (quote_id, created, origin, destination, trip_type, "+
+			"return_origin, adults, children, infants, outbound_date, holiday_duration, cabin_class, currency, "+
+			"country, locale, group_pricing, cheapest, median, most_expensive) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			run.QuoteID, run.Created.UTC().Format(time.RFC3339), run.Arguments.Origin, run.Arguments.Destination,
+			run.Arguments.Trip(), run.Arguments.ReturnOrigin, run.Arguments.Adults, run.Arguments.Children,
+			run.Arguments.Infants, run.Arguments.OutboundDate, run.Arguments.HolidayDuration,
+			run.Arguments.CabinClass, run.Arguments.Currency, run.Arguments.Country, run.Arguments.Locale,
+			groupPricing(&run.Arguments), run.Cheapest, run.Median, run.MostExpensive)
+		if err != nil {
+			return nil, err
+		}
+		return result.LastInsertId()
+	})
+	if err != nil {
+		return err
+	}
+	run.ID = id.(int64)
+	return nil
+}
+
+// ReadPriceHistory reads all search runs with the same search criteria as the arguments, oldest first.
+func (repo *FlightRepository) ReadPriceHistory(arguments *domain.Arguments) ([]*domain.SearchRun, error) {
+	return repo.readSearchRuns(sameSearchClause+"ORDER BY created, id", sameSearchArgs(arguments)...)
+}
+
+// ReadLatestSearchRun reads the most recent search run with the same search criteria as the arguments, or returns
+// nil if there isn't one.
+func (repo *FlightRepository) ReadLatestSearchRun(arguments *domain.Arguments) (*domain.SearchRun, error) {
+	runs, err := repo.readSearchRuns(sameSearchClause+"ORDER BY created DESC, id DESC LIMIT 1",
+		sameSearchArgs(arguments)...)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return runs[0], nil
+}
+
+// sameSearchClause selects the search runs with the same search criteria (everything that affects the prices found)
+// as the arguments given by sameSearchArgs.
+const sameSearchClause = "WHERE origin = ? AND destination = ? AND trip_type = ? AND return_origin = ? " +
+	"AND adults = ? AND children = ? AND infants = ? AND outbound_date = ? AND holiday_duration = ? " +
+	"AND cabin_class = ? AND currency = ? AND country = ? AND group_pricing = ? "
+
+// sameSearchArgs returns the values of sameSearchClause for the arguments.
+func sameSearchArgs(arguments *domain.Arguments) []interface{} {
+	return []interface{}{arguments.Origin, arguments.Destination, arguments.Trip(), arguments.ReturnOrigin,
+		arguments.Adults, arguments.Children, arguments.Infants, arguments.OutboundDate, arguments.Nights(),
+		arguments.CabinClass, arguments.Currency, arguments.Country, groupPricing(arguments)}
+}
+
+// readSearchRuns reads the search runs selected by the where and order by clauses.
+func (repo *FlightRepository) readSearchRuns(clauses string, args ...interface{}) ([]*domain.SearchRun, error) {
+	runs, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		rows, err := tx.Query("SELECT id, quote_id, created, origin, destination, trip_type, return_origin, adults, "+
+			"children, infants, outbound_date, holiday_duration, cabin_class, currency, country, locale, "+
+			"group_pricing, cheapest, median, most_expensive FROM search_run "+clauses, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		runs := make([]*domain.SearchRun, 0)
+		var created string
+		for rows.Next() {
+			run := domain.SearchRun{}
+			run.Arguments.GroupPricing = new(bool)
+			err = rows.Scan(&run.ID, &run.QuoteID, &created, &run.Arguments.Origin, &run.Arguments.Destination,
+				&run.Arguments.TripType, &run.Arguments.ReturnOrigin, &run.Arguments.Adults, &run.Arguments.Children,
+				&run.Arguments.Infants, &run.Arguments.OutboundDate, &run.Arguments.HolidayDuration,
+				&run.Arguments.CabinClass, &run.Arguments.Currency, &run.Arguments.Country, &run.Arguments.Locale,
+				run.Arguments.GroupPricing, &run.Cheapest, &run.Median, &run.MostExpensive)
+			if err != nil {
+				return nil, err
+			}
+			run.Created, err = time.Parse(time.RFC3339, created)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, &run)
+		}
+		return runs, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs.([]*domain.SearchRun), nil
+}
+
+// SaveSearchSession inserts the search session into the repository, replacing any with the same session key.
+func (repo *FlightRepository) SaveSearchSession(session *domain.SearchSession) error {
+	_, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		return tx.Exec("INSERT OR REPLACE INTO search_session (session_key, created, origin, destination, trip_type, "+
+			"return_origin, adults, children, infants, outbound_date, holiday_duration, cabin_class, currency, "+
+			"country, locale, group_pricing) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			session.SessionKey, session.Created.UTC().Format(time.RFC3339), session.Arguments.Origin,
+			session.Arguments.Destination, session.Arguments.Trip(), session.Arguments.ReturnOrigin,
+			session.Arguments.Adults, session.Arguments.Children, session.Arguments.Infants,
+			session.Arguments.OutboundDate, session.Arguments.HolidayDuration, session.Arguments.CabinClass,
+			session.Arguments.Currency, session.Arguments.Country, session.Arguments.Locale,
+			groupPricing(&session.Arguments))
+	})
+	return err
+}
+
+// ReadSearchSessions reads all search sessions, oldest first.
+func (repo *FlightRepository) ReadSearchSessions() ([]*domain.SearchSession, error) {
+	sessions, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		rows, err := tx.Query("SELECT session_key, created, origin, destination, trip_type, return_origin, adults, " +
+			"children, infants, outbound_date, holiday_duration, cabin_class, currency, country, locale, " +
+			"group_pricing FROM search_session ORDER BY created, session_key")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		sessions := make([]*domain.SearchSession, 0)
+		var created string
+		for rows.Next() {
+			session := domain.SearchSession{}
+			session.Arguments.GroupPricing = new(bool)
+			err = rows.Scan(&session.SessionKey, &created, &session.Arguments.Origin, &session.Arguments.Destination,
+				&session.Arguments.TripType, &session.Arguments.ReturnOrigin, &session.Arguments.Adults,
+				&session.Arguments.Children, &session.Arguments.Infants, &session.Arguments.OutboundDate,
+				&session.Arguments.HolidayDuration, &session.Arguments.CabinClass, &session.Arguments.Currency,
+				&session.Arguments.Country, &session.Arguments.Locale, session.Arguments.GroupPricing)
+			if err != nil {
+				return nil, err
+			}
+			session.Created, err = time.Parse(time.RFC3339, created)
+			if err != nil {
+				return nil, err
+			}
+			sessions = append(sessions, &session)
+		}
+		return sessions, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions.([]*domain.SearchSession), nil
+}
+
+// DeleteSearchSession deletes the search session with the session key, if there is one.
+func (repo *FlightRepository) DeleteSearchSession(sessionKey string) error {
+	_, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		return tx.Exec("DELETE FROM search_session WHERE session_key = ?", sessionKey)
+	})
+	return err
+}
+
+// SaveAirportDataset inserts the airport dataset into the repository. The dataset version is set to the generated
+// version, which is higher than that of any dataset saved before.
+func (repo *FlightRepository) SaveAirportDataset(dataset *domain.AirportDataset) error {
+	version, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		return insertAirportDataset(tx, dataset)
+	})
+	if err != nil {
+		return err
+	}
+	dataset.Version = version.(int64)
+	return nil
+}
+
+// insertAirportDataset inserts the airport dataset, returning its generated version.
+func insertAirportDataset(tx *sql.Tx, dataset *domain.AirportDataset) (int64, error) {
+	published := ""
+	if !dataset.Published.IsZero() {
+		published = dataset.Published.UTC().Format(time.RFC3339)
+	}
+	result, err := tx.Exec("INSERT INTO airport_dataset (source, downloaded, published, checksum, countries, "+
+		"regions, airports) VALUES (?, ?, ?, ?, ?, ?, ?)", dataset.Source,
+		dataset.Downloaded.UTC().Format(time.RFC3339), published, dataset.Checksum, dataset.Countries,
+		dataset.Regions, dataset.Airports)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ReadLatestAirportDataset reads the airport dataset with the highest version, or returns nil if there isn't one.
+func (repo *FlightRepository) ReadLatestAirportDataset() (*domain.AirportDataset, error) {
+	dataset, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		dataset := domain.AirportDataset{}
+		var downloaded, published string
+		err := tx.QueryRow("SELECT version, source, downloaded, published, checksum, countries, regions, airports "+
+			"FROM airport_dataset ORDER BY version DESC LIMIT 1").Scan(&dataset.Version, &dataset.Source,
+			&downloaded, &published, &dataset.Checksum, &dataset.Countries, &dataset.Regions, &dataset.Airports)
+		if err == sql.ErrNoRows {
+			return (*domain.AirportDataset)(nil), nil
+		} else if err != nil {
+			return nil, err
+		}
+		dataset.Downloaded, err = time.Parse(time.RFC3339, downloaded)
+		if err != nil {
+			return nil, err
+		}
+		if published != "" {
+			dataset.Published, err = time.Parse(time.RFC3339, published)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &dataset, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dataset.(*domain.AirportDataset), nil
+}
+
+// DeleteAirportDataset deletes the airport dataset with the version, if there is one.
+func (repo *FlightRepository) DeleteAirportDataset(version int64) error {
+	_, err := withTransaction(repo.db, func(tx *sql.Tx) (interface{}, error) {
+		return tx.Exec("DELETE FROM airport_dataset WHERE version = ?", version)
+	})
+	return err
+}
+
+// groupPricing returns whether the arguments price for all passengers, which is the default if not set.
+func groupPricing(arguments *domain.Arguments) bool {
+	if arguments.GroupPricing == nil {
+		return domain.DefaultGroupPricing
+	}
+	return *arguments.GroupPricing
+}
+
 // withTransaction starts a transaction, passes it to a callback, then commits or rolls it back based on if an error is
 // returned from the callback function.
 func withTransaction(db *sql.DB, callback func(transaction *sql.Tx) (interface{}, error)) (interface{}, error) {
@@ -0,0 +1,485 @@
+package framework
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/mocks"
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestRepository returns a repository backed by an in-memory database, with the latest schema.
+func newTestRepository(t *testing.T) (*FlightRepository, *sql.DB) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err, "Error not expected")
+	db.SetMaxOpenConns(1) // each connection would otherwise get its own in-memory database
+
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything).Maybe()
+	repo := NewFlightRepository(mockLogger, db)
+	assert.Nil(t, repo.MigrateSchema(), "Error not expected")
+	return repo, db
+}
+
+// getExampleQuote returns a quote with two itineraries that share the same journeys.
+func getExampleQuote() *domain.Quote {
+	outbound := &domain.Journey{
+		ID:        "leg1",
+		Direction: domain.Outbound,
+		Flights: []*domain.Flight{
+			&domain.Flight{
+				ID: "10",
+				FlightNumber: &domain.FlightNumber{
+					FlightNumber: "123",
+					CarrierName:  "Carrier 1",
+					CarrierCode:  "CA1",
+				},
+				StartAirport:       &airport1,
+				StartTime:          time.Date(2019, time.October, 14, 8, 35, 0, 0, time.UTC),
+				DestinationAirport: &airport2,
+				DestinationTime:    time.Date(2019, time.October, 14, 9, 30, 0, 0, time.UTC),
+				Duration:           55 * time.Minute,
+			},
+		},
+		Duration:  65 * time.Minute,
+		StartTime: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC),
+		EndTime:   time.Date(2019, time.October, 14, 9, 35, 0, 0, time.UTC),
+	}
+	inbound := &domain.Journey{
+		ID:        "leg2",
+		Direction: domain.Inbound,
+		Flights: []*domain.Flight{
+			&domain.Flight{
+				ID: "20",
+				FlightNumber: &domain.FlightNumber{
+					FlightNumber: "123",
+					CarrierName:  "Carrier 2",
+					CarrierCode:  "CA2",
+				},
+				StartAirport:       &airport2,
+				StartTime:          time.Date(2019, time.October, 16, 10, 20, 0, 0, time.UTC),
+				DestinationAirport: &airport1,
+				DestinationTime:    time.Date(2019, time.October, 16, 11, 30, 0, 0, time.UTC),
+				Duration:           70 * time.Minute,
+			},
+		},
+		Duration:  80 * time.Minute,
+		StartTime: time.Date(2019, time.October, 16, 10, 15, 0, 0, time.UTC),
+		EndTime:   time.Date(2019, time.October, 16, 11, 35, 0, 0, time.UTC),
+	}
+	return &domain.Quote{
+		Currency: "USD",
+		CurrencyFormat: &domain.CurrencyFormat{Code: "USD", Symbol: "$", ThousandsSeparator: ",",
+			DecimalSeparator: ".", SymbolOnLeft: true, DecimalDigits: 2},
+		Itineraries: []*domain.Itinerary{
+			&domain.Itinerary{
+				Origin:          airport1.IataCode,
+				Destination:     airport2.IataCode,
+				Provider:        "skyscanner",
+				SupplierName:    "Agent1",
+				SupplierType:    "Airline",
+				Price:           domain.Money{Amount: 10099, Currency: "USD"},
+				DeeplinkURL:     "http://test.com/book?agent=1",
+				QuoteAge:        25 * time.Minute,
+				OutboundJourney: outbound,
+				InboundJourney:  inbound,
+			},
+			&domain.Itinerary{
+				Origin:          airport1.IataCode,
+				Destination:     airport2.IataCode,
+				Provider:        "farefile",
+				SupplierName:    "Agent2",
+				SupplierType:    "TravelAgent",
+				Price:           domain.Money{Amount: 9950, Currency: "USD"},
+				OutboundJourney: outbound,
+				InboundJourney:  inbound,
+			},
+		},
+		Complete: true,
+	}
+}
+
+// TestSaveQuote_RoundTrip tests saving a quote then reading it back.
+func TestSaveQuote_RoundTrip(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2}), "Error not expected")
+
+	expected := getExampleQuote()
+	err := repo.SaveQuote(expected)
+	assert.Nil(t, err, "Error not expected")
+	assert.NotZero(t, expected.ID, "Expected an id")
+
+	actual, err := repo.ReadQuote(expected.ID)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, expected, actual, "Wrong quote")
+}
+
+// TestSaveQuote_OneWay tests saving a quote whose itineraries have no inbound journey, then reading it back.
+func TestSaveQuote_OneWay(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2}), "Error not expected")
+
+	expected := getExampleQuote()
+	for _, itinerary := range expected.Itineraries {
+		itinerary.InboundJourney = nil
+	}
+	assert.Nil(t, repo.SaveQuote(expected), "Error not expected")
+
+	actual, err := repo.ReadQuote(expected.ID)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, expected, actual, "Wrong quote")
+}
+
+// TestSaveQuote_NoCarrierName tests saving a fare file quote, whose flights have no carrier names, keeps the carrier
+// names saved with an earlier sky scanner quote for the same flights.
+func TestSaveQuote_NoCarrierName(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2}), "Error not expected")
+
+	skyScannerQuote := getExampleQuote()
+	assert.Nil(t, repo.SaveQuote(skyScannerQuote), "Error not expected")
+
+	fareFileQuote := getExampleQuote()
+	fareFileQuote.Itineraries = fareFileQuote.Itineraries[1:]
+	fareFileQuote.Itineraries[0].OutboundJourney.Flights[0].FlightNumber.CarrierName = ""
+	fareFileQuote.Itineraries[0].InboundJourney.Flights[0].FlightNumber.CarrierName = ""
+	assert.Nil(t, repo.SaveQuote(fareFileQuote), "Error not expected")
+
+	actual, err := repo.ReadQuote(skyScannerQuote.ID)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, skyScannerQuote, actual, "Wrong quote")
+	assert.Equal(t, "Carrier 1", actual.Itineraries[0].OutboundJourney.Flights[0].FlightNumber.CarrierName,
+		"Wrong carrier name")
+}
+
+// TestReadCurrencyFormat tests reading the format of a currency saved with a quote, and of an unknown currency.
+func TestReadCurrencyFormat(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2}), "Error not expected")
+	quote := getExampleQuote()
+	assert.Nil(t, repo.SaveQuote(quote), "Error not expected")
+
+	format, err := repo.ReadCurrencyFormat("USD")
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, quote.CurrencyFormat, format, "Wrong format")
+
+	format, err = repo.ReadCurrencyFormat("EUR")
+	assert.Nil(t, err, "Error not expected")
+	assert.Nil(t, format, "Expected no format")
+}
+
+// TestSaveQuote_UnknownAirports tests reading a quote whose airports are not in the repository.
+func TestSaveQuote_UnknownAirports(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	err := repo.SaveQuote(getExampleQuote())
+	assert.Nil(t, err, "Error not expected")
+
+	actual, err := repo.ReadQuote(1)
+	assert.Nil(t, err, "Error not expected")
+	flight := actual.Itineraries[0].OutboundJourney.Flights[0]
+	assert.Equal(t, &domain.Airport{IataCode: airport1.IataCode}, flight.StartAirport, "Wrong airport")
+}
+
+// TestReadAllQuotes tests reading several quotes.
+func TestReadAllQuotes(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	first := getExampleQuote()
+	second := getExampleQuote() // same journey ids, but in a different quote
+	assert.Nil(t, repo.SaveQuote(first), "Error not expected")
+	assert.Nil(t, repo.SaveQuote(second), "Error not expected")
+
+	quotes, err := repo.ReadAllQuotes()
+	assert.Nil(t, err, "Error not expected")
+	assert.Len(t, quotes, 2, "Wrong number of quotes")
+	assert.Equal(t, first.ID, quotes[0].ID, "Wrong order")
+	assert.Equal(t, second.ID, quotes[1].ID, "Wrong order")
+}
+
+// TestReadQuote_Unknown tests reading a quote that doesn't exist.
+func TestReadQuote_Unknown(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	actual, err := repo.ReadQuote(1234)
+	assert.Nil(t, actual, "No quote expected")
+	assert.EqualError(t, err, "Unknown quote id 1234")
+}
+
+// TestReadPriceHistory tests only search runs with the same search criteria are read, oldest first.
+func TestReadPriceHistory(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	arguments := *(&domain.Arguments{Origin: "LHR", Destination: "LAX", TripType: domain.ReturnTrip, Adults: 2,
+		OutboundDate: "2019-11-01", HolidayDuration: 14}).WithDefaults()
+	otherDate := arguments
+	otherDate.OutboundDate = "2019-11-02"
+	otherCabinClass := arguments
+	otherCabinClass.CabinClass = "business"
+
+	later := &domain.SearchRun{QuoteID: 1, Arguments: arguments, Cheapest: 100, Median: 200, MostExpensive: 300,
+		Created: time.Date(2019, time.October, 15, 8, 30, 0, 0, time.UTC)}
+	earlier := &domain.SearchRun{QuoteID: 2, Arguments: arguments, Cheapest: 150, Median: 250, MostExpensive: 350,
+		Created: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)}
+	other := &domain.SearchRun{QuoteID: 3, Arguments: otherDate,
+		Created: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)}
+	business := &domain.SearchRun{QuoteID: 4, Arguments: otherCabinClass, Cheapest: 500, Median: 600,
+		MostExpensive: 700, Created: time.Date(2019, time.October, 14, 9, 30, 0, 0, time.UTC)}
+
+	for _, run := range []*domain.SearchRun{later, earlier, other, business} {
+		assert.Nil(t, repo.SaveSearchRun(run), "Error not expected")
+	}
+
+	actual, err := repo.ReadPriceHistory(&arguments)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []*domain.SearchRun{earlier, later}, actual, "Wrong history")
+}
+
+// TestMigrateSchema_Twice tests migrating a repository that is already up to date.
+func TestMigrateSchema_Twice(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1}), "Error not expected")
+	assert.Nil(t, repo.MigrateSchema(), "Error not expected")
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2}), "Error not expected")
+
+	version, err := repo.SchemaVersion()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, repo.LatestSchemaVersion(), version, "Wrong version")
+}
+
+// TestReadAllAirports tests every detail of the airports is saved and read.
+func TestReadAllAirports(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	heathrow := domain.Airport{Name: "London Heathrow Airport", IataCode: "LHR", Country: "United Kingdom",
+		Region: "England", IcaoCode: "EGLL", Type: domain.LargeAirport, Latitude: 51.4706, Longitude: -0.461941,
+		Municipality: "London", ScheduledService: true}
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{heathrow}), "Error not expected")
+
+	airports, err := repo.ReadAllAirports()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []domain.Airport{heathrow}, airports, "Wrong airports")
+}
+
+// TestImportAirports tests importing airports replaces those no longer imported, unless a saved flight refers to them,
+// and records their dataset.
+func TestImportAirports(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	unused := domain.Airport{Name: "Unused", IataCode: "CODE3", Region: "Region3", Country: "Country3"}
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2, unused}), "Error not expected")
+	assert.Nil(t, repo.SaveQuote(getExampleQuote()), "Error not expected") // flies from CODE1 to CODE2
+
+	renamed := airport1
+	renamed.Name = "Renamed"
+	dataset := &domain.AirportDataset{Source: "https://example.com/data/",
+		Downloaded: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC), Checksum: "abc", Airports: 1}
+	assert.Nil(t, repo.ImportAirports([]domain.Airport{renamed}, dataset), "Error not expected")
+	assert.Equal(t, int64(1), dataset.Version, "Wrong version")
+
+	airports, err := repo.ReadAllAirports()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []domain.Airport{renamed, airport2}, airports, "Wrong airports")
+
+	latest, err := repo.ReadLatestAirportDataset()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, dataset, latest, "Wrong dataset")
+
+	assert.Nil(t, repo.ImportAirports([]domain.Airport{airport1}, nil), "Error not expected")
+	latest, err = repo.ReadLatestAirportDataset()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, int64(1), latest.Version, "Expected no new dataset")
+}
+
+// TestMigrateSchema_Legacy tests migrating a repository created before schema versions were recorded, whose tables
+// don't match the first migration.
+func TestMigrateSchema_Legacy(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err, "Error not expected")
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	legacy := []string{
+		`CREATE TABLE airport (code TEXT PRIMARY KEY NOT NULL, name TEXT NOT NULL, region TEXT NOT NULL,
+			country TEXT NOT NULL)`,
+		`CREATE TABLE flight_number (flight_number TEXT PRIMARY KEY NOT NULL, carrier_name TEXT NOT NULL,
+			carrier_code TEXT NOT NULL)`,
+		`CREATE TABLE journey (id TEXT PRIMARY KEY NOT NULL, direction INTEGER NOT NULL CHECK (direction in (1,2)),
+			flights INTEGER NOT NULL, duration INTEGER NOT NULL, start_time TEXT NOT NULL, end_time TEXT NOT NULL)`,
+		`CREATE TABLE flight (id TEXT PRIMARY KEY NOT NULL, journey_id TEXT NOT NULL, flight_number INTEGER NOT NULL,
+			start_airport TEXT NOT NULL, start_time TEXT NOT NULL, dest_airport TEXT NOT NULL, dest_time TEXT NOT NULL,
+			duration INTEGER NOT NULL)`,
+		`CREATE TABLE itinerary (supplier_name TEXT NOT NULL, supplier_type TEXT NOT NULL, amount INTEGER NOT NULL,
+			outbound_journey TEXT NOT NULL, inbound_journey TEXT NOT NULL)`,
+		`INSERT INTO airport VALUES ('LHR', 'London Heathrow', 'England', 'United Kingdom')`,
+	}
+	for _, statement := range legacy {
+		_, err = db.Exec(statement)
+		assert.Nil(t, err, "Error not expected")
+	}
+
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything).Maybe()
+	repo := NewFlightRepository(mockLogger, db)
+	assert.Nil(t, repo.MigrateSchema(), "Error not expected")
+
+	version, err := repo.SchemaVersion()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, repo.LatestSchemaVersion(), version, "Wrong version")
+
+	assert.Nil(t, repo.CreateAirports([]domain.Airport{airport1, airport2}), "Error not expected")
+	assert.Nil(t, repo.SaveQuote(getExampleQuote()), "Error not expected")
+	airports, err := repo.ReadAllAirports()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []domain.Airport{airport1, airport2}, airports, "Expected the legacy airports to be dropped")
+}
+
+// TestMigrateSchema_TooNew tests migrating a repository created by a newer version of the program.
+func TestMigrateSchema_TooNew(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	_, err := db.Exec("PRAGMA user_version = 999")
+	assert.Nil(t, err, "Error not expected")
+	assert.Error(t, repo.MigrateSchema(), "Error expected")
+}
+
+// TestReadLatestSearchRun tests reading the most recent search run with the same criteria.
+func TestReadLatestSearchRun(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	arguments := *(&domain.Arguments{Origin: "LHR", Destination: "LAX", TripType: domain.ReturnTrip, Adults: 2,
+		OutboundDate: "2019-11-01", HolidayDuration: 14}).WithDefaults()
+	otherDuration := arguments
+	otherDuration.HolidayDuration = 7
+	otherCurrency := arguments
+	otherCurrency.Currency = "EUR"
+
+	actual, err := repo.ReadLatestSearchRun(&arguments)
+	assert.Nil(t, err, "Error not expected")
+	assert.Nil(t, actual, "No search run expected")
+
+	earlier := &domain.SearchRun{QuoteID: 1, Arguments: arguments,
+		Created: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)}
+	later := &domain.SearchRun{QuoteID: 2, Arguments: arguments,
+		Created: time.Date(2019, time.October, 15, 8, 30, 0, 0, time.UTC)}
+	other := &domain.SearchRun{QuoteID: 3, Arguments: otherDuration,
+		Created: time.Date(2019, time.October, 16, 8, 30, 0, 0, time.UTC)}
+	priced := &domain.SearchRun{QuoteID: 4, Arguments: otherCurrency,
+		Created: time.Date(2019, time.October, 16, 8, 30, 0, 0, time.UTC)}
+
+	for _, run := range []*domain.SearchRun{earlier, later, other, priced} {
+		assert.Nil(t, repo.SaveSearchRun(run), "Error not expected")
+	}
+
+	actual, err = repo.ReadLatestSearchRun(&arguments)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, later, actual, "Wrong search run")
+}
+
+// TestSearchSessions tests saving, replacing, reading and deleting search sessions.
+func TestSearchSessions(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	arguments := *(&domain.Arguments{Origin: "LHR", Destination: "LAX", TripType: domain.ReturnTrip, Adults: 2,
+		OutboundDate: "2019-11-01", HolidayDuration: 14}).WithDefaults()
+	oneWay := arguments
+	oneWay.TripType = domain.OneWayTrip
+	oneWay.HolidayDuration = 0
+
+	later := &domain.SearchSession{SessionKey: "abc", Arguments: arguments,
+		Created: time.Date(2019, time.October, 15, 8, 30, 0, 0, time.UTC)}
+	earlier := &domain.SearchSession{SessionKey: "def", Arguments: oneWay,
+		Created: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC)}
+	for _, session := range []*domain.SearchSession{later, earlier, later} {
+		assert.Nil(t, repo.SaveSearchSession(session), "Error not expected")
+	}
+
+	actual, err := repo.ReadSearchSessions()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []*domain.SearchSession{earlier, later}, actual, "Wrong sessions")
+
+	assert.Nil(t, repo.DeleteSearchSession("def"), "Error not expected")
+	assert.Nil(t, repo.DeleteSearchSession("unknown"), "Error not expected")
+	actual, err = repo.ReadSearchSessions()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, []*domain.SearchSession{later}, actual, "Wrong sessions")
+}
+
+// TestAirportDatasets tests saving, reading and deleting airport datasets.
+func TestAirportDatasets(t *testing.T) {
+	repo, db := newTestRepository(t)
+	defer db.Close()
+
+	actual, err := repo.ReadLatestAirportDataset()
+	assert.Nil(t, err, "Error not expected")
+	assert.Nil(t, actual, "Expected no dataset")
+
+	first := &domain.AirportDataset{Source: "https://example.com/data/",
+		Downloaded: time.Date(2019, time.October, 14, 8, 30, 0, 0, time.UTC),
+		Published:  time.Date(2019, time.October, 6, 0, 0, 0, 0, time.UTC),
+		Checksum:   "abc", Countries: 247, Regions: 3995, Airports: 9137}
+	second := &domain.AirportDataset{Source: "file:///mirror/",
+		Downloaded: time.Date(2019, time.October, 15, 8, 30, 0, 0, time.UTC), Checksum: "def", Countries: 1,
+		Regions: 2, Airports: 3}
+	assert.Nil(t, repo.SaveAirportDataset(first), "Error not expected")
+	assert.Nil(t, repo.SaveAirportDataset(second), "Error not expected")
+	assert.Equal(t, int64(1), first.Version, "Wrong version")
+	assert.Equal(t, int64(2), second.Version, "Wrong version")
+
+	actual, err = repo.ReadLatestAirportDataset()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, second, actual, "Wrong dataset")
+
+	assert.Nil(t, repo.DeleteAirportDataset(second.Version), "Error not expected")
+	actual, err = repo.ReadLatestAirportDataset()
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, first, actual, "Wrong dataset")
+}
+
+// TestMigrateSchema_FromVersion1 tests upgrading a version 1 database, with itineraries saved before their airports
+// were recorded.
+func TestMigrateSchema_FromVersion1(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err, "Error not expected")
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	mockLogger := &mocks.Logger{}
+	mockLogger.On("Debugf", mock.Anything, mock.Anything).Maybe()
+	repo := NewFlightRepository(mockLogger, db)
+	assert.Nil(t, repo.executeMigration(migrations[0], 1), "Error not expected")
+
+	_, err = db.Exec("INSERT INTO itinerary (quote_id, sequence, supplier_name, supplier_type, amount, " +
+		"outbound_journey, inbound_journey) VALUES (1, 0, 'Agent1', 'Airline', 100, 'leg1', 'leg2')")
+	assert.Nil(t, err, "Error not expected")
+
+	assert.Nil(t, repo.MigrateSchema(), "Error not expected")
+
+	var origin, destination string
+	err = db.QueryRow("SELECT origin, destination FROM itinerary").Scan(&origin, &destination)
+	assert.Nil(t, err, "Error not expected")
+	assert.Equal(t, "", origin, "Wrong origin")
+	assert.Equal(t, "", destination, "Wrong destination")
+}
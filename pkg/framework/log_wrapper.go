@@ -1,6 +1,7 @@
 package framework
 
 import (
+	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -11,26 +12,47 @@ type LogWrapper struct {
 	logger *logrus.Entry
 }
 
+// LogOptions configures a logger.
+type LogOptions struct {
+	Level  string    // e.g. "debug", "info", "warn", "error"
+	Output io.Writer // defaults to stdout
+}
+
 // NewLogWrapper creates a logger with the specified name.
 func NewLogWrapper(name string, debug bool) *LogWrapper {
+	level := "info"
+	if debug {
+		level = "debug"
+	}
+	logger, _ := NewLogWrapperWithOptions(name, LogOptions{Level: level}) // level is always valid
+	return logger
+}
+
+// NewLogWrapperWithOptions creates a logger with the specified name and options, or returns an error if the level is
+// invalid.
+func NewLogWrapperWithOptions(name string, options LogOptions) (*LogWrapper, error) {
 	logger := logrus.New()
 
-	if debug {
-		logger.SetLevel(logrus.DebugLevel)
+	level, err := logrus.ParseLevel(options.Level)
+	if err != nil {
+		return nil, err
+	}
+	logger.SetLevel(level)
 
-		// adds func and file fields, has small runtime overhead
-		// logger.SetReportCaller(true)
+	// adds func and file fields, has small runtime overhead
+	// logger.SetReportCaller(true)
+
+	if options.Output != nil {
+		logger.SetOutput(options.Output)
 	} else {
-		logger.SetLevel(logrus.InfoLevel)
+		logger.SetOutput(os.Stdout)
 	}
-
-	logger.SetOutput(os.Stdout)
 	logger.SetFormatter(&logrus.TextFormatter{
 		// DisableColors: true, // sets logfmt format
 		FullTimestamp: true,
 	})
 
-	return &LogWrapper{logger.WithFields(logrus.Fields{"name": name})}
+	return &LogWrapper{logger.WithFields(logrus.Fields{"name": name})}, nil
 }
 
 // Debug writes a static message at debug level.
@@ -0,0 +1,64 @@
+package framework
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of requests, allowing short bursts. It is safe for concurrent use.
+type TokenBucket struct {
+	mutex    sync.Mutex
+	tokens   float64
+	capacity float64
+	interval time.Duration // between each token being added
+	last     time.Time     // when tokens was last updated
+}
+
+// NewTokenBucket creates a new instance, allowing the specified number of requests per minute, in bursts of up to
+// the specified size.
+func NewTokenBucket(requestsPerMinute int, burst int) *TokenBucket {
+	return &TokenBucket{
+		tokens:   float64(burst),
+		capacity: float64(burst),
+		interval: time.Minute / time.Duration(requestsPerMinute),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed, or returns an error if the context is done first.
+func (bucket *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		delay := bucket.take()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take removes a token if one is available and returns zero, otherwise returns how long until one will be.
+func (bucket *TokenBucket) take() time.Duration {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	now := time.Now()
+	bucket.tokens += float64(now.Sub(bucket.last)) / float64(bucket.interval)
+	if bucket.tokens > bucket.capacity {
+		bucket.tokens = bucket.capacity
+	}
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) * float64(bucket.interval))
+}
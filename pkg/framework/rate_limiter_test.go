@@ -0,0 +1,33 @@
+package framework
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTokenBucket_Burst tests a burst of requests is allowed straight away, and the next has to wait.
+func TestTokenBucket_Burst(t *testing.T) {
+	bucket := NewTokenBucket(600, 3) // i.e. one every 100ms
+
+	start := time.Now()
+	for index := 0; index < 3; index++ {
+		assert.Nil(t, bucket.Wait(context.Background()), "Error not expected")
+	}
+	assert.True(t, time.Since(start) < 50*time.Millisecond, "Burst should not wait")
+
+	assert.Nil(t, bucket.Wait(context.Background()), "Error not expected")
+	assert.True(t, time.Since(start) >= 90*time.Millisecond, "Request after burst should wait")
+}
+
+// TestTokenBucket_Cancelled tests waiting is abandoned when the context is cancelled.
+func TestTokenBucket_Cancelled(t *testing.T) {
+	bucket := NewTokenBucket(1, 1)
+	assert.Nil(t, bucket.Wait(context.Background()), "Error not expected")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, bucket.Wait(ctx), "Wrong error")
+}
@@ -0,0 +1,100 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+)
+
+// RetryPolicy controls how requests that fail with a network error, rate limit or server error are retried, waiting
+// longer after each attempt.
+type RetryPolicy struct {
+	MaxAttempts    int           // including the first, so 1 (or less) never retries
+	InitialBackoff time.Duration // before the first retry, doubling before each retry after that
+	MaxBackoff     time.Duration // the longest wait between attempts, unless the server asks for longer
+}
+
+// DefaultRetryPolicy retries up to three times, after waiting around 1, 2 then 4 seconds.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// Backoff returns how long to wait after the specified attempt (from 1) failed. This is the exponential backoff with
+// up to half of it random jitter, so concurrent searches don't all retry at once, or retryAfter if longer.
+func (policy RetryPolicy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := policy.InitialBackoff
+	for count := 1; count < attempt && backoff < policy.MaxBackoff; count++ {
+		backoff *= 2
+	}
+	if backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+
+	if backoff > 1 {
+		backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	}
+	if retryAfter > backoff {
+		return retryAfter
+	}
+	return backoff
+}
+
+// sleep pauses for the duration, or returns an error if the context is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter returns how long a Retry-After header value asks to wait, either in seconds or until a date, or
+// zero if not set or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	date, err := http.ParseTime(value)
+	if err != nil || date.Before(now) {
+		return 0
+	}
+	return date.Sub(now)
+}
+
+// isRetryable returns whether a request that failed with the HTTP status code might succeed if retried.
+func isRetryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// statusError returns the error for an unexpected HTTP status, wrapping one of the domain errors where possible.
+func statusError(res *http.Response) error {
+	switch {
+	case res.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%w (%s)", domain.ErrBadRequest, res.Status)
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w (%s)", domain.ErrUnauthorized, res.Status)
+	case res.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w (%s)", domain.ErrSessionExpired, res.Status)
+	case res.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w (%s)", domain.ErrRateLimited, res.Status)
+	case res.StatusCode >= 500:
+		return fmt.Errorf("%w (%s)", domain.ErrUnavailable, res.Status)
+	default:
+		return fmt.Errorf("Request rejected with %s", res.Status)
+	}
+}
@@ -0,0 +1,75 @@
+package framework
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chrisnappin/flightchecker/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRetryPolicy_Backoff tests each backoff doubles, with jitter, up to the maximum.
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	testCases := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{9, 5 * time.Second},
+	}
+	for _, testCase := range testCases {
+		for count := 0; count < 20; count++ {
+			backoff := policy.Backoff(testCase.attempt, 0)
+			assert.True(t, backoff >= testCase.max/2, "Backoff %s too short for attempt %d", backoff, testCase.attempt)
+			assert.True(t, backoff <= testCase.max, "Backoff %s too long for attempt %d", backoff, testCase.attempt)
+		}
+	}
+}
+
+// TestRetryPolicy_BackoffRetryAfter tests a longer wait asked for by the server is honoured.
+func TestRetryPolicy_BackoffRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Minute, policy.Backoff(1, time.Minute), "Wrong backoff")
+}
+
+// TestParseRetryAfter tests parsing Retry-After values in seconds and as dates.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now), "Wrong seconds")
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Fri, 01 Nov 2019 12:01:30 GMT", now), "Wrong date")
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Fri, 01 Nov 2019 11:00:00 GMT", now), "Past date")
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now), "Not set")
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-5", now), "Negative")
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now), "Invalid")
+}
+
+// TestStatusError tests each HTTP status is mapped to the right kind of error.
+func TestStatusError(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		expected   error
+	}{
+		{http.StatusBadRequest, domain.ErrBadRequest},
+		{http.StatusUnauthorized, domain.ErrUnauthorized},
+		{http.StatusForbidden, domain.ErrUnauthorized},
+		{http.StatusGone, domain.ErrSessionExpired},
+		{http.StatusTooManyRequests, domain.ErrRateLimited},
+		{http.StatusInternalServerError, domain.ErrUnavailable},
+		{http.StatusServiceUnavailable, domain.ErrUnavailable},
+	}
+	for _, testCase := range testCases {
+		err := statusError(&http.Response{StatusCode: testCase.statusCode, Status: http.StatusText(testCase.statusCode)})
+		assert.True(t, errors.Is(err, testCase.expected), "Wrong error %s for status %d", err, testCase.statusCode)
+	}
+
+	err := statusError(&http.Response{StatusCode: http.StatusTeapot, Status: "418 I'm a teapot"})
+	assert.EqualError(t, err, "Request rejected with 418 I'm a teapot", "Wrong error")
+}
@@ -1,12 +1,15 @@
 package framework
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -68,6 +71,28 @@ type SkyScannerBookingDetailsLink struct {
 	Method string // e.g. "PUT"
 }
 
+// SkyScannerBookingDetails is the result of polling the booking details of an itinerary
+type SkyScannerBookingDetails struct {
+	Query          SkyScannerQuery
+	BookingOptions []SkyScannerBookingOption
+	Agents         []SkyScannerAgent
+	Currencies     []SkyScannerCurrency
+}
+
+// SkyScannerBookingOption contains a way to book an itinerary, with one or more Agents
+type SkyScannerBookingOption struct {
+	BookingItems []SkyScannerBookingItem
+}
+
+// SkyScannerBookingItem contains part of a booking option, with a single Agent
+type SkyScannerBookingItem struct {
+	AgentID    int     `json:"AgentID"`
+	Status     string  // e.g. "Current", "Pending", "Estimated", "Failed"
+	Price      float64 // e.g. 758.42
+	Deeplink   string
+	SegmentIds []int
+}
+
 // SkyScannerLeg contains details of part of an itinery, e.g. the outbound flight
 type SkyScannerLeg struct {
 	ID                 string `json:"Id"`
@@ -146,62 +171,194 @@ type SkyScannerCurrency struct {
 	DecimalDigits               int // e.g. 2
 }
 
+// DefaultPageSize is the number of itineraries requested per page of session results.
+const DefaultPageSize = 100
+
+// DefaultUserAgent identifies requests made by this program.
+const DefaultUserAgent = "flightchecker"
+
+// SkyScannerOptions configures how the sky scanner API is called.
+type SkyScannerOptions struct {
+	Client         *http.Client  // nil uses http.DefaultClient
+	BaseURL        string        // e.g. "http://localhost:8080", or empty for "https://" then the API host
+	RequestTimeout time.Duration // for each attempt at a request, or zero for no limit
+	UserAgent      string        // empty uses DefaultUserAgent
+	PageSize       int           // itineraries per page of session results, or zero for DefaultPageSize
+	Retry          RetryPolicy
+	Limiter        *TokenBucket // shared by every search, takes a token for each attempt at a request, nil for no limit
+}
+
+// DefaultSkyScannerOptions returns the options used unless configured otherwise.
+func DefaultSkyScannerOptions() SkyScannerOptions {
+	return SkyScannerOptions{
+		RequestTimeout: 30 * time.Second,
+		UserAgent:      DefaultUserAgent,
+		PageSize:       DefaultPageSize,
+		Retry:          DefaultRetryPolicy,
+	}
+}
+
 // SkyScannerService handles calling the sky scanner API.
 type SkyScannerService struct {
-	logger domain.Logger
+	logger  domain.Logger
+	options SkyScannerOptions
+	sleep   func(ctx context.Context, delay time.Duration) error // waits between retries
 }
 
-// NewSkyScannerService creates a new instance.
-func NewSkyScannerService(logger domain.Logger) *SkyScannerService {
-	return &SkyScannerService{logger}
+// NewSkyScannerService creates a new instance, configured by the options.
+func NewSkyScannerService(logger domain.Logger, options SkyScannerOptions) *SkyScannerService {
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+	if options.UserAgent == "" {
+		options.UserAgent = DefaultUserAgent
+	}
+	if options.PageSize <= 0 {
+		options.PageSize = DefaultPageSize
+	}
+	options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+	return &SkyScannerService{logger, options, sleep}
 }
 
-// PollForQuotes calls the skyscanner "Poll session results" operation, to look for quotes
-func (service *SkyScannerService) PollForQuotes(sessionKey string, apiHost string, apiKey string,
-	airports map[string]domain.Airport) (*domain.Quote, error) {
-	const pageIndex = 0
-	const pageSize = 10
-
-	service.logger.Debugf("GET first page of %d quotes...", pageSize)
-	url := fmt.Sprintf("https://%s/apiservices/pricing/uk2/v1.0/%%7B%s%%7D?pageIndex=%d&pageSize=%d",
-		apiHost, sessionKey, pageIndex, pageSize)
+// baseURL returns the URL that API paths are relative to.
+func (service *SkyScannerService) baseURL(apiHost string) string {
+	if service.options.BaseURL != "" {
+		return service.options.BaseURL
+	}
+	return "https://" + apiHost
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// PollForQuotes calls the skyscanner "Poll session results" operation, to look for quotes. Once the results are
+// complete every page is fetched, so all itineraries are returned.
+func (service *SkyScannerService) PollForQuotes(ctx context.Context, sessionKey string, apiHost string, apiKey string,
+	airports map[string]domain.Airport) (*domain.Quote, error) {
+	response, err := service.pollPage(ctx, sessionKey, apiHost, apiKey, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("x-rapidapi-host", apiHost)
-	req.Header.Add("x-rapidapi-key", apiKey)
+	// results that are still being updated are only checked for completion, so only need the first page
+	pageIndex := 1
+	for response.Status == "UpdatesComplete" && len(response.Itineraries) == pageIndex*service.options.PageSize {
+		page, err := service.pollPage(ctx, sessionKey, apiHost, apiKey, pageIndex)
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Itineraries) == 0 {
+			break
+		}
+		mergePage(response, page)
+		pageIndex++
+	}
+	return service.convertToDomain(response, airports)
+}
+
+// pollPage fetches a single page of session results.
+func (service *SkyScannerService) pollPage(ctx context.Context, sessionKey string, apiHost string, apiKey string,
+	pageIndex int) (*SkyScannerResponse, error) {
+	service.logger.Debugf("GET page %d of %d quotes...", pageIndex, service.options.PageSize)
+	url := fmt.Sprintf("%s/apiservices/pricing/uk2/v1.0/%%7B%s%%7D?pageIndex=%d&pageSize=%d",
+		service.baseURL(apiHost), sessionKey, pageIndex, service.options.PageSize)
 
-	res, err := http.DefaultClient.Do(req)
+	_, body, err := service.send(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("x-rapidapi-host", apiHost)
+		req.Header.Add("x-rapidapi-key", apiKey)
+		return req, nil
+	}, http.StatusOK)
 	if err != nil {
 		return nil, err
 	}
 
-	service.logger.Debug("Response received...")
-	defer res.Body.Close()
-	body, err := ioutil.ReadAll(res.Body)
+	var r SkyScannerResponse
+	err = json.Unmarshal(body, &r)
 	if err != nil {
 		return nil, err
 	}
+	return &r, nil
+}
 
-	if res.StatusCode != 200 {
-		service.logInvalidResponse(res)
-		return nil, errors.New("Request rejected")
+// mergePage adds the itineraries of a later page to the response, with any legs, segments, carriers, agents, places
+// and currencies not already included.
+func mergePage(response *SkyScannerResponse, page *SkyScannerResponse) {
+	response.Itineraries = append(response.Itineraries, page.Itineraries...)
+	if page.Status != "UpdatesComplete" {
+		response.Status = page.Status
 	}
 
-	var r SkyScannerResponse
-	err = json.Unmarshal(body, &r)
-	if err != nil {
-		return nil, err
+	legs := make(map[string]bool)
+	for _, leg := range response.Legs {
+		legs[leg.ID] = true
+	}
+	for _, leg := range page.Legs {
+		if !legs[leg.ID] {
+			legs[leg.ID] = true
+			response.Legs = append(response.Legs, leg)
+		}
+	}
+
+	segments := make(map[int]bool)
+	for _, segment := range response.Segments {
+		segments[segment.ID] = true
+	}
+	for _, segment := range page.Segments {
+		if !segments[segment.ID] {
+			segments[segment.ID] = true
+			response.Segments = append(response.Segments, segment)
+		}
+	}
+
+	carriers := make(map[int]bool)
+	for _, carrier := range response.Carriers {
+		carriers[carrier.ID] = true
+	}
+	for _, carrier := range page.Carriers {
+		if !carriers[carrier.ID] {
+			carriers[carrier.ID] = true
+			response.Carriers = append(response.Carriers, carrier)
+		}
+	}
+
+	agents := make(map[int]bool)
+	for _, agent := range response.Agents {
+		agents[agent.ID] = true
+	}
+	for _, agent := range page.Agents {
+		if !agents[agent.ID] {
+			agents[agent.ID] = true
+			response.Agents = append(response.Agents, agent)
+		}
+	}
+
+	places := make(map[int]bool)
+	for _, place := range response.Places {
+		places[place.ID] = true
+	}
+	for _, place := range page.Places {
+		if !places[place.ID] {
+			places[place.ID] = true
+			response.Places = append(response.Places, place)
+		}
+	}
+
+	currencies := make(map[string]bool)
+	for _, currency := range response.Currencies {
+		currencies[currency.Code] = true
+	}
+	for _, currency := range page.Currencies {
+		if !currencies[currency.Code] {
+			currencies[currency.Code] = true
+			response.Currencies = append(response.Currencies, currency)
+		}
 	}
-	return service.convertToDomain(&r, airports)
 }
 
 // StartSearch calls the skyscanner "Create session" operation, which returns a session key.
-func (service *SkyScannerService) StartSearch(arguments *domain.Arguments) (string, error) {
-	url := "https://" + arguments.APIHost + "/apiservices/pricing/v1.0"
+func (service *SkyScannerService) StartSearch(ctx context.Context, arguments *domain.Arguments) (string, error) {
+	url := service.baseURL(arguments.APIHost) + "/apiservices/pricing/v1.0"
 
 	service.logger.Debug("POST flight search to create session...")
 	payload, err := service.formatSearchPayload(arguments)
@@ -209,25 +366,20 @@ func (service *SkyScannerService) StartSearch(arguments *domain.Arguments) (stri
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, strings.NewReader(payload))
+	res, _, err := service.send(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("x-rapidapi-host", arguments.APIHost)
+		req.Header.Add("x-rapidapi-key", arguments.APIKey)
+		req.Header.Add("content-type", "application/x-www-form-urlencoded")
+		return req, nil
+	}, http.StatusCreated)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Add("x-rapidapi-host", arguments.APIHost)
-	req.Header.Add("x-rapidapi-key", arguments.APIKey)
-	req.Header.Add("content-type", "application/x-www-form-urlencoded")
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", nil
-	}
-
-	if res.StatusCode != 201 {
-		service.logInvalidResponse(res)
-		return "", errors.New("Request rejected")
-	}
-
 	// in practice this returns 201, with body of "{}"
 	// Location [0] http header in response is of the form:
 	// http://partners.api.skyscanner.net/apiservices/pricing/uk2/v1.0/c1b3deed-0419-4296-a4e3-3b5afa6b8ea9
@@ -246,24 +398,140 @@ func (service *SkyScannerService) StartSearch(arguments *domain.Arguments) (stri
 	return "", errors.New("No Location returned in response")
 }
 
+// GetBookingDetails follows the booking details link of an itinerary, calling the skyscanner "Create booking details"
+// operation then "Poll booking details", to find the ways to book it with each agent. This only works while the
+// search session that found the itinerary lasts.
+func (service *SkyScannerService) GetBookingDetails(ctx context.Context, link *domain.BookingDetailsLink,
+	apiHost string, apiKey string) (*domain.BookingDetails, error) {
+	method := link.Method
+	if method == "" {
+		method = "PUT"
+	}
+
+	service.logger.Debugf("%s booking details...", method)
+	res, _, err := service.send(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, service.baseURL(apiHost)+link.URI,
+			strings.NewReader(link.Body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("x-rapidapi-host", apiHost)
+		req.Header.Add("x-rapidapi-key", apiKey)
+		req.Header.Add("content-type", "application/x-www-form-urlencoded")
+		return req, nil
+	}, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	// as with sessions, the Location can't be used directly, but its path can be polled via the API host
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil || location.Path == "" {
+		return nil, fmt.Errorf("No booking details location returned in response: %s", res.Header.Get("Location"))
+	}
+
+	service.logger.Debug("GET booking details...")
+	_, body, err := service.send(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", service.baseURL(apiHost)+location.RequestURI(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("x-rapidapi-host", apiHost)
+		req.Header.Add("x-rapidapi-key", apiKey)
+		return req, nil
+	}, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var details SkyScannerBookingDetails
+	err = json.Unmarshal(body, &details)
+	if err != nil {
+		return nil, err
+	}
+	return service.convertBookingDetailsToDomain(&details)
+}
+
+// convertBookingDetailsToDomain returns the booking options, cheapest first.
+func (service *SkyScannerService) convertBookingDetailsToDomain(details *SkyScannerBookingDetails) (
+	*domain.BookingDetails, error) {
+	agents := make(map[int]SkyScannerAgent)
+	for _, agent := range details.Agents {
+		agents[agent.ID] = agent
+	}
+
+	decimalDigits := domain.CurrencyDecimalDigits(details.Query.Currency)
+	currencyFormat := service.convertCurrencyToDomain(details.Currencies, details.Query.Currency)
+	if currencyFormat != nil {
+		decimalDigits = currencyFormat.DecimalDigits
+	}
+
+	result := domain.BookingDetails{Options: []*domain.BookingOption{}, Complete: true}
+	amounts := make(map[*domain.BookingOption]int) // maps option to its total price, for ranking
+	for _, bookingOption := range details.BookingOptions {
+		option := domain.BookingOption{}
+		for _, bookingItem := range bookingOption.BookingItems {
+			agent, exists := agents[bookingItem.AgentID]
+			if !exists {
+				return nil, fmt.Errorf("Unknown agent id %d", bookingItem.AgentID)
+			}
+			if bookingItem.Status == "Pending" {
+				result.Complete = false
+			}
+			option.Items = append(option.Items, &domain.BookingItem{
+				SupplierName: agent.Name,
+				SupplierType: agent.Type,
+				Price: domain.Money{
+					Amount:   int(math.Round(bookingItem.Price * math.Pow10(decimalDigits))),
+					Currency: details.Query.Currency,
+				},
+				DeeplinkURL: bookingItem.Deeplink,
+				Status:      bookingItem.Status,
+			})
+		}
+		price, err := option.Price()
+		if err != nil {
+			return nil, err
+		}
+		amounts[&option] = price.Amount
+		result.Options = append(result.Options, &option)
+	}
+	sort.SliceStable(result.Options, func(i, j int) bool {
+		return amounts[result.Options[i]] < amounts[result.Options[j]]
+	})
+	return &result, nil
+}
+
 func (service *SkyScannerService) formatSearchPayload(arguments *domain.Arguments) (string, error) {
-	const country = "GB"
-	const currency = "GBP"
-	const locale = "en-GB"
-	const cabinClass = "economy"    // economy, premiumeconomy, business, first
-	const groupPricing = true       // true = price for all, false = price for 1 adult
-	const dateFormat = "2006-01-02" // i.e. YYYY-MM-DD
-
-	holidayStartDate, err := time.Parse(dateFormat, arguments.OutboundDate)
+	arguments = arguments.WithDefaults()
+	err := arguments.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = time.Parse(domain.DateFormat, arguments.OutboundDate)
 	if err != nil {
 		return "", err
 	}
-	holidayEndDate := holidayStartDate.AddDate(0, 0, arguments.HolidayDuration)
 
-	return fmt.Sprintf("inboundDate=%s&cabinClass=%s&children=%d&infants=%d&country=%s&"+
+	// one-way trips have no inbound date, open-jaw trips must be searched as two one-way trips
+	inboundDate := ""
+	switch arguments.Trip() {
+	case domain.ReturnTrip:
+		date, err := arguments.InboundDate()
+		if err != nil {
+			return "", err
+		}
+		inboundDate = "inboundDate=" + date + "&"
+	case domain.OpenJawTrip:
+		return "", errors.New("Open-jaw trips must be searched as two one-way trips")
+	}
+
+	return fmt.Sprintf("%scabinClass=%s&children=%d&infants=%d&country=%s&"+
 		"currency=%s&locale=%s&originPlace=%s-sky&destinationPlace=%s-sky&outboundDate=%s&adults=%d&groupPricing=%t",
-		holidayEndDate.Format(dateFormat), cabinClass, arguments.Children, arguments.Infants, country, currency,
-		locale, arguments.Origin, arguments.Destination, arguments.OutboundDate, arguments.Adults, groupPricing), nil
+		inboundDate, arguments.CabinClass, arguments.Children, arguments.Infants, arguments.Country, arguments.Currency,
+		arguments.Locale, arguments.Origin, arguments.Destination, arguments.OutboundDate, arguments.Adults,
+		*arguments.GroupPricing), nil
 
 }
 
@@ -302,6 +570,12 @@ func (service *SkyScannerService) convertToDomain(response *SkyScannerResponse,
 
 	const timeFormat = "2006-01-02T15:04:05"
 
+	currencyFormat := service.convertCurrencyToDomain(response.Currencies, response.Query.Currency)
+	decimalDigits := domain.CurrencyDecimalDigits(response.Query.Currency)
+	if currencyFormat != nil {
+		decimalDigits = currencyFormat.DecimalDigits
+	}
+
 	itineraries := []*domain.Itinerary{}
 	for _, responseItinerary := range response.Itineraries {
 		for _, pricingOption := range responseItinerary.PricingOptions {
@@ -317,30 +591,75 @@ func (service *SkyScannerService) convertToDomain(response *SkyScannerResponse,
 					return nil, err
 				}
 
-				inboundJourney, err := service.convertLegToDomain(
-					legs, segments, places, carriers, responseItinerary.InboundLegID, domain.Inbound, airports)
-				if err != nil {
-					return nil, err
+				// one-way trips have no inbound leg
+				var inboundJourney *domain.Journey
+				if responseItinerary.InboundLegID != "" {
+					inboundJourney, err = service.convertLegToDomain(
+						legs, segments, places, carriers, responseItinerary.InboundLegID, domain.Inbound, airports)
+					if err != nil {
+						return nil, err
+					}
 				}
 
 				itinerary := domain.Itinerary{
-					SupplierName:    agent.Name,
-					SupplierType:    agent.Type,
-					Amount:          int(math.Round(pricingOption.Price * 100)),
-					OutboundJourney: outboundJourney,
-					InboundJourney:  inboundJourney,
+					SupplierName: agent.Name,
+					SupplierType: agent.Type,
+					Price: domain.Money{
+						Amount:   int(math.Round(pricingOption.Price * math.Pow10(decimalDigits))),
+						Currency: response.Query.Currency,
+					},
+					DeeplinkURL:        pricingOption.DeeplinkURL,
+					QuoteAge:           time.Duration(pricingOption.QuoteAgeInMinutes) * time.Minute,
+					BookingDetailsLink: convertBookingDetailsLink(responseItinerary.BookingDetailsLink),
+					OutboundJourney:    outboundJourney,
+					InboundJourney:     inboundJourney,
 				}
 				itineraries = append(itineraries, &itinerary)
 			}
 		}
 	}
 	quote := domain.Quote{
-		Itineraries: itineraries,
-		Complete:    response.Status == "UpdatesComplete",
+		Currency:       response.Query.Currency,
+		CurrencyFormat: currencyFormat,
+		Itineraries:    itineraries,
+		Complete:       response.Status == "UpdatesComplete",
+		Agents:         len(response.Agents),
+	}
+	for _, agent := range response.Agents {
+		if agent.Status == "UpdatesPending" {
+			quote.AgentsPending++
+		}
 	}
 	return &quote, nil
 }
 
+// convertBookingDetailsLink returns the domain link, or nil if the itinerary doesn't have one.
+func convertBookingDetailsLink(link SkyScannerBookingDetailsLink) *domain.BookingDetailsLink {
+	if link.URI == "" {
+		return nil
+	}
+	return &domain.BookingDetailsLink{URI: link.URI, Body: link.Body, Method: link.Method}
+}
+
+// convertCurrencyToDomain returns the format of the currency with the code, or nil if the response doesn't include it.
+func (service *SkyScannerService) convertCurrencyToDomain(currencies []SkyScannerCurrency,
+	code string) *domain.CurrencyFormat {
+	for _, currency := range currencies {
+		if currency.Code == code {
+			return &domain.CurrencyFormat{
+				Code:                        currency.Code,
+				Symbol:                      currency.Symbol,
+				ThousandsSeparator:          currency.ThousandsSeparator,
+				DecimalSeparator:            currency.DecimalSeparator,
+				SymbolOnLeft:                currency.SymbolOnLeft,
+				SpaceBetweenAmountAndSymbol: currency.SpaceBetweenAmountAndSymbol,
+				DecimalDigits:               currency.DecimalDigits,
+			}
+		}
+	}
+	return nil
+}
+
 func (service *SkyScannerService) convertLegToDomain(legs map[string]SkyScannerLeg, segments map[int]SkyScannerSegment,
 	places map[int]SkyScannerPlace, carriers map[int]SkyScannerCarrier, id string, direction domain.Direction,
 	airports map[string]domain.Airport) (*domain.Journey, error) {
@@ -433,16 +752,78 @@ func (service *SkyScannerService) convertAirport(airports map[string]domain.Airp
 	return &airport, nil
 }
 
-func (service *SkyScannerService) logInvalidResponse(res *http.Response) {
-	service.logger.Errorf("Request rejected with %s", res.Status)
+// send makes the request built by newRequest, retrying as per the retry policy, and returns the response and its body
+// if it has the expected status. The request is built again for each attempt, since sending consumes its body, and
+// fails straight away if it can't be built, as retrying won't help. Gives up once the context is done.
+func (service *SkyScannerService) send(ctx context.Context, newRequest func(context.Context) (*http.Request, error),
+	expectedStatus int) (*http.Response, []byte, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, body, err := service.attempt(ctx, req)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		retryable := true
+		retryAfter := time.Duration(0)
+		if err == nil && res.StatusCode == expectedStatus {
+			service.logger.Debug("Response received...")
+			return res, body, nil
+		} else if err == nil {
+			service.logInvalidResponse(res, body)
+			retryable = isRetryable(res.StatusCode)
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+			err = statusError(res)
+		}
+
+		if !retryable || attempt >= service.options.Retry.MaxAttempts {
+			return nil, nil, err
+		}
+		delay := service.options.Retry.Backoff(attempt, retryAfter)
+		service.logger.Warnf("Attempt %d failed with %s, retrying in %s", attempt, err, delay.Round(time.Millisecond))
+		err = service.sleep(ctx, delay)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
 
-	if res.ContentLength != 0 {
-		defer res.Body.Close()
-		body, err := ioutil.ReadAll(res.Body)
+// attempt makes a single attempt at the request, once the rate limit (if any) allows it, within the request timeout
+// (if any), and returns the response with its body read.
+func (service *SkyScannerService) attempt(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	if service.options.Limiter != nil {
+		err := service.options.Limiter.Wait(ctx)
 		if err != nil {
-			service.logger.Error(err)
-		} else {
-			service.logger.Errorf("Response was: %s", body)
+			return nil, nil, err
 		}
 	}
+	if service.options.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, service.options.RequestTimeout)
+		defer cancel()
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", service.options.UserAgent)
+
+	res, err := service.options.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, body, nil
+}
+
+func (service *SkyScannerService) logInvalidResponse(res *http.Response, body []byte) {
+	service.logger.Errorf("Request rejected with %s", res.Status)
+	if len(body) > 0 {
+		service.logger.Errorf("Response was: %s", body)
+	}
 }
@@ -1,6 +1,12 @@
 package framework
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -42,13 +48,31 @@ var dummyAirports = map[string]domain.Airport{
 	airport2.IataCode: airport2,
 }
 
+// newTestService returns a service that doesn't retry failed requests.
+func newTestService(logger domain.Logger, pageSize int) *SkyScannerService {
+	return NewSkyScannerService(logger, SkyScannerOptions{PageSize: pageSize, Retry: RetryPolicy{MaxAttempts: 1}})
+}
+
+// newRetryingTestService returns a service that retries failed requests up to the maximum attempts, recording each
+// delay rather than waiting.
+func newRetryingTestService(logger domain.Logger, maxAttempts int) (*SkyScannerService, *[]time.Duration) {
+	delays := []time.Duration{}
+	service := NewSkyScannerService(logger, SkyScannerOptions{PageSize: 10,
+		Retry: RetryPolicy{MaxAttempts: maxAttempts, InitialBackoff: time.Second, MaxBackoff: time.Minute}})
+	service.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	return service, &delays
+}
+
 // TestFormatSearchPayload tests formatting the payload of search parameters, with valid input.
 func TestFormatSearchPayload_AllValid(t *testing.T) {
 	expected := "inboundDate=2019-11-10&cabinClass=economy&children=2&infants=0&country=GB&currency=GBP&locale=en-GB" +
 		"&originPlace=LHR-sky&destinationPlace=LAX-sky&outboundDate=2019-11-01&adults=2&groupPricing=true"
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 	actual, err := service.formatSearchPayload(&dummyArguments)
 
 	assert.Equal(t, expected, actual, "Incorrect payload")
@@ -61,13 +85,77 @@ func TestFormatSearchPayload_InvalidDate(t *testing.T) {
 	brokenArguments.OutboundDate = "01/02/2003" // not YYYY-MM-DD
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 	actual, err := service.formatSearchPayload(&brokenArguments)
 
 	assert.Equal(t, "", actual, "No payload expected")
 	assert.Error(t, err, "Error expected")
 }
 
+// TestFormatSearchPayload_Options tests formatting the payload of a search with the optional fields set.
+func TestFormatSearchPayload_Options(t *testing.T) {
+	expected := "inboundDate=2019-11-10&cabinClass=business&children=2&infants=0&country=US&currency=USD&locale=en-US" +
+		"&originPlace=LHR-sky&destinationPlace=LAX-sky&outboundDate=2019-11-01&adults=2&groupPricing=false"
+
+	groupPricing := false
+	arguments := dummyArguments
+	arguments.CabinClass = "business"
+	arguments.Currency = "USD"
+	arguments.Country = "US"
+	arguments.Locale = "en-US"
+	arguments.GroupPricing = &groupPricing
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+	actual, err := service.formatSearchPayload(&arguments)
+
+	assert.Equal(t, expected, actual, "Incorrect payload")
+	assert.Nil(t, err, "Error not expected")
+}
+
+// TestFormatSearchPayload_InvalidCabinClass tests formatting the payload of a search with an unknown cabin class.
+func TestFormatSearchPayload_InvalidCabinClass(t *testing.T) {
+	arguments := dummyArguments
+	arguments.CabinClass = "steerage"
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+	actual, err := service.formatSearchPayload(&arguments)
+
+	assert.Equal(t, "", actual, "No payload expected")
+	assert.Error(t, err, "Error expected")
+}
+
+// TestFormatSearchPayload_OneWay tests formatting the payload of a one-way trip, which has no inbound date.
+func TestFormatSearchPayload_OneWay(t *testing.T) {
+	expected := "cabinClass=economy&children=2&infants=0&country=GB&currency=GBP&locale=en-GB" +
+		"&originPlace=LHR-sky&destinationPlace=LAX-sky&outboundDate=2019-11-01&adults=2&groupPricing=true"
+
+	arguments := dummyArguments
+	arguments.TripType = domain.OneWayTrip
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+	actual, err := service.formatSearchPayload(&arguments)
+
+	assert.Equal(t, expected, actual, "Incorrect payload")
+	assert.Nil(t, err, "Error not expected")
+}
+
+// TestFormatSearchPayload_OpenJaw tests formatting the payload of an open-jaw trip, which can't be searched directly.
+func TestFormatSearchPayload_OpenJaw(t *testing.T) {
+	arguments := dummyArguments
+	arguments.TripType = domain.OpenJawTrip
+	arguments.ReturnOrigin = "SFO"
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+	actual, err := service.formatSearchPayload(&arguments)
+
+	assert.Equal(t, "", actual, "No payload expected")
+	assert.Error(t, err, "Error expected")
+}
+
 // TestStartSearch_HappyPath tests starting a search, when the response is success.
 func TestStartSearch_HappyPath(t *testing.T) {
 	defer gock.Off()
@@ -80,12 +168,12 @@ func TestStartSearch_HappyPath(t *testing.T) {
 		AddHeader("Location", "https://test.com/aaa/bbb/ccc/abc")
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
 	mockLogger.On("Infof", mock.Anything, mock.Anything)
 
-	sessionKey, err := service.StartSearch(&dummyArguments)
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
 	assert.Nil(t, err, "No error expected")
 	assert.Equal(t, "abc", sessionKey, "Invalid session key")
 	assert.Equal(t, gock.IsDone(), true)
@@ -102,12 +190,12 @@ func TestStartSearch_NoLocation(t *testing.T) {
 		Reply(201)
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
 	mockLogger.On("Infof", mock.Anything, mock.Anything)
 
-	sessionKey, err := service.StartSearch(&dummyArguments)
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
 	assert.Error(t, err, "Error expected")
 	assert.Equal(t, "", sessionKey, "No session key expected")
 	assert.Equal(t, gock.IsDone(), true)
@@ -125,12 +213,12 @@ func TestStartSearch_NoSessionKey(t *testing.T) {
 		AddHeader("Location", "wibble") // no / character...
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
 	mockLogger.On("Infof", mock.Anything, mock.Anything)
 
-	sessionKey, err := service.StartSearch(&dummyArguments)
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
 	assert.Error(t, err, "Error expected")
 	assert.Equal(t, "", sessionKey, "No session key expected")
 	assert.Equal(t, gock.IsDone(), true)
@@ -145,23 +233,268 @@ func TestStartSearch_Rejected(t *testing.T) {
 		Reply(401)
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
 	mockLogger.On("Infof", mock.Anything, mock.Anything)
 	mockLogger.On("Errorf", mock.Anything, mock.Anything, mock.Anything)
 
-	sessionKey, err := service.StartSearch(&dummyArguments)
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
+	assert.Error(t, err, "Error expected")
+	assert.Equal(t, "", sessionKey, "No session key expected")
+	assert.Equal(t, gock.IsDone(), true)
+}
+
+// TestStartSearch_NetworkError tests starting a search, when the request fails, is retried then returns the error.
+func TestStartSearch_NetworkError(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://test.com").
+		Post("/apiservices/pricing/v1.0").
+		Times(2).
+		ReplyError(errors.New("Connection refused"))
+
+	mockLogger := &mocks.Logger{}
+	service, delays := newRetryingTestService(mockLogger, 2)
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
+	assert.Error(t, err, "Error expected")
+	assert.Equal(t, "", sessionKey, "No session key expected")
+	assert.Len(t, *delays, 1, "Expected one retry")
+	assert.Equal(t, gock.IsDone(), true)
+}
+
+// TestStartSearch_InvalidRequest tests a request that can't be built fails straight away, without retrying.
+func TestStartSearch_InvalidRequest(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	service, delays := newRetryingTestService(mockLogger, 3)
+	service.options.BaseURL = "https://bad host"
+
+	mockLogger.On("Debug", mock.Anything)
+
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
 	assert.Error(t, err, "Error expected")
 	assert.Equal(t, "", sessionKey, "No session key expected")
+	assert.Empty(t, *delays, "Expected no retries")
+}
+
+// TestStartSearch_RateLimited tests starting a search is retried after the wait in the Retry-After header.
+func TestStartSearch_RateLimited(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://test.com").
+		Post("/apiservices/pricing/v1.0").
+		Reply(429).
+		AddHeader("Retry-After", "120")
+	gock.New("https://test.com").
+		Post("/apiservices/pricing/v1.0").
+		Reply(201).
+		AddHeader("Location", "https://test.com/aaa/bbb/ccc/abc")
+
+	mockLogger := &mocks.Logger{}
+	service, delays := newRetryingTestService(mockLogger, 3)
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything)
+	mockLogger.On("Errorf", mock.Anything, mock.Anything)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, "abc", sessionKey, "Invalid session key")
+	assert.Equal(t, []time.Duration{2 * time.Minute}, *delays, "Wrong delays")
 	assert.Equal(t, gock.IsDone(), true)
 }
 
+// TestStartSearch_Unauthorized tests starting a search with an invalid API key fails straight away.
+func TestStartSearch_Unauthorized(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://test.com").
+		Post("/apiservices/pricing/v1.0").
+		Reply(403)
+
+	mockLogger := &mocks.Logger{}
+	service, delays := newRetryingTestService(mockLogger, 3)
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Errorf", mock.Anything, mock.Anything)
+
+	_, err := service.StartSearch(context.Background(), &dummyArguments)
+	assert.True(t, errors.Is(err, domain.ErrUnauthorized), "Wrong error %s", err)
+	assert.Empty(t, *delays, "Expected no retries")
+	assert.Equal(t, gock.IsDone(), true)
+}
+
+// TestStartSearch_Options tests starting a search against a local server, with the configured client and user agent.
+func TestStartSearch_Options(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/apiservices/pricing/v1.0", r.URL.Path, "Wrong path")
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"), "Wrong user agent")
+		assert.Equal(t, "test.com", r.Header.Get("x-rapidapi-host"), "Wrong API host")
+		w.Header().Set("Location", "http://localhost/apiservices/pricing/uk2/v1.0/abc")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	mockLogger := &mocks.Logger{}
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{Client: server.Client(), BaseURL: server.URL + "/",
+		UserAgent: "test-agent"})
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Infof", mock.Anything, mock.Anything)
+
+	sessionKey, err := service.StartSearch(context.Background(), &dummyArguments)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, "abc", sessionKey, "Invalid session key")
+}
+
+// TestGetBookingDetails_HappyPath tests following a booking details link, then polling the booking details.
+func TestGetBookingDetails_HappyPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "testKey", r.Header.Get("x-rapidapi-key"), "Wrong API key")
+		switch r.Method {
+		case http.MethodPut:
+			assert.Equal(t, "/apiservices/pricing/v1.0/abc/booking", r.URL.Path, "Wrong path")
+			assert.Nil(t, r.ParseForm(), "No error expected")
+			assert.Equal(t, "leg1", r.PostForm.Get("OutboundLegId"), "Wrong outbound leg")
+			w.Header().Set("Location", "http://elsewhere/apiservices/pricing/v1.0/abc/booking/leg1;leg2")
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			assert.Equal(t, "/apiservices/pricing/v1.0/abc/booking/leg1;leg2", r.URL.Path, "Wrong path")
+			json.NewEncoder(w).Encode(SkyScannerBookingDetails{
+				Query: SkyScannerQuery{Currency: "GBP"},
+				BookingOptions: []SkyScannerBookingOption{
+					SkyScannerBookingOption{BookingItems: []SkyScannerBookingItem{
+						SkyScannerBookingItem{AgentID: 1111, Status: "Current", Price: 100.99, Deeplink: "http://a"},
+						SkyScannerBookingItem{AgentID: 1112, Status: "Pending", Price: 20, Deeplink: "http://b"},
+					}},
+					SkyScannerBookingOption{BookingItems: []SkyScannerBookingItem{
+						SkyScannerBookingItem{AgentID: 1112, Status: "Current", Price: 99.5, Deeplink: "http://c"},
+					}},
+				},
+				Agents: []SkyScannerAgent{
+					SkyScannerAgent{ID: 1111, Name: "Agent1", Type: "Airline"},
+					SkyScannerAgent{ID: 1112, Name: "Agent2", Type: "TravelAgent"},
+				},
+			})
+		}
+	}))
+	defer server.Close()
+
+	mockLogger := &mocks.Logger{}
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{BaseURL: server.URL})
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+
+	link := &domain.BookingDetailsLink{URI: "/apiservices/pricing/v1.0/abc/booking",
+		Body: "OutboundLegId=leg1&InboundLegId=leg2", Method: "PUT"}
+	details, err := service.GetBookingDetails(context.Background(), link, "test.com", "testKey")
+	assert.Nil(t, err, "No error expected")
+	assert.False(t, details.Complete, "Expected pending prices")
+	assert.Equal(t, []*domain.BookingOption{
+		&domain.BookingOption{Items: []*domain.BookingItem{
+			&domain.BookingItem{SupplierName: "Agent2", SupplierType: "TravelAgent",
+				Price: domain.Money{Amount: 9950, Currency: "GBP"}, DeeplinkURL: "http://c", Status: "Current"},
+		}},
+		&domain.BookingOption{Items: []*domain.BookingItem{
+			&domain.BookingItem{SupplierName: "Agent1", SupplierType: "Airline",
+				Price: domain.Money{Amount: 10099, Currency: "GBP"}, DeeplinkURL: "http://a", Status: "Current"},
+			&domain.BookingItem{SupplierName: "Agent2", SupplierType: "TravelAgent",
+				Price: domain.Money{Amount: 2000, Currency: "GBP"}, DeeplinkURL: "http://b", Status: "Pending"},
+		}},
+	}, details.Options, "Wrong booking options")
+}
+
+// TestGetBookingDetails_NoLocation tests creating booking details that returns no location to poll.
+func TestGetBookingDetails_NoLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	mockLogger := &mocks.Logger{}
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{BaseURL: server.URL})
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+
+	link := &domain.BookingDetailsLink{URI: "/apiservices/pricing/v1.0/abc/booking", Body: "OutboundLegId=leg1"}
+	_, err := service.GetBookingDetails(context.Background(), link, "test.com", "testKey")
+	assert.EqualError(t, err, "No booking details location returned in response: ", "Wrong error")
+}
+
+// TestStartSearch_Timeout tests a request that takes longer than the request timeout fails.
+func TestStartSearch_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	mockLogger := &mocks.Logger{}
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{BaseURL: server.URL,
+		RequestTimeout: 10 * time.Millisecond, Retry: RetryPolicy{MaxAttempts: 1}})
+
+	mockLogger.On("Debug", mock.Anything)
+
+	_, err := service.StartSearch(context.Background(), &dummyArguments)
+	assert.Error(t, err, "Error expected")
+}
+
+// TestStartSearch_Cancelled tests no request is retried once the context is done.
+func TestStartSearch_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	mockLogger := &mocks.Logger{}
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{BaseURL: server.URL,
+		Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}})
+
+	mockLogger.On("Debug", mock.Anything)
+
+	_, err := service.StartSearch(ctx, &dummyArguments)
+	assert.Equal(t, context.Canceled, err, "Wrong error")
+}
+
+// TestStartSearch_RetriesLimited tests every attempt at a request, including retries, takes a token from the limiter.
+func TestStartSearch_RetriesLimited(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	mockLogger := &mocks.Logger{}
+	service := NewSkyScannerService(mockLogger, SkyScannerOptions{BaseURL: server.URL,
+		Retry: RetryPolicy{MaxAttempts: 5}, Limiter: NewTokenBucket(1, 2)})
+	service.sleep = func(ctx context.Context, delay time.Duration) error { return nil }
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Errorf", mock.Anything, mock.Anything)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := service.StartSearch(ctx, &dummyArguments)
+	assert.Equal(t, context.DeadlineExceeded, err, "Wrong error")
+	assert.Equal(t, 2, requests, "Expected only the burst of requests")
+}
+
 // TestPollForQuote_HappyPath tests polling for quotes, when the response is success.
 func TestPollForQuote_HappyPath(t *testing.T) {
 	defer gock.Off()
 
 	expected := &domain.Quote{
+		Currency:    "GBP",
 		Itineraries: []*domain.Itinerary{},
 		Complete:    false,
 	}
@@ -182,17 +515,96 @@ func TestPollForQuote_HappyPath(t *testing.T) {
 		})
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
-	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
 
-	actual, err := service.PollForQuotes("abc", "test.com", "testKey", dummyAirports)
+	actual, err := service.PollForQuotes(context.Background(), "abc", "test.com", "testKey", dummyAirports)
 	assert.Nil(t, err, "No error expected")
 	assert.EqualValues(t, expected, actual, "Invalid response")
 	assert.Equal(t, gock.IsDone(), true)
 }
 
+// TestPollForQuote_Paginated tests polling for complete quotes fetches every page, until a page is empty.
+func TestPollForQuote_Paginated(t *testing.T) {
+	defer gock.Off()
+
+	first := getExampleResponse(valid)
+	first.Status = "UpdatesComplete"
+	second := getExampleResponse(valid)
+	second.Status = "UpdatesComplete"
+	second.Itineraries[0].PricingOptions[0].Agents = []int{1112}
+	second.Itineraries[0].PricingOptions[0].Price = 90.5
+	second.Agents = []SkyScannerAgent{SkyScannerAgent{ID: 1112, Name: "Agent2", Type: "TravelAgent"}}
+
+	for index, page := range []*SkyScannerResponse{first, second, &SkyScannerResponse{Status: "UpdatesComplete"}} {
+		gock.New("https://test.com/apiservices/pricing/uk2/v1.0/%7Babc%7D").
+			MatchParam("pageIndex", strconv.Itoa(index)).
+			MatchParam("pageSize", "1").
+			Reply(200).
+			JSON(page)
+	}
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 1)
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
+
+	actual, err := service.PollForQuotes(context.Background(), "abc", "test.com", "testKey", dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.True(t, actual.Complete, "Expected complete")
+	assert.Len(t, actual.Itineraries, 2, "Wrong number of itineraries")
+	assert.Equal(t, "Agent1", actual.Itineraries[0].SupplierName, "Wrong first supplier")
+	assert.Equal(t, "Agent2", actual.Itineraries[1].SupplierName, "Wrong second supplier")
+	assert.Equal(t, gock.IsDone(), true)
+}
+
+// TestPollForQuote_PendingFirstPageOnly tests polling for quotes still being updated only fetches the first page.
+func TestPollForQuote_PendingFirstPageOnly(t *testing.T) {
+	defer gock.Off()
+
+	pending := getExampleResponse(valid)
+	pending.Status = "UpdatesPending"
+	gock.New("https://test.com/apiservices/pricing/uk2/v1.0/%7Babc%7D").
+		MatchParam("pageIndex", "0").
+		MatchParam("pageSize", "1").
+		Reply(200).
+		JSON(pending)
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 1)
+
+	mockLogger.On("Debug", mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
+
+	actual, err := service.PollForQuotes(context.Background(), "abc", "test.com", "testKey", dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.False(t, actual.Complete, "Expected incomplete")
+	assert.Len(t, actual.Itineraries, 1, "Wrong number of itineraries")
+	assert.Equal(t, gock.IsDone(), true)
+}
+
+// TestMergePage tests merging a later page adds its itineraries, and only the entities not already included.
+func TestMergePage(t *testing.T) {
+	response := getExampleResponse(valid)
+	response.Status = "UpdatesComplete"
+	page := getExampleResponse(valid)
+	page.Status = "UpdatesPending"
+	page.Agents = append(page.Agents, SkyScannerAgent{ID: 1112, Name: "Agent2"})
+	page.Places = append(page.Places, SkyScannerPlace{ID: 103, Code: "CODE3"})
+
+	mergePage(response, page)
+	assert.Len(t, response.Itineraries, 2, "Wrong number of itineraries")
+	assert.Len(t, response.Legs, 2, "Wrong number of legs")
+	assert.Len(t, response.Segments, 2, "Wrong number of segments")
+	assert.Len(t, response.Carriers, 2, "Wrong number of carriers")
+	assert.Len(t, response.Agents, 2, "Wrong number of agents")
+	assert.Len(t, response.Places, 3, "Wrong number of places")
+	assert.Equal(t, "UpdatesPending", response.Status, "Wrong status")
+}
+
 // TestPollForQuote_ServerError tests polling for quotes, when the response is a server error.
 func TestPollForQuote_ServerError(t *testing.T) {
 	defer gock.Off()
@@ -204,18 +616,38 @@ func TestPollForQuote_ServerError(t *testing.T) {
 		BodyString("Oops")
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
-	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
 	mockLogger.On("Errorf", mock.Anything, mock.Anything, mock.Anything)
 
-	actual, err := service.PollForQuotes("abc", "test.com", "testKey", dummyAirports)
+	actual, err := service.PollForQuotes(context.Background(), "abc", "test.com", "testKey", dummyAirports)
 	assert.Error(t, err, "Error expected")
 	assert.Nil(t, actual, "No response expected")
 	assert.Equal(t, gock.IsDone(), true)
 }
 
+// TestPollForQuote_SessionExpired tests polling for quotes, when the session has expired.
+func TestPollForQuote_SessionExpired(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://test.com/apiservices/pricing/uk2/v1.0/%7Babc%7D").
+		Reply(410)
+
+	mockLogger := &mocks.Logger{}
+	service, delays := newRetryingTestService(mockLogger, 3)
+
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
+	mockLogger.On("Errorf", mock.Anything, mock.Anything)
+
+	actual, err := service.PollForQuotes(context.Background(), "abc", "test.com", "testKey", dummyAirports)
+	assert.True(t, errors.Is(err, domain.ErrSessionExpired), "Wrong error %s", err)
+	assert.Nil(t, actual, "No response expected")
+	assert.Empty(t, *delays, "Expected no retries")
+	assert.Equal(t, gock.IsDone(), true)
+}
+
 // TestPollForQuote_InvalidResponse tests polling for quotes, when the response is invalid JSON.
 func TestPollForQuote_InvalidResponse(t *testing.T) {
 	defer gock.Off()
@@ -227,12 +659,12 @@ func TestPollForQuote_InvalidResponse(t *testing.T) {
 		BodyString("{\"wibble\":1234,") // un-terminated JSON
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	mockLogger.On("Debug", mock.Anything)
-	mockLogger.On("Debugf", mock.Anything, mock.Anything)
+	mockLogger.On("Debugf", mock.Anything, mock.Anything, mock.Anything)
 
-	actual, err := service.PollForQuotes("abc", "test.com", "testKey", dummyAirports)
+	actual, err := service.PollForQuotes(context.Background(), "abc", "test.com", "testKey", dummyAirports)
 	assert.Error(t, err, "Error expected")
 	assert.Nil(t, actual, "No response expected")
 	assert.Equal(t, gock.IsDone(), true)
@@ -247,7 +679,7 @@ func TestConvertToDomain_Empty(t *testing.T) {
 	}
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	actual, err := service.convertToDomain(&input, dummyAirports)
 	assert.Nil(t, err, "No error expected")
@@ -257,11 +689,19 @@ func TestConvertToDomain_Empty(t *testing.T) {
 // TestConvertToDomain_Populated tests converting to domain values, when the response is populated and valid.
 func TestConvertToDomain_PopulatedValid(t *testing.T) {
 	expected := domain.Quote{
+		Currency: "GBP",
 		Itineraries: []*domain.Itinerary{
 			&domain.Itinerary{
 				SupplierName: "Agent1",
 				SupplierType: "Airline",
-				Amount:       10099,
+				Price:        domain.Money{Amount: 10099, Currency: "GBP"},
+				DeeplinkURL:  "http://test.com/book?agent=1111",
+				QuoteAge:     12 * time.Minute,
+				BookingDetailsLink: &domain.BookingDetailsLink{
+					URI:    "/apiservices/pricing/v1.0/abc/booking",
+					Body:   "OutboundLegId=leg1&InboundLegId=leg2",
+					Method: "PUT",
+				},
 				OutboundJourney: &domain.Journey{
 					ID:        "leg1",
 					Direction: domain.Outbound,
@@ -309,20 +749,78 @@ func TestConvertToDomain_PopulatedValid(t *testing.T) {
 			},
 		},
 		Complete: true,
+		Agents:   1,
 	}
 
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	actual, err := service.convertToDomain(getExampleResponse(valid), dummyAirports)
 	assert.Nil(t, err, "No error expected")
 	assert.Equal(t, expected, *actual, "Wrong output")
 }
 
+// TestConvertToDomain_AgentsPending tests counting the agents yet to quote, while the search is pending.
+func TestConvertToDomain_AgentsPending(t *testing.T) {
+	response := getExampleResponse(valid)
+	response.Status = "UpdatesPending"
+	response.Agents[0].Status = "UpdatesComplete"
+	response.Agents = append(response.Agents,
+		SkyScannerAgent{ID: 1112, Name: "Agent2", Status: "UpdatesPending"},
+		SkyScannerAgent{ID: 1113, Name: "Agent3", Status: "UpdatesPending"})
+
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+
+	actual, err := service.convertToDomain(response, dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.False(t, actual.Complete, "Expected pending quote")
+	assert.Equal(t, 3, actual.Agents, "Wrong number of agents")
+	assert.Equal(t, 2, actual.AgentsPending, "Wrong number of agents pending")
+}
+
+// TestConvertToDomain_OneWay tests converting to domain values, when the itineraries have no inbound leg.
+func TestConvertToDomain_OneWay(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+
+	actual, err := service.convertToDomain(getExampleResponse(oneWay), dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Len(t, actual.Itineraries, 1, "Wrong number of itineraries")
+	assert.Equal(t, "leg1", actual.Itineraries[0].OutboundJourney.ID, "Wrong outbound journey")
+	assert.Nil(t, actual.Itineraries[0].InboundJourney, "No inbound journey expected")
+}
+
+// TestConvertToDomain_Currency tests converting to domain values, using the format of the query currency.
+func TestConvertToDomain_Currency(t *testing.T) {
+	mockLogger := &mocks.Logger{}
+	service := newTestService(mockLogger, 10)
+
+	response := getExampleResponse(valid)
+	response.Query.Currency = "JPY"
+	response.Currencies = []SkyScannerCurrency{
+		SkyScannerCurrency{Code: "GBP", Symbol: "£", DecimalDigits: 2},
+		SkyScannerCurrency{Code: "JPY", Symbol: "¥", ThousandsSeparator: ",", DecimalSeparator: ".",
+			SymbolOnLeft: true, DecimalDigits: 0},
+	}
+
+	actual, err := service.convertToDomain(response, dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Equal(t, &domain.CurrencyFormat{Code: "JPY", Symbol: "¥", ThousandsSeparator: ",", DecimalSeparator: ".",
+		SymbolOnLeft: true, DecimalDigits: 0}, actual.CurrencyFormat, "Wrong currency format")
+	assert.Equal(t, domain.Money{Amount: 101, Currency: "JPY"}, actual.Itineraries[0].Price, "Wrong price")
+
+	response.Currencies = nil // the format isn't known, so the decimal digits of JPY are assumed
+	actual, err = service.convertToDomain(response, dummyAirports)
+	assert.Nil(t, err, "No error expected")
+	assert.Nil(t, actual.CurrencyFormat, "Expected no currency format")
+	assert.Equal(t, domain.Money{Amount: 101, Currency: "JPY"}, actual.Itineraries[0].Price, "Wrong price")
+}
+
 // TestConvertToDomain_Errors tests converting to domain values, when the response contains various types of errors.
 func TestConvertToDomain_Errors(t *testing.T) {
 	mockLogger := &mocks.Logger{}
-	service := SkyScannerService{mockLogger}
+	service := newTestService(mockLogger, 10)
 
 	testCases := []struct {
 		option  responseOption
@@ -411,21 +909,32 @@ const (
 	unknownInboundAirport
 	missingOutboundCarrier
 	missingInboundCarrier
+	oneWay
 )
 
 // getExampleResponse returns an example skyscanner format response
 func getExampleResponse(option responseOption) *SkyScannerResponse {
 	response := SkyScannerResponse{
+		Query: SkyScannerQuery{
+			Currency: "GBP",
+		},
 		Itineraries: []SkyScannerItinerary{
 			SkyScannerItinerary{
 				OutboundLegID: "leg1",
 				InboundLegID:  "leg2",
 				PricingOptions: []SkyScannerPricingOption{
 					SkyScannerPricingOption{
-						Agents: []int{1111},
-						Price:  100.99,
+						Agents:            []int{1111},
+						QuoteAgeInMinutes: 12,
+						Price:             100.99,
+						DeeplinkURL:       "http://test.com/book?agent=1111",
 					},
 				},
+				BookingDetailsLink: SkyScannerBookingDetailsLink{
+					URI:    "/apiservices/pricing/v1.0/abc/booking",
+					Body:   "OutboundLegId=leg1&InboundLegId=leg2",
+					Method: "PUT",
+				},
 			},
 		},
 		Legs: []SkyScannerLeg{
@@ -548,6 +1057,10 @@ func getExampleResponse(option responseOption) *SkyScannerResponse {
 
 	case missingInboundCarrier:
 		response.Carriers = response.Carriers[0:1]
+
+	case oneWay:
+		response.Itineraries[0].InboundLegID = ""
+		response.Legs = response.Legs[0:1]
 	}
 
 	return &response